@@ -9,6 +9,8 @@ package v1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,10 +23,128 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type SessionCommand_Op int32
+
+const (
+	SessionCommand_OP_UNSPECIFIED SessionCommand_Op = 0
+	SessionCommand_OPEN           SessionCommand_Op = 1 // 打开会话，session_id 为空时新建；流上已有会话时返回 FAILED_PRECONDITION
+	SessionCommand_ADD            SessionCommand_Op = 2 // 累加器加上 value
+	SessionCommand_SUBTRACT       SessionCommand_Op = 3 // 累加器减去 value
+	SessionCommand_RESET          SessionCommand_Op = 4 // 累加器置为 value
+	SessionCommand_UNDO           SessionCommand_Op = 5 // 撤销上一次修改，没有可撤销的修改时返回 FAILED_PRECONDITION
+	SessionCommand_SNAPSHOT       SessionCommand_Op = 6 // 只返回当前状态
+)
+
+// Enum value maps for SessionCommand_Op.
+var (
+	SessionCommand_Op_name = map[int32]string{
+		0: "OP_UNSPECIFIED",
+		1: "OPEN",
+		2: "ADD",
+		3: "SUBTRACT",
+		4: "RESET",
+		5: "UNDO",
+		6: "SNAPSHOT",
+	}
+	SessionCommand_Op_value = map[string]int32{
+		"OP_UNSPECIFIED": 0,
+		"OPEN":           1,
+		"ADD":            2,
+		"SUBTRACT":       3,
+		"RESET":          4,
+		"UNDO":           5,
+		"SNAPSHOT":       6,
+	}
+)
+
+func (x SessionCommand_Op) Enum() *SessionCommand_Op {
+	p := new(SessionCommand_Op)
+	*p = x
+	return p
+}
+
+func (x SessionCommand_Op) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SessionCommand_Op) Descriptor() protoreflect.EnumDescriptor {
+	return file_calculator_proto_enumTypes[0].Descriptor()
+}
+
+func (SessionCommand_Op) Type() protoreflect.EnumType {
+	return &file_calculator_proto_enumTypes[0]
+}
+
+func (x SessionCommand_Op) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SessionCommand_Op.Descriptor instead.
+func (SessionCommand_Op) EnumDescriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{2, 0}
+}
+
+type Operation_State int32
+
+const (
+	Operation_STATE_UNSPECIFIED Operation_State = 0
+	Operation_RUNNING           Operation_State = 1
+	Operation_SUCCEEDED         Operation_State = 2
+	Operation_FAILED            Operation_State = 3
+	Operation_CANCELLED         Operation_State = 4
+)
+
+// Enum value maps for Operation_State.
+var (
+	Operation_State_name = map[int32]string{
+		0: "STATE_UNSPECIFIED",
+		1: "RUNNING",
+		2: "SUCCEEDED",
+		3: "FAILED",
+		4: "CANCELLED",
+	}
+	Operation_State_value = map[string]int32{
+		"STATE_UNSPECIFIED": 0,
+		"RUNNING":           1,
+		"SUCCEEDED":         2,
+		"FAILED":            3,
+		"CANCELLED":         4,
+	}
+)
+
+func (x Operation_State) Enum() *Operation_State {
+	p := new(Operation_State)
+	*p = x
+	return p
+}
+
+func (x Operation_State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Operation_State) Descriptor() protoreflect.EnumDescriptor {
+	return file_calculator_proto_enumTypes[1].Descriptor()
+}
+
+func (Operation_State) Type() protoreflect.EnumType {
+	return &file_calculator_proto_enumTypes[1]
+}
+
+func (x Operation_State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Operation_State.Descriptor instead.
+func (Operation_State) EnumDescriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{27, 0}
+}
+
 type AddRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	A             int64                  `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
-	B             int64                  `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	A     int64                  `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B     int64                  `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	// 仅 ChatAdd 使用：设置后这条消息是会话命令，忽略 a 和 b
+	Session       *SessionCommand `protobuf:"bytes,3,opt,name=session,proto3" json:"session,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,9 +193,20 @@ func (x *AddRequest) GetB() int64 {
 	return 0
 }
 
+func (x *AddRequest) GetSession() *SessionCommand {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
 type AddResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Result        int64                  `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Result int64                  `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	// 仅 RangeAdd 填写：把它放进 RangeRequest.resume_token 重新请求，即可从下一个元素继续
+	ContinuationToken string `protobuf:"bytes,2,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
+	// 仅 ChatAdd 回复会话命令时填写，此时 result 为累加器的当前值
+	Session       *SessionState `protobuf:"bytes,3,opt,name=session,proto3" json:"session,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -117,17 +248,162 @@ func (x *AddResponse) GetResult() int64 {
 	return 0
 }
 
+func (x *AddResponse) GetContinuationToken() string {
+	if x != nil {
+		return x.ContinuationToken
+	}
+	return ""
+}
+
+func (x *AddResponse) GetSession() *SessionState {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
+// ChatAdd 会话：流上的会话命令共同操作服务端保存的一个累加器。
+// 流上第一条会话命令打开会话，OPEN 带 session_id 时恢复之前的会话，否则新建；
+// 流断开后会话保留一段空闲时间，期间同一调用方可以在新的流上恢复。
+// 命令出错时流以对应的状态结束，会话状态不变。
+// 没有 session 字段的消息仍然回复 a + b，不影响累加器
+type SessionCommand struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Op    SessionCommand_Op      `protobuf:"varint,1,opt,name=op,proto3,enum=calculator.v1.SessionCommand_Op" json:"op,omitempty"`
+	Value int64                  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	// OPEN 时要恢复的会话；会话不存在、已过期或属于其他调用方时返回 NOT_FOUND
+	SessionId     string `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionCommand) Reset() {
+	*x = SessionCommand{}
+	mi := &file_calculator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionCommand) ProtoMessage() {}
+
+func (x *SessionCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionCommand.ProtoReflect.Descriptor instead.
+func (*SessionCommand) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SessionCommand) GetOp() SessionCommand_Op {
+	if x != nil {
+		return x.Op
+	}
+	return SessionCommand_OP_UNSPECIFIED
+}
+
+func (x *SessionCommand) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *SessionCommand) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type SessionState struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// 每次修改加一；重连后对比它即可知道断开前最后一条命令是否已经生效
+	Version uint64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// 还能撤销的步数
+	UndoDepth     int32 `protobuf:"varint,3,opt,name=undo_depth,json=undoDepth,proto3" json:"undo_depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionState) Reset() {
+	*x = SessionState{}
+	mi := &file_calculator_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionState) ProtoMessage() {}
+
+func (x *SessionState) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionState.ProtoReflect.Descriptor instead.
+func (*SessionState) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SessionState) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionState) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *SessionState) GetUndoDepth() int32 {
+	if x != nil {
+		return x.UndoDepth
+	}
+	return 0
+}
+
 type RangeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Start         int64                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
 	End           int64                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	Step          int64                  `protobuf:"varint,3,opt,name=step,proto3" json:"step,omitempty"`                                 // 步长，0 表示 1，不能为负数
+	Rate          float64                `protobuf:"fixed64,4,opt,name=rate,proto3" json:"rate,omitempty"`                                // 每秒最多发送的消息数，0 表示不限速，只受流控约束；NaN、Inf 和小于约 1.1e-10 的正数无效
+	ResumeToken   string                 `protobuf:"bytes,5,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"` // 上次收到的 continuation_token，必须与 start/end/step 匹配
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RangeRequest) Reset() {
 	*x = RangeRequest{}
-	mi := &file_calculator_proto_msgTypes[2]
+	mi := &file_calculator_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -139,7 +415,7 @@ func (x *RangeRequest) String() string {
 func (*RangeRequest) ProtoMessage() {}
 
 func (x *RangeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_calculator_proto_msgTypes[2]
+	mi := &file_calculator_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -152,7 +428,7 @@ func (x *RangeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RangeRequest.ProtoReflect.Descriptor instead.
 func (*RangeRequest) Descriptor() ([]byte, []int) {
-	return file_calculator_proto_rawDescGZIP(), []int{2}
+	return file_calculator_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *RangeRequest) GetStart() int64 {
@@ -169,77 +445,2192 @@ func (x *RangeRequest) GetEnd() int64 {
 	return 0
 }
 
-var File_calculator_proto protoreflect.FileDescriptor
+func (x *RangeRequest) GetStep() int64 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
 
-const file_calculator_proto_rawDesc = "" +
-	"\n" +
-	"\x10calculator.proto\x12\rcalculator.v1\"(\n" +
-	"\n" +
-	"AddRequest\x12\f\n" +
-	"\x01a\x18\x01 \x01(\x03R\x01a\x12\f\n" +
-	"\x01b\x18\x02 \x01(\x03R\x01b\"%\n" +
-	"\vAddResponse\x12\x16\n" +
-	"\x06result\x18\x01 \x01(\x03R\x06result\"6\n" +
-	"\fRangeRequest\x12\x14\n" +
-	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x10\n" +
-	"\x03end\x18\x02 \x01(\x03R\x03end2\xa4\x02\n" +
-	"\x11CalculatorService\x12<\n" +
-	"\x03Add\x12\x19.calculator.v1.AddRequest\x1a\x1a.calculator.v1.AddResponse\x12D\n" +
-	"\tSumStream\x12\x19.calculator.v1.AddRequest\x1a\x1a.calculator.v1.AddResponse(\x01\x12E\n" +
-	"\bRangeAdd\x12\x1b.calculator.v1.RangeRequest\x1a\x1a.calculator.v1.AddResponse0\x01\x12D\n" +
-	"\aChatAdd\x12\x19.calculator.v1.AddRequest\x1a\x1a.calculator.v1.AddResponse(\x010\x01B#Z!grpc-demo/api/gen/caculator/v1;v1b\x06proto3"
+func (x *RangeRequest) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
 
-var (
-	file_calculator_proto_rawDescOnce sync.Once
-	file_calculator_proto_rawDescData []byte
-)
+func (x *RangeRequest) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
 
-func file_calculator_proto_rawDescGZIP() []byte {
-	file_calculator_proto_rawDescOnce.Do(func() {
-		file_calculator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_calculator_proto_rawDesc), len(file_calculator_proto_rawDesc)))
-	})
-	return file_calculator_proto_rawDescData
+type OperandsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	A             int64                  `protobuf:"varint,1,opt,name=a,proto3" json:"a,omitempty"`
+	B             int64                  `protobuf:"varint,2,opt,name=b,proto3" json:"b,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-var file_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
-var file_calculator_proto_goTypes = []any{
-	(*AddRequest)(nil),   // 0: calculator.v1.AddRequest
-	(*AddResponse)(nil),  // 1: calculator.v1.AddResponse
-	(*RangeRequest)(nil), // 2: calculator.v1.RangeRequest
+func (x *OperandsRequest) Reset() {
+	*x = OperandsRequest{}
+	mi := &file_calculator_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
-var file_calculator_proto_depIdxs = []int32{
-	0, // 0: calculator.v1.CalculatorService.Add:input_type -> calculator.v1.AddRequest
-	0, // 1: calculator.v1.CalculatorService.SumStream:input_type -> calculator.v1.AddRequest
-	2, // 2: calculator.v1.CalculatorService.RangeAdd:input_type -> calculator.v1.RangeRequest
-	0, // 3: calculator.v1.CalculatorService.ChatAdd:input_type -> calculator.v1.AddRequest
-	1, // 4: calculator.v1.CalculatorService.Add:output_type -> calculator.v1.AddResponse
-	1, // 5: calculator.v1.CalculatorService.SumStream:output_type -> calculator.v1.AddResponse
-	1, // 6: calculator.v1.CalculatorService.RangeAdd:output_type -> calculator.v1.AddResponse
-	1, // 7: calculator.v1.CalculatorService.ChatAdd:output_type -> calculator.v1.AddResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+func (x *OperandsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() { file_calculator_proto_init() }
-func file_calculator_proto_init() {
-	if File_calculator_proto != nil {
-		return
+func (*OperandsRequest) ProtoMessage() {}
+
+func (x *OperandsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperandsRequest.ProtoReflect.Descriptor instead.
+func (*OperandsRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OperandsRequest) GetA() int64 {
+	if x != nil {
+		return x.A
+	}
+	return 0
+}
+
+func (x *OperandsRequest) GetB() int64 {
+	if x != nil {
+		return x.B
+	}
+	return 0
+}
+
+type ResultResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        int64                  `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultResponse) Reset() {
+	*x = ResultResponse{}
+	mi := &file_calculator_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultResponse) ProtoMessage() {}
+
+func (x *ResultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultResponse.ProtoReflect.Descriptor instead.
+func (*ResultResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResultResponse) GetResult() int64 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+type DivideResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quotient      int64                  `protobuf:"varint,1,opt,name=quotient,proto3" json:"quotient,omitempty"`
+	Remainder     int64                  `protobuf:"varint,2,opt,name=remainder,proto3" json:"remainder,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DivideResponse) Reset() {
+	*x = DivideResponse{}
+	mi := &file_calculator_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DivideResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DivideResponse) ProtoMessage() {}
+
+func (x *DivideResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DivideResponse.ProtoReflect.Descriptor instead.
+func (*DivideResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DivideResponse) GetQuotient() int64 {
+	if x != nil {
+		return x.Quotient
+	}
+	return 0
+}
+
+func (x *DivideResponse) GetRemainder() int64 {
+	if x != nil {
+		return x.Remainder
+	}
+	return 0
+}
+
+// 批量请求中任意一项出错，整个请求失败，错误详情中的字段名带有下标，如 items[2].b
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*OperandsRequest     `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_calculator_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BatchRequest) GetItems() []*OperandsRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []int64                `protobuf:"varint,1,rep,packed,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_calculator_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BatchResponse) GetResults() []int64 {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type DivideBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*DivideResponse      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DivideBatchResponse) Reset() {
+	*x = DivideBatchResponse{}
+	mi := &file_calculator_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DivideBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DivideBatchResponse) ProtoMessage() {}
+
+func (x *DivideBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DivideBatchResponse.ProtoReflect.Descriptor instead.
+func (*DivideBatchResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DivideBatchResponse) GetResults() []*DivideResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type EvaluateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Expression    string                 `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Variables     map[string]int64       `protobuf:"bytes,2,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateRequest) Reset() {
+	*x = EvaluateRequest{}
+	mi := &file_calculator_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateRequest) ProtoMessage() {}
+
+func (x *EvaluateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateRequest.ProtoReflect.Descriptor instead.
+func (*EvaluateRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *EvaluateRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetVariables() map[string]int64 {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+type EvaluateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        int64                  `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateResponse) Reset() {
+	*x = EvaluateResponse{}
+	mi := &file_calculator_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateResponse) ProtoMessage() {}
+
+func (x *EvaluateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateResponse.ProtoReflect.Descriptor instead.
+func (*EvaluateResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *EvaluateResponse) GetResult() int64 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         int64                  `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_calculator_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StatsRequest) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type Percentile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quantile      float64                `protobuf:"fixed64,1,opt,name=quantile,proto3" json:"quantile,omitempty"` // 0.5, 0.9, 0.95, 0.99
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Percentile) Reset() {
+	*x = Percentile{}
+	mi := &file_calculator_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Percentile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Percentile) ProtoMessage() {}
+
+func (x *Percentile) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Percentile.ProtoReflect.Descriptor instead.
+func (*Percentile) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Percentile) GetQuantile() float64 {
+	if x != nil {
+		return x.Quantile
+	}
+	return 0
+}
+
+func (x *Percentile) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// 统计量在服务端流式计算，内存占用与上传数量无关；percentiles 为 P² 算法的近似值
+type StatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Sum           int64                  `protobuf:"varint,2,opt,name=sum,proto3" json:"sum,omitempty"`
+	Min           int64                  `protobuf:"varint,3,opt,name=min,proto3" json:"min,omitempty"`
+	Max           int64                  `protobuf:"varint,4,opt,name=max,proto3" json:"max,omitempty"`
+	Mean          float64                `protobuf:"fixed64,5,opt,name=mean,proto3" json:"mean,omitempty"`
+	Variance      float64                `protobuf:"fixed64,6,opt,name=variance,proto3" json:"variance,omitempty"` // 总体方差
+	Percentiles   []*Percentile          `protobuf:"bytes,7,rep,name=percentiles,proto3" json:"percentiles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_calculator_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *StatsResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetSum() int64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetMin() int64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetMax() int64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetMean() float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetVariance() float64 {
+	if x != nil {
+		return x.Variance
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetPercentiles() []*Percentile {
+	if x != nil {
+		return x.Percentiles
+	}
+	return nil
+}
+
+type HistoryEntry struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`        // 单调递增
+	Time             *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`     // 调用开始时间
+	Caller           string                 `protobuf:"bytes,3,opt,name=caller,proto3" json:"caller,omitempty"` // 调用方，sub:<subject> 或 ip:<host>
+	Method           string                 `protobuf:"bytes,4,opt,name=method,proto3" json:"method,omitempty"` // 完整方法名
+	RequestId        string                 `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Request          string                 `protobuf:"bytes,6,opt,name=request,proto3" json:"request,omitempty"`   // 请求的 JSON；流式调用为收到的前若干条消息组成的数组
+	Response         string                 `protobuf:"bytes,7,opt,name=response,proto3" json:"response,omitempty"` // 响应的 JSON；流式调用为最后一条发送的消息，出错时为空
+	Code             uint32                 `protobuf:"varint,8,opt,name=code,proto3" json:"code,omitempty"`        // gRPC 状态码，0 为 OK
+	Error            string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	Latency          *durationpb.Duration   `protobuf:"bytes,10,opt,name=latency,proto3" json:"latency,omitempty"`
+	MessagesReceived uint32                 `protobuf:"varint,11,opt,name=messages_received,json=messagesReceived,proto3" json:"messages_received,omitempty"`
+	MessagesSent     uint32                 `protobuf:"varint,12,opt,name=messages_sent,json=messagesSent,proto3" json:"messages_sent,omitempty"`
+	Truncated        bool                   `protobuf:"varint,13,opt,name=truncated,proto3" json:"truncated,omitempty"` // request 或 response 超过 1KB 被截断，不再是完整的 JSON
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_calculator_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *HistoryEntry) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetRequest() string {
+	if x != nil {
+		return x.Request
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetMessagesReceived() uint32 {
+	if x != nil {
+		return x.MessagesReceived
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetMessagesSent() uint32 {
+	if x != nil {
+		return x.MessagesSent
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+// 各条件之间为与关系，留空表示不过滤
+type HistoryFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Caller        string                 `protobuf:"bytes,1,opt,name=caller,proto3" json:"caller,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`       // 完整方法名，或只写方法名如 Add
+	Codes         []uint32               `protobuf:"varint,3,rep,packed,name=codes,proto3" json:"codes,omitempty"` // 状态码任意一个匹配即可
+	Since         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=since,proto3" json:"since,omitempty"`         // 包含
+	Until         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=until,proto3" json:"until,omitempty"`         // 不包含
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryFilter) Reset() {
+	*x = HistoryFilter{}
+	mi := &file_calculator_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryFilter) ProtoMessage() {}
+
+func (x *HistoryFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryFilter.ProtoReflect.Descriptor instead.
+func (*HistoryFilter) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *HistoryFilter) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *HistoryFilter) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *HistoryFilter) GetCodes() []uint32 {
+	if x != nil {
+		return x.Codes
+	}
+	return nil
+}
+
+func (x *HistoryFilter) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *HistoryFilter) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+type ListHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        *HistoryFilter         `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"` // 0 表示默认 50，最大 1000；一页超过 1MB 时提前截止
+	PageToken     string                 `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHistoryRequest) Reset() {
+	*x = ListHistoryRequest{}
+	mi := &file_calculator_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHistoryRequest) ProtoMessage() {}
+
+func (x *ListHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHistoryRequest.ProtoReflect.Descriptor instead.
+func (*ListHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListHistoryRequest) GetFilter() *HistoryFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListHistoryRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListHistoryRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // 为空表示没有更多记录
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHistoryResponse) Reset() {
+	*x = ListHistoryResponse{}
+	mi := &file_calculator_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHistoryResponse) ProtoMessage() {}
+
+func (x *ListHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHistoryResponse.ProtoReflect.Descriptor instead.
+func (*ListHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListHistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ListHistoryResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type StreamHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        *HistoryFilter         `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	SinceId       *uint64                `protobuf:"varint,2,opt,name=since_id,json=sinceId,proto3,oneof" json:"since_id,omitempty"` // 不填表示只推送新记录，填 0 则从第一条开始补发
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamHistoryRequest) Reset() {
+	*x = StreamHistoryRequest{}
+	mi := &file_calculator_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamHistoryRequest) ProtoMessage() {}
+
+func (x *StreamHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamHistoryRequest.ProtoReflect.Descriptor instead.
+func (*StreamHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *StreamHistoryRequest) GetFilter() *HistoryFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *StreamHistoryRequest) GetSinceId() uint64 {
+	if x != nil && x.SinceId != nil {
+		return *x.SinceId
+	}
+	return 0
+}
+
+// 一个方法的故障规则。每次调用分别按比例决定是否延迟、是否中止；比例为 0~100，0 表示 100
+type FaultRule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"` // 完整方法名、只写方法名如 Add，或 * 匹配所有方法；多条匹配时取最具体的
+	Delay         *durationpb.Duration   `protobuf:"bytes,2,opt,name=delay,proto3" json:"delay,omitempty"`   // 调用开始前的延迟，计入调用方的 deadline
+	DelayPercent  float64                `protobuf:"fixed64,3,opt,name=delay_percent,json=delayPercent,proto3" json:"delay_percent,omitempty"`
+	AbortCode     uint32                 `protobuf:"varint,4,opt,name=abort_code,json=abortCode,proto3" json:"abort_code,omitempty"` // 以该 gRPC 状态码中止，0 表示不中止；设置了 cut_after 时默认为 UNAVAILABLE
+	AbortPercent  float64                `protobuf:"fixed64,5,opt,name=abort_percent,json=abortPercent,proto3" json:"abort_percent,omitempty"`
+	CutAfter      uint32                 `protobuf:"varint,6,opt,name=cut_after,json=cutAfter,proto3" json:"cut_after,omitempty"` // 收发这么多条消息后才中止，0 表示调用开始时就中止；一元调用设置后处理函数照常执行，但响应被丢弃
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FaultRule) Reset() {
+	*x = FaultRule{}
+	mi := &file_calculator_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FaultRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FaultRule) ProtoMessage() {}
+
+func (x *FaultRule) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FaultRule.ProtoReflect.Descriptor instead.
+func (*FaultRule) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *FaultRule) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *FaultRule) GetDelay() *durationpb.Duration {
+	if x != nil {
+		return x.Delay
+	}
+	return nil
+}
+
+func (x *FaultRule) GetDelayPercent() float64 {
+	if x != nil {
+		return x.DelayPercent
+	}
+	return 0
+}
+
+func (x *FaultRule) GetAbortCode() uint32 {
+	if x != nil {
+		return x.AbortCode
+	}
+	return 0
+}
+
+func (x *FaultRule) GetAbortPercent() float64 {
+	if x != nil {
+		return x.AbortPercent
+	}
+	return 0
+}
+
+func (x *FaultRule) GetCutAfter() uint32 {
+	if x != nil {
+		return x.CutAfter
+	}
+	return 0
+}
+
+type FaultConfig struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*FaultRule           `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FaultConfig) Reset() {
+	*x = FaultConfig{}
+	mi := &file_calculator_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FaultConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FaultConfig) ProtoMessage() {}
+
+func (x *FaultConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FaultConfig.ProtoReflect.Descriptor instead.
+func (*FaultConfig) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *FaultConfig) GetRules() []*FaultRule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type GetFaultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFaultsRequest) Reset() {
+	*x = GetFaultsRequest{}
+	mi := &file_calculator_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFaultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFaultsRequest) ProtoMessage() {}
+
+func (x *GetFaultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFaultsRequest.ProtoReflect.Descriptor instead.
+func (*GetFaultsRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{23}
+}
+
+type StartOperationRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Job:
+	//
+	//	*StartOperationRequest_CountPrimes
+	//	*StartOperationRequest_Pow
+	Job           isStartOperationRequest_Job `protobuf_oneof:"job"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartOperationRequest) Reset() {
+	*x = StartOperationRequest{}
+	mi := &file_calculator_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartOperationRequest) ProtoMessage() {}
+
+func (x *StartOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartOperationRequest.ProtoReflect.Descriptor instead.
+func (*StartOperationRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *StartOperationRequest) GetJob() isStartOperationRequest_Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *StartOperationRequest) GetCountPrimes() *CountPrimesJob {
+	if x != nil {
+		if x, ok := x.Job.(*StartOperationRequest_CountPrimes); ok {
+			return x.CountPrimes
+		}
+	}
+	return nil
+}
+
+func (x *StartOperationRequest) GetPow() *PowJob {
+	if x != nil {
+		if x, ok := x.Job.(*StartOperationRequest_Pow); ok {
+			return x.Pow
+		}
+	}
+	return nil
+}
+
+type isStartOperationRequest_Job interface {
+	isStartOperationRequest_Job()
+}
+
+type StartOperationRequest_CountPrimes struct {
+	CountPrimes *CountPrimesJob `protobuf:"bytes,1,opt,name=count_primes,json=countPrimes,proto3,oneof"`
+}
+
+type StartOperationRequest_Pow struct {
+	Pow *PowJob `protobuf:"bytes,2,opt,name=pow,proto3,oneof"`
+}
+
+func (*StartOperationRequest_CountPrimes) isStartOperationRequest_Job() {}
+
+func (*StartOperationRequest_Pow) isStartOperationRequest_Job() {}
+
+// 用并发素数筛（每个素数一个 goroutine）统计小于 below 的素数个数，below 最大为 200000
+type CountPrimesJob struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Below         int64                  `protobuf:"varint,1,opt,name=below,proto3" json:"below,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountPrimesJob) Reset() {
+	*x = CountPrimesJob{}
+	mi := &file_calculator_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountPrimesJob) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountPrimesJob) ProtoMessage() {}
+
+func (x *CountPrimesJob) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountPrimesJob.ProtoReflect.Descriptor instead.
+func (*CountPrimesJob) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CountPrimesJob) GetBelow() int64 {
+	if x != nil {
+		return x.Below
+	}
+	return 0
+}
+
+// 任意精度的 a ^ b，操作数格式同 calculator.v2.CalculatorService.Pow；结果最多 2^23 位（二进制），是同步调用的 8 倍
+type PowJob struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	A             string                 `protobuf:"bytes,1,opt,name=a,proto3" json:"a,omitempty"`
+	B             string                 `protobuf:"bytes,2,opt,name=b,proto3" json:"b,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PowJob) Reset() {
+	*x = PowJob{}
+	mi := &file_calculator_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PowJob) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PowJob) ProtoMessage() {}
+
+func (x *PowJob) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PowJob.ProtoReflect.Descriptor instead.
+func (*PowJob) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PowJob) GetA() string {
+	if x != nil {
+		return x.A
+	}
+	return ""
+}
+
+func (x *PowJob) GetB() string {
+	if x != nil {
+		return x.B
+	}
+	return ""
+}
+
+type Operation struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Kind       string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"` // count_primes 或 pow
+	State      Operation_State        `protobuf:"varint,3,opt,name=state,proto3,enum=calculator.v1.Operation_State" json:"state,omitempty"`
+	Progress   float64                `protobuf:"fixed64,4,opt,name=progress,proto3" json:"progress,omitempty"` // 完成百分比，0~100
+	CreateTime *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
+	UpdateTime *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=update_time,json=updateTime,proto3" json:"update_time,omitempty"`
+	// 成功时为结果（ListOperations 中省略），失败或取消时为错误
+	//
+	// Types that are valid to be assigned to Outcome:
+	//
+	//	*Operation_Result
+	//	*Operation_Error
+	Outcome       isOperation_Outcome `protobuf_oneof:"outcome"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_calculator_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Operation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Operation) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Operation) GetState() Operation_State {
+	if x != nil {
+		return x.State
+	}
+	return Operation_STATE_UNSPECIFIED
+}
+
+func (x *Operation) GetProgress() float64 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *Operation) GetCreateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreateTime
+	}
+	return nil
+}
+
+func (x *Operation) GetUpdateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdateTime
+	}
+	return nil
+}
+
+func (x *Operation) GetOutcome() isOperation_Outcome {
+	if x != nil {
+		return x.Outcome
+	}
+	return nil
+}
+
+func (x *Operation) GetResult() *OperationResult {
+	if x != nil {
+		if x, ok := x.Outcome.(*Operation_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *Operation) GetError() *OperationError {
+	if x != nil {
+		if x, ok := x.Outcome.(*Operation_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isOperation_Outcome interface {
+	isOperation_Outcome()
+}
+
+type Operation_Result struct {
+	Result *OperationResult `protobuf:"bytes,7,opt,name=result,proto3,oneof"`
+}
+
+type Operation_Error struct {
+	Error *OperationError `protobuf:"bytes,8,opt,name=error,proto3,oneof"`
+}
+
+func (*Operation_Result) isOperation_Outcome() {}
+
+func (*Operation_Error) isOperation_Outcome() {}
+
+type OperationResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*OperationResult_PrimeCount
+	//	*OperationResult_Pow
+	Value         isOperationResult_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OperationResult) Reset() {
+	*x = OperationResult{}
+	mi := &file_calculator_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OperationResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OperationResult) ProtoMessage() {}
+
+func (x *OperationResult) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperationResult.ProtoReflect.Descriptor instead.
+func (*OperationResult) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *OperationResult) GetValue() isOperationResult_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *OperationResult) GetPrimeCount() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*OperationResult_PrimeCount); ok {
+			return x.PrimeCount
+		}
+	}
+	return 0
+}
+
+func (x *OperationResult) GetPow() string {
+	if x != nil {
+		if x, ok := x.Value.(*OperationResult_Pow); ok {
+			return x.Pow
+		}
+	}
+	return ""
+}
+
+type isOperationResult_Value interface {
+	isOperationResult_Value()
+}
+
+type OperationResult_PrimeCount struct {
+	PrimeCount int64 `protobuf:"varint,1,opt,name=prime_count,json=primeCount,proto3,oneof"`
+}
+
+type OperationResult_Pow struct {
+	Pow string `protobuf:"bytes,2,opt,name=pow,proto3,oneof"` // 精确结果：整数或最简分数
+}
+
+func (*OperationResult_PrimeCount) isOperationResult_Value() {}
+
+func (*OperationResult_Pow) isOperationResult_Value() {}
+
+type OperationError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          uint32                 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // gRPC 状态码
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OperationError) Reset() {
+	*x = OperationError{}
+	mi := &file_calculator_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OperationError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OperationError) ProtoMessage() {}
+
+func (x *OperationError) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperationError.ProtoReflect.Descriptor instead.
+func (*OperationError) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *OperationError) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *OperationError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOperationRequest) Reset() {
+	*x = GetOperationRequest{}
+	mi := &file_calculator_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOperationRequest) ProtoMessage() {}
+
+func (x *GetOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOperationRequest.ProtoReflect.Descriptor instead.
+func (*GetOperationRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *GetOperationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListOperationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOperationsRequest) Reset() {
+	*x = ListOperationsRequest{}
+	mi := &file_calculator_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOperationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOperationsRequest) ProtoMessage() {}
+
+func (x *ListOperationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOperationsRequest.ProtoReflect.Descriptor instead.
+func (*ListOperationsRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{31}
+}
+
+type ListOperationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operations    []*Operation           `protobuf:"bytes,1,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListOperationsResponse) Reset() {
+	*x = ListOperationsResponse{}
+	mi := &file_calculator_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListOperationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOperationsResponse) ProtoMessage() {}
+
+func (x *ListOperationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOperationsResponse.ProtoReflect.Descriptor instead.
+func (*ListOperationsResponse) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ListOperationsResponse) GetOperations() []*Operation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type CancelOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelOperationRequest) Reset() {
+	*x = CancelOperationRequest{}
+	mi := &file_calculator_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelOperationRequest) ProtoMessage() {}
+
+func (x *CancelOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelOperationRequest.ProtoReflect.Descriptor instead.
+func (*CancelOperationRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *CancelOperationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type WaitOperationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Timeout       *durationpb.Duration   `protobuf:"bytes,2,opt,name=timeout,proto3" json:"timeout,omitempty"` // 不填表示 10s，最长 5m
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WaitOperationRequest) Reset() {
+	*x = WaitOperationRequest{}
+	mi := &file_calculator_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WaitOperationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WaitOperationRequest) ProtoMessage() {}
+
+func (x *WaitOperationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WaitOperationRequest.ProtoReflect.Descriptor instead.
+func (*WaitOperationRequest) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *WaitOperationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *WaitOperationRequest) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+var File_calculator_proto protoreflect.FileDescriptor
+
+const file_calculator_proto_rawDesc = "" +
+	"\n" +
+	"\x10calculator.proto\x12\rcalculator.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"a\n" +
+	"\n" +
+	"AddRequest\x12\f\n" +
+	"\x01a\x18\x01 \x01(\x03R\x01a\x12\f\n" +
+	"\x01b\x18\x02 \x01(\x03R\x01b\x127\n" +
+	"\asession\x18\x03 \x01(\v2\x1d.calculator.v1.SessionCommandR\asession\"\x8b\x01\n" +
+	"\vAddResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\x03R\x06result\x12-\n" +
+	"\x12continuation_token\x18\x02 \x01(\tR\x11continuationToken\x125\n" +
+	"\asession\x18\x03 \x01(\v2\x1b.calculator.v1.SessionStateR\asession\"\xd5\x01\n" +
+	"\x0eSessionCommand\x120\n" +
+	"\x02op\x18\x01 \x01(\x0e2 .calculator.v1.SessionCommand.OpR\x02op\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x03 \x01(\tR\tsessionId\"\\\n" +
+	"\x02Op\x12\x12\n" +
+	"\x0eOP_UNSPECIFIED\x10\x00\x12\b\n" +
+	"\x04OPEN\x10\x01\x12\a\n" +
+	"\x03ADD\x10\x02\x12\f\n" +
+	"\bSUBTRACT\x10\x03\x12\t\n" +
+	"\x05RESET\x10\x04\x12\b\n" +
+	"\x04UNDO\x10\x05\x12\f\n" +
+	"\bSNAPSHOT\x10\x06\"f\n" +
+	"\fSessionState\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x04R\aversion\x12\x1d\n" +
+	"\n" +
+	"undo_depth\x18\x03 \x01(\x05R\tundoDepth\"\x81\x01\n" +
+	"\fRangeRequest\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x03R\x03end\x12\x12\n" +
+	"\x04step\x18\x03 \x01(\x03R\x04step\x12\x12\n" +
+	"\x04rate\x18\x04 \x01(\x01R\x04rate\x12!\n" +
+	"\fresume_token\x18\x05 \x01(\tR\vresumeToken\"-\n" +
+	"\x0fOperandsRequest\x12\f\n" +
+	"\x01a\x18\x01 \x01(\x03R\x01a\x12\f\n" +
+	"\x01b\x18\x02 \x01(\x03R\x01b\"(\n" +
+	"\x0eResultResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\x03R\x06result\"J\n" +
+	"\x0eDivideResponse\x12\x1a\n" +
+	"\bquotient\x18\x01 \x01(\x03R\bquotient\x12\x1c\n" +
+	"\tremainder\x18\x02 \x01(\x03R\tremainder\"D\n" +
+	"\fBatchRequest\x124\n" +
+	"\x05items\x18\x01 \x03(\v2\x1e.calculator.v1.OperandsRequestR\x05items\")\n" +
+	"\rBatchResponse\x12\x18\n" +
+	"\aresults\x18\x01 \x03(\x03R\aresults\"N\n" +
+	"\x13DivideBatchResponse\x127\n" +
+	"\aresults\x18\x01 \x03(\v2\x1d.calculator.v1.DivideResponseR\aresults\"\xbc\x01\n" +
+	"\x0fEvaluateRequest\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x01 \x01(\tR\n" +
+	"expression\x12K\n" +
+	"\tvariables\x18\x02 \x03(\v2-.calculator.v1.EvaluateRequest.VariablesEntryR\tvariables\x1a<\n" +
+	"\x0eVariablesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"*\n" +
+	"\x10EvaluateResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\x03R\x06result\"$\n" +
+	"\fStatsRequest\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x03R\x05value\">\n" +
+	"\n" +
+	"Percentile\x12\x1a\n" +
+	"\bquantile\x18\x01 \x01(\x01R\bquantile\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\"\xc8\x01\n" +
+	"\rStatsResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\x12\x10\n" +
+	"\x03sum\x18\x02 \x01(\x03R\x03sum\x12\x10\n" +
+	"\x03min\x18\x03 \x01(\x03R\x03min\x12\x10\n" +
+	"\x03max\x18\x04 \x01(\x03R\x03max\x12\x12\n" +
+	"\x04mean\x18\x05 \x01(\x01R\x04mean\x12\x1a\n" +
+	"\bvariance\x18\x06 \x01(\x01R\bvariance\x12;\n" +
+	"\vpercentiles\x18\a \x03(\v2\x19.calculator.v1.PercentileR\vpercentiles\"\xa2\x03\n" +
+	"\fHistoryEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x16\n" +
+	"\x06caller\x18\x03 \x01(\tR\x06caller\x12\x16\n" +
+	"\x06method\x18\x04 \x01(\tR\x06method\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x12\x18\n" +
+	"\arequest\x18\x06 \x01(\tR\arequest\x12\x1a\n" +
+	"\bresponse\x18\a \x01(\tR\bresponse\x12\x12\n" +
+	"\x04code\x18\b \x01(\rR\x04code\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x123\n" +
+	"\alatency\x18\n" +
+	" \x01(\v2\x19.google.protobuf.DurationR\alatency\x12+\n" +
+	"\x11messages_received\x18\v \x01(\rR\x10messagesReceived\x12#\n" +
+	"\rmessages_sent\x18\f \x01(\rR\fmessagesSent\x12\x1c\n" +
+	"\ttruncated\x18\r \x01(\bR\ttruncated\"\xb9\x01\n" +
+	"\rHistoryFilter\x12\x16\n" +
+	"\x06caller\x18\x01 \x01(\tR\x06caller\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x14\n" +
+	"\x05codes\x18\x03 \x03(\rR\x05codes\x120\n" +
+	"\x05since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\"\x86\x01\n" +
+	"\x12ListHistoryRequest\x124\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1c.calculator.v1.HistoryFilterR\x06filter\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"t\n" +
+	"\x13ListHistoryResponse\x125\n" +
+	"\aentries\x18\x01 \x03(\v2\x1b.calculator.v1.HistoryEntryR\aentries\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"y\n" +
+	"\x14StreamHistoryRequest\x124\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1c.calculator.v1.HistoryFilterR\x06filter\x12\x1e\n" +
+	"\bsince_id\x18\x02 \x01(\x04H\x00R\asinceId\x88\x01\x01B\v\n" +
+	"\t_since_id\"\xda\x01\n" +
+	"\tFaultRule\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12/\n" +
+	"\x05delay\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x05delay\x12#\n" +
+	"\rdelay_percent\x18\x03 \x01(\x01R\fdelayPercent\x12\x1d\n" +
+	"\n" +
+	"abort_code\x18\x04 \x01(\rR\tabortCode\x12#\n" +
+	"\rabort_percent\x18\x05 \x01(\x01R\fabortPercent\x12\x1b\n" +
+	"\tcut_after\x18\x06 \x01(\rR\bcutAfter\"=\n" +
+	"\vFaultConfig\x12.\n" +
+	"\x05rules\x18\x01 \x03(\v2\x18.calculator.v1.FaultRuleR\x05rules\"\x12\n" +
+	"\x10GetFaultsRequest\"\x8d\x01\n" +
+	"\x15StartOperationRequest\x12B\n" +
+	"\fcount_primes\x18\x01 \x01(\v2\x1d.calculator.v1.CountPrimesJobH\x00R\vcountPrimes\x12)\n" +
+	"\x03pow\x18\x02 \x01(\v2\x15.calculator.v1.PowJobH\x00R\x03powB\x05\n" +
+	"\x03job\"&\n" +
+	"\x0eCountPrimesJob\x12\x14\n" +
+	"\x05below\x18\x01 \x01(\x03R\x05below\"$\n" +
+	"\x06PowJob\x12\f\n" +
+	"\x01a\x18\x01 \x01(\tR\x01a\x12\f\n" +
+	"\x01b\x18\x02 \x01(\tR\x01b\"\xce\x03\n" +
+	"\tOperation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x124\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x1e.calculator.v1.Operation.StateR\x05state\x12\x1a\n" +
+	"\bprogress\x18\x04 \x01(\x01R\bprogress\x12;\n" +
+	"\vcreate_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"createTime\x12;\n" +
+	"\vupdate_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"updateTime\x128\n" +
+	"\x06result\x18\a \x01(\v2\x1e.calculator.v1.OperationResultH\x00R\x06result\x125\n" +
+	"\x05error\x18\b \x01(\v2\x1d.calculator.v1.OperationErrorH\x00R\x05error\"U\n" +
+	"\x05State\x12\x15\n" +
+	"\x11STATE_UNSPECIFIED\x10\x00\x12\v\n" +
+	"\aRUNNING\x10\x01\x12\r\n" +
+	"\tSUCCEEDED\x10\x02\x12\n" +
+	"\n" +
+	"\x06FAILED\x10\x03\x12\r\n" +
+	"\tCANCELLED\x10\x04B\t\n" +
+	"\aoutcome\"Q\n" +
+	"\x0fOperationResult\x12!\n" +
+	"\vprime_count\x18\x01 \x01(\x03H\x00R\n" +
+	"primeCount\x12\x12\n" +
+	"\x03pow\x18\x02 \x01(\tH\x00R\x03powB\a\n" +
+	"\x05value\">\n" +
+	"\x0eOperationError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\rR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"%\n" +
+	"\x13GetOperationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x17\n" +
+	"\x15ListOperationsRequest\"R\n" +
+	"\x16ListOperationsResponse\x128\n" +
+	"\n" +
+	"operations\x18\x01 \x03(\v2\x18.calculator.v1.OperationR\n" +
+	"operations\"(\n" +
+	"\x16CancelOperationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"[\n" +
+	"\x14WaitOperationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x123\n" +
+	"\atimeout\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\atimeout2\xba\f\n" +
+	"\x11CalculatorService\x12<\n" +
+	"\x03Add\x12\x19.calculator.v1.AddRequest\x1a\x1a.calculator.v1.AddResponse\x12D\n" +
+	"\tSumStream\x12\x19.calculator.v1.AddRequest\x1a\x1a.calculator.v1.AddResponse(\x01\x12J\n" +
+	"\vStatsStream\x12\x1b.calculator.v1.StatsRequest\x1a\x1c.calculator.v1.StatsResponse(\x01\x12E\n" +
+	"\bRangeAdd\x12\x1b.calculator.v1.RangeRequest\x1a\x1a.calculator.v1.AddResponse0\x01\x12D\n" +
+	"\aChatAdd\x12\x19.calculator.v1.AddRequest\x1a\x1a.calculator.v1.AddResponse(\x010\x01\x12I\n" +
+	"\bSubtract\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse\x12J\n" +
+	"\rSubtractBatch\x12\x1b.calculator.v1.BatchRequest\x1a\x1c.calculator.v1.BatchResponse\x12Q\n" +
+	"\fChatSubtract\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse(\x010\x01\x12I\n" +
+	"\bMultiply\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse\x12J\n" +
+	"\rMultiplyBatch\x12\x1b.calculator.v1.BatchRequest\x1a\x1c.calculator.v1.BatchResponse\x12Q\n" +
+	"\fChatMultiply\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse(\x010\x01\x12G\n" +
+	"\x06Divide\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.DivideResponse\x12N\n" +
+	"\vDivideBatch\x12\x1b.calculator.v1.BatchRequest\x1a\".calculator.v1.DivideBatchResponse\x12O\n" +
+	"\n" +
+	"ChatDivide\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.DivideResponse(\x010\x01\x12G\n" +
+	"\x06Modulo\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse\x12H\n" +
+	"\vModuloBatch\x12\x1b.calculator.v1.BatchRequest\x1a\x1c.calculator.v1.BatchResponse\x12O\n" +
+	"\n" +
+	"ChatModulo\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse(\x010\x01\x12D\n" +
+	"\x03Pow\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse\x12E\n" +
+	"\bPowBatch\x12\x1b.calculator.v1.BatchRequest\x1a\x1c.calculator.v1.BatchResponse\x12L\n" +
+	"\aChatPow\x12\x1e.calculator.v1.OperandsRequest\x1a\x1d.calculator.v1.ResultResponse(\x010\x01\x12K\n" +
+	"\bEvaluate\x12\x1e.calculator.v1.EvaluateRequest\x1a\x1f.calculator.v1.EvaluateResponse2\xbb\x01\n" +
+	"\x0eHistoryService\x12T\n" +
+	"\vListHistory\x12!.calculator.v1.ListHistoryRequest\x1a\".calculator.v1.ListHistoryResponse\x12S\n" +
+	"\rStreamHistory\x12#.calculator.v1.StreamHistoryRequest\x1a\x1b.calculator.v1.HistoryEntry0\x012\x9d\x01\n" +
+	"\fFaultService\x12C\n" +
+	"\tSetFaults\x12\x1a.calculator.v1.FaultConfig\x1a\x1a.calculator.v1.FaultConfig\x12H\n" +
+	"\tGetFaults\x12\x1f.calculator.v1.GetFaultsRequest\x1a\x1a.calculator.v1.FaultConfig2\xb5\x03\n" +
+	"\x10OperationService\x12P\n" +
+	"\x0eStartOperation\x12$.calculator.v1.StartOperationRequest\x1a\x18.calculator.v1.Operation\x12L\n" +
+	"\fGetOperation\x12\".calculator.v1.GetOperationRequest\x1a\x18.calculator.v1.Operation\x12]\n" +
+	"\x0eListOperations\x12$.calculator.v1.ListOperationsRequest\x1a%.calculator.v1.ListOperationsResponse\x12R\n" +
+	"\x0fCancelOperation\x12%.calculator.v1.CancelOperationRequest\x1a\x18.calculator.v1.Operation\x12N\n" +
+	"\rWaitOperation\x12#.calculator.v1.WaitOperationRequest\x1a\x18.calculator.v1.OperationB#Z!grpc-demo/api/gen/caculator/v1;v1b\x06proto3"
+
+var (
+	file_calculator_proto_rawDescOnce sync.Once
+	file_calculator_proto_rawDescData []byte
+)
+
+func file_calculator_proto_rawDescGZIP() []byte {
+	file_calculator_proto_rawDescOnce.Do(func() {
+		file_calculator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_calculator_proto_rawDesc), len(file_calculator_proto_rawDesc)))
+	})
+	return file_calculator_proto_rawDescData
+}
+
+var file_calculator_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 36)
+var file_calculator_proto_goTypes = []any{
+	(SessionCommand_Op)(0),         // 0: calculator.v1.SessionCommand.Op
+	(Operation_State)(0),           // 1: calculator.v1.Operation.State
+	(*AddRequest)(nil),             // 2: calculator.v1.AddRequest
+	(*AddResponse)(nil),            // 3: calculator.v1.AddResponse
+	(*SessionCommand)(nil),         // 4: calculator.v1.SessionCommand
+	(*SessionState)(nil),           // 5: calculator.v1.SessionState
+	(*RangeRequest)(nil),           // 6: calculator.v1.RangeRequest
+	(*OperandsRequest)(nil),        // 7: calculator.v1.OperandsRequest
+	(*ResultResponse)(nil),         // 8: calculator.v1.ResultResponse
+	(*DivideResponse)(nil),         // 9: calculator.v1.DivideResponse
+	(*BatchRequest)(nil),           // 10: calculator.v1.BatchRequest
+	(*BatchResponse)(nil),          // 11: calculator.v1.BatchResponse
+	(*DivideBatchResponse)(nil),    // 12: calculator.v1.DivideBatchResponse
+	(*EvaluateRequest)(nil),        // 13: calculator.v1.EvaluateRequest
+	(*EvaluateResponse)(nil),       // 14: calculator.v1.EvaluateResponse
+	(*StatsRequest)(nil),           // 15: calculator.v1.StatsRequest
+	(*Percentile)(nil),             // 16: calculator.v1.Percentile
+	(*StatsResponse)(nil),          // 17: calculator.v1.StatsResponse
+	(*HistoryEntry)(nil),           // 18: calculator.v1.HistoryEntry
+	(*HistoryFilter)(nil),          // 19: calculator.v1.HistoryFilter
+	(*ListHistoryRequest)(nil),     // 20: calculator.v1.ListHistoryRequest
+	(*ListHistoryResponse)(nil),    // 21: calculator.v1.ListHistoryResponse
+	(*StreamHistoryRequest)(nil),   // 22: calculator.v1.StreamHistoryRequest
+	(*FaultRule)(nil),              // 23: calculator.v1.FaultRule
+	(*FaultConfig)(nil),            // 24: calculator.v1.FaultConfig
+	(*GetFaultsRequest)(nil),       // 25: calculator.v1.GetFaultsRequest
+	(*StartOperationRequest)(nil),  // 26: calculator.v1.StartOperationRequest
+	(*CountPrimesJob)(nil),         // 27: calculator.v1.CountPrimesJob
+	(*PowJob)(nil),                 // 28: calculator.v1.PowJob
+	(*Operation)(nil),              // 29: calculator.v1.Operation
+	(*OperationResult)(nil),        // 30: calculator.v1.OperationResult
+	(*OperationError)(nil),         // 31: calculator.v1.OperationError
+	(*GetOperationRequest)(nil),    // 32: calculator.v1.GetOperationRequest
+	(*ListOperationsRequest)(nil),  // 33: calculator.v1.ListOperationsRequest
+	(*ListOperationsResponse)(nil), // 34: calculator.v1.ListOperationsResponse
+	(*CancelOperationRequest)(nil), // 35: calculator.v1.CancelOperationRequest
+	(*WaitOperationRequest)(nil),   // 36: calculator.v1.WaitOperationRequest
+	nil,                            // 37: calculator.v1.EvaluateRequest.VariablesEntry
+	(*timestamppb.Timestamp)(nil),  // 38: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),    // 39: google.protobuf.Duration
+}
+var file_calculator_proto_depIdxs = []int32{
+	4,  // 0: calculator.v1.AddRequest.session:type_name -> calculator.v1.SessionCommand
+	5,  // 1: calculator.v1.AddResponse.session:type_name -> calculator.v1.SessionState
+	0,  // 2: calculator.v1.SessionCommand.op:type_name -> calculator.v1.SessionCommand.Op
+	7,  // 3: calculator.v1.BatchRequest.items:type_name -> calculator.v1.OperandsRequest
+	9,  // 4: calculator.v1.DivideBatchResponse.results:type_name -> calculator.v1.DivideResponse
+	37, // 5: calculator.v1.EvaluateRequest.variables:type_name -> calculator.v1.EvaluateRequest.VariablesEntry
+	16, // 6: calculator.v1.StatsResponse.percentiles:type_name -> calculator.v1.Percentile
+	38, // 7: calculator.v1.HistoryEntry.time:type_name -> google.protobuf.Timestamp
+	39, // 8: calculator.v1.HistoryEntry.latency:type_name -> google.protobuf.Duration
+	38, // 9: calculator.v1.HistoryFilter.since:type_name -> google.protobuf.Timestamp
+	38, // 10: calculator.v1.HistoryFilter.until:type_name -> google.protobuf.Timestamp
+	19, // 11: calculator.v1.ListHistoryRequest.filter:type_name -> calculator.v1.HistoryFilter
+	18, // 12: calculator.v1.ListHistoryResponse.entries:type_name -> calculator.v1.HistoryEntry
+	19, // 13: calculator.v1.StreamHistoryRequest.filter:type_name -> calculator.v1.HistoryFilter
+	39, // 14: calculator.v1.FaultRule.delay:type_name -> google.protobuf.Duration
+	23, // 15: calculator.v1.FaultConfig.rules:type_name -> calculator.v1.FaultRule
+	27, // 16: calculator.v1.StartOperationRequest.count_primes:type_name -> calculator.v1.CountPrimesJob
+	28, // 17: calculator.v1.StartOperationRequest.pow:type_name -> calculator.v1.PowJob
+	1,  // 18: calculator.v1.Operation.state:type_name -> calculator.v1.Operation.State
+	38, // 19: calculator.v1.Operation.create_time:type_name -> google.protobuf.Timestamp
+	38, // 20: calculator.v1.Operation.update_time:type_name -> google.protobuf.Timestamp
+	30, // 21: calculator.v1.Operation.result:type_name -> calculator.v1.OperationResult
+	31, // 22: calculator.v1.Operation.error:type_name -> calculator.v1.OperationError
+	29, // 23: calculator.v1.ListOperationsResponse.operations:type_name -> calculator.v1.Operation
+	39, // 24: calculator.v1.WaitOperationRequest.timeout:type_name -> google.protobuf.Duration
+	2,  // 25: calculator.v1.CalculatorService.Add:input_type -> calculator.v1.AddRequest
+	2,  // 26: calculator.v1.CalculatorService.SumStream:input_type -> calculator.v1.AddRequest
+	15, // 27: calculator.v1.CalculatorService.StatsStream:input_type -> calculator.v1.StatsRequest
+	6,  // 28: calculator.v1.CalculatorService.RangeAdd:input_type -> calculator.v1.RangeRequest
+	2,  // 29: calculator.v1.CalculatorService.ChatAdd:input_type -> calculator.v1.AddRequest
+	7,  // 30: calculator.v1.CalculatorService.Subtract:input_type -> calculator.v1.OperandsRequest
+	10, // 31: calculator.v1.CalculatorService.SubtractBatch:input_type -> calculator.v1.BatchRequest
+	7,  // 32: calculator.v1.CalculatorService.ChatSubtract:input_type -> calculator.v1.OperandsRequest
+	7,  // 33: calculator.v1.CalculatorService.Multiply:input_type -> calculator.v1.OperandsRequest
+	10, // 34: calculator.v1.CalculatorService.MultiplyBatch:input_type -> calculator.v1.BatchRequest
+	7,  // 35: calculator.v1.CalculatorService.ChatMultiply:input_type -> calculator.v1.OperandsRequest
+	7,  // 36: calculator.v1.CalculatorService.Divide:input_type -> calculator.v1.OperandsRequest
+	10, // 37: calculator.v1.CalculatorService.DivideBatch:input_type -> calculator.v1.BatchRequest
+	7,  // 38: calculator.v1.CalculatorService.ChatDivide:input_type -> calculator.v1.OperandsRequest
+	7,  // 39: calculator.v1.CalculatorService.Modulo:input_type -> calculator.v1.OperandsRequest
+	10, // 40: calculator.v1.CalculatorService.ModuloBatch:input_type -> calculator.v1.BatchRequest
+	7,  // 41: calculator.v1.CalculatorService.ChatModulo:input_type -> calculator.v1.OperandsRequest
+	7,  // 42: calculator.v1.CalculatorService.Pow:input_type -> calculator.v1.OperandsRequest
+	10, // 43: calculator.v1.CalculatorService.PowBatch:input_type -> calculator.v1.BatchRequest
+	7,  // 44: calculator.v1.CalculatorService.ChatPow:input_type -> calculator.v1.OperandsRequest
+	13, // 45: calculator.v1.CalculatorService.Evaluate:input_type -> calculator.v1.EvaluateRequest
+	20, // 46: calculator.v1.HistoryService.ListHistory:input_type -> calculator.v1.ListHistoryRequest
+	22, // 47: calculator.v1.HistoryService.StreamHistory:input_type -> calculator.v1.StreamHistoryRequest
+	24, // 48: calculator.v1.FaultService.SetFaults:input_type -> calculator.v1.FaultConfig
+	25, // 49: calculator.v1.FaultService.GetFaults:input_type -> calculator.v1.GetFaultsRequest
+	26, // 50: calculator.v1.OperationService.StartOperation:input_type -> calculator.v1.StartOperationRequest
+	32, // 51: calculator.v1.OperationService.GetOperation:input_type -> calculator.v1.GetOperationRequest
+	33, // 52: calculator.v1.OperationService.ListOperations:input_type -> calculator.v1.ListOperationsRequest
+	35, // 53: calculator.v1.OperationService.CancelOperation:input_type -> calculator.v1.CancelOperationRequest
+	36, // 54: calculator.v1.OperationService.WaitOperation:input_type -> calculator.v1.WaitOperationRequest
+	3,  // 55: calculator.v1.CalculatorService.Add:output_type -> calculator.v1.AddResponse
+	3,  // 56: calculator.v1.CalculatorService.SumStream:output_type -> calculator.v1.AddResponse
+	17, // 57: calculator.v1.CalculatorService.StatsStream:output_type -> calculator.v1.StatsResponse
+	3,  // 58: calculator.v1.CalculatorService.RangeAdd:output_type -> calculator.v1.AddResponse
+	3,  // 59: calculator.v1.CalculatorService.ChatAdd:output_type -> calculator.v1.AddResponse
+	8,  // 60: calculator.v1.CalculatorService.Subtract:output_type -> calculator.v1.ResultResponse
+	11, // 61: calculator.v1.CalculatorService.SubtractBatch:output_type -> calculator.v1.BatchResponse
+	8,  // 62: calculator.v1.CalculatorService.ChatSubtract:output_type -> calculator.v1.ResultResponse
+	8,  // 63: calculator.v1.CalculatorService.Multiply:output_type -> calculator.v1.ResultResponse
+	11, // 64: calculator.v1.CalculatorService.MultiplyBatch:output_type -> calculator.v1.BatchResponse
+	8,  // 65: calculator.v1.CalculatorService.ChatMultiply:output_type -> calculator.v1.ResultResponse
+	9,  // 66: calculator.v1.CalculatorService.Divide:output_type -> calculator.v1.DivideResponse
+	12, // 67: calculator.v1.CalculatorService.DivideBatch:output_type -> calculator.v1.DivideBatchResponse
+	9,  // 68: calculator.v1.CalculatorService.ChatDivide:output_type -> calculator.v1.DivideResponse
+	8,  // 69: calculator.v1.CalculatorService.Modulo:output_type -> calculator.v1.ResultResponse
+	11, // 70: calculator.v1.CalculatorService.ModuloBatch:output_type -> calculator.v1.BatchResponse
+	8,  // 71: calculator.v1.CalculatorService.ChatModulo:output_type -> calculator.v1.ResultResponse
+	8,  // 72: calculator.v1.CalculatorService.Pow:output_type -> calculator.v1.ResultResponse
+	11, // 73: calculator.v1.CalculatorService.PowBatch:output_type -> calculator.v1.BatchResponse
+	8,  // 74: calculator.v1.CalculatorService.ChatPow:output_type -> calculator.v1.ResultResponse
+	14, // 75: calculator.v1.CalculatorService.Evaluate:output_type -> calculator.v1.EvaluateResponse
+	21, // 76: calculator.v1.HistoryService.ListHistory:output_type -> calculator.v1.ListHistoryResponse
+	18, // 77: calculator.v1.HistoryService.StreamHistory:output_type -> calculator.v1.HistoryEntry
+	24, // 78: calculator.v1.FaultService.SetFaults:output_type -> calculator.v1.FaultConfig
+	24, // 79: calculator.v1.FaultService.GetFaults:output_type -> calculator.v1.FaultConfig
+	29, // 80: calculator.v1.OperationService.StartOperation:output_type -> calculator.v1.Operation
+	29, // 81: calculator.v1.OperationService.GetOperation:output_type -> calculator.v1.Operation
+	34, // 82: calculator.v1.OperationService.ListOperations:output_type -> calculator.v1.ListOperationsResponse
+	29, // 83: calculator.v1.OperationService.CancelOperation:output_type -> calculator.v1.Operation
+	29, // 84: calculator.v1.OperationService.WaitOperation:output_type -> calculator.v1.Operation
+	55, // [55:85] is the sub-list for method output_type
+	25, // [25:55] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
+}
+
+func init() { file_calculator_proto_init() }
+func file_calculator_proto_init() {
+	if File_calculator_proto != nil {
+		return
+	}
+	file_calculator_proto_msgTypes[20].OneofWrappers = []any{}
+	file_calculator_proto_msgTypes[24].OneofWrappers = []any{
+		(*StartOperationRequest_CountPrimes)(nil),
+		(*StartOperationRequest_Pow)(nil),
+	}
+	file_calculator_proto_msgTypes[27].OneofWrappers = []any{
+		(*Operation_Result)(nil),
+		(*Operation_Error)(nil),
+	}
+	file_calculator_proto_msgTypes[28].OneofWrappers = []any{
+		(*OperationResult_PrimeCount)(nil),
+		(*OperationResult_Pow)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_calculator_proto_rawDesc), len(file_calculator_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      2,
+			NumMessages:   36,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   4,
 		},
 		GoTypes:           file_calculator_proto_goTypes,
 		DependencyIndexes: file_calculator_proto_depIdxs,
+		EnumInfos:         file_calculator_proto_enumTypes,
 		MessageInfos:      file_calculator_proto_msgTypes,
 	}.Build()
 	File_calculator_proto = out.File
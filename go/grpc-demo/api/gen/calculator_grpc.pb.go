@@ -19,10 +19,27 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	CalculatorService_Add_FullMethodName       = "/calculator.v1.CalculatorService/Add"
-	CalculatorService_SumStream_FullMethodName = "/calculator.v1.CalculatorService/SumStream"
-	CalculatorService_RangeAdd_FullMethodName  = "/calculator.v1.CalculatorService/RangeAdd"
-	CalculatorService_ChatAdd_FullMethodName   = "/calculator.v1.CalculatorService/ChatAdd"
+	CalculatorService_Add_FullMethodName           = "/calculator.v1.CalculatorService/Add"
+	CalculatorService_SumStream_FullMethodName     = "/calculator.v1.CalculatorService/SumStream"
+	CalculatorService_StatsStream_FullMethodName   = "/calculator.v1.CalculatorService/StatsStream"
+	CalculatorService_RangeAdd_FullMethodName      = "/calculator.v1.CalculatorService/RangeAdd"
+	CalculatorService_ChatAdd_FullMethodName       = "/calculator.v1.CalculatorService/ChatAdd"
+	CalculatorService_Subtract_FullMethodName      = "/calculator.v1.CalculatorService/Subtract"
+	CalculatorService_SubtractBatch_FullMethodName = "/calculator.v1.CalculatorService/SubtractBatch"
+	CalculatorService_ChatSubtract_FullMethodName  = "/calculator.v1.CalculatorService/ChatSubtract"
+	CalculatorService_Multiply_FullMethodName      = "/calculator.v1.CalculatorService/Multiply"
+	CalculatorService_MultiplyBatch_FullMethodName = "/calculator.v1.CalculatorService/MultiplyBatch"
+	CalculatorService_ChatMultiply_FullMethodName  = "/calculator.v1.CalculatorService/ChatMultiply"
+	CalculatorService_Divide_FullMethodName        = "/calculator.v1.CalculatorService/Divide"
+	CalculatorService_DivideBatch_FullMethodName   = "/calculator.v1.CalculatorService/DivideBatch"
+	CalculatorService_ChatDivide_FullMethodName    = "/calculator.v1.CalculatorService/ChatDivide"
+	CalculatorService_Modulo_FullMethodName        = "/calculator.v1.CalculatorService/Modulo"
+	CalculatorService_ModuloBatch_FullMethodName   = "/calculator.v1.CalculatorService/ModuloBatch"
+	CalculatorService_ChatModulo_FullMethodName    = "/calculator.v1.CalculatorService/ChatModulo"
+	CalculatorService_Pow_FullMethodName           = "/calculator.v1.CalculatorService/Pow"
+	CalculatorService_PowBatch_FullMethodName      = "/calculator.v1.CalculatorService/PowBatch"
+	CalculatorService_ChatPow_FullMethodName       = "/calculator.v1.CalculatorService/ChatPow"
+	CalculatorService_Evaluate_FullMethodName      = "/calculator.v1.CalculatorService/Evaluate"
 )
 
 // CalculatorServiceClient is the client API for CalculatorService service.
@@ -31,8 +48,33 @@ const (
 type CalculatorServiceClient interface {
 	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*AddResponse, error)
 	SumStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[AddRequest, AddResponse], error)
+	StatsStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[StatsRequest, StatsResponse], error)
 	RangeAdd(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AddResponse], error)
+	// bidirectional；带 session 字段的消息是会话命令，见 SessionCommand
 	ChatAdd(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AddRequest, AddResponse], error)
+	// a - b
+	Subtract(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	SubtractBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	ChatSubtract(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error)
+	// a * b
+	Multiply(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	MultiplyBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	ChatMultiply(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error)
+	// a / b，商向零截断，余数与 a 同号；b 为 0 时返回 INVALID_ARGUMENT
+	Divide(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*DivideResponse, error)
+	DivideBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*DivideBatchResponse, error)
+	ChatDivide(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, DivideResponse], error)
+	// a mod b，结果总是落在 [0, |b|)；b 为 0 时返回 INVALID_ARGUMENT
+	Modulo(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	ModuloBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	ChatModulo(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error)
+	// a ^ b，b 为负数时返回 INVALID_ARGUMENT
+	Pow(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	PowBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	ChatPow(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error)
+	// 计算中缀表达式，支持括号、+ - * / % ^、一元负号、变量以及 min/max/abs/gcd 函数；
+	// 语法错误返回 INVALID_ARGUMENT，ErrorInfo.metadata["position"] 为出错位置的字节偏移
+	Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error)
 }
 
 type calculatorServiceClient struct {
@@ -66,9 +108,22 @@ func (c *calculatorServiceClient) SumStream(ctx context.Context, opts ...grpc.Ca
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type CalculatorService_SumStreamClient = grpc.ClientStreamingClient[AddRequest, AddResponse]
 
+func (c *calculatorServiceClient) StatsStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[StatsRequest, StatsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[1], CalculatorService_StatsStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StatsRequest, StatsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_StatsStreamClient = grpc.ClientStreamingClient[StatsRequest, StatsResponse]
+
 func (c *calculatorServiceClient) RangeAdd(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AddResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[1], CalculatorService_RangeAdd_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[2], CalculatorService_RangeAdd_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +142,7 @@ type CalculatorService_RangeAddClient = grpc.ServerStreamingClient[AddResponse]
 
 func (c *calculatorServiceClient) ChatAdd(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AddRequest, AddResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[2], CalculatorService_ChatAdd_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[3], CalculatorService_ChatAdd_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -98,14 +153,214 @@ func (c *calculatorServiceClient) ChatAdd(ctx context.Context, opts ...grpc.Call
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type CalculatorService_ChatAddClient = grpc.BidiStreamingClient[AddRequest, AddResponse]
 
+func (c *calculatorServiceClient) Subtract(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_Subtract_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) SubtractBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_SubtractBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ChatSubtract(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[4], CalculatorService_ChatSubtract_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[OperandsRequest, ResultResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatSubtractClient = grpc.BidiStreamingClient[OperandsRequest, ResultResponse]
+
+func (c *calculatorServiceClient) Multiply(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_Multiply_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) MultiplyBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_MultiplyBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ChatMultiply(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[5], CalculatorService_ChatMultiply_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[OperandsRequest, ResultResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatMultiplyClient = grpc.BidiStreamingClient[OperandsRequest, ResultResponse]
+
+func (c *calculatorServiceClient) Divide(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*DivideResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DivideResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_Divide_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) DivideBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*DivideBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DivideBatchResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_DivideBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ChatDivide(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, DivideResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[6], CalculatorService_ChatDivide_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[OperandsRequest, DivideResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatDivideClient = grpc.BidiStreamingClient[OperandsRequest, DivideResponse]
+
+func (c *calculatorServiceClient) Modulo(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_Modulo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ModuloBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_ModuloBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ChatModulo(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[7], CalculatorService_ChatModulo_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[OperandsRequest, ResultResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatModuloClient = grpc.BidiStreamingClient[OperandsRequest, ResultResponse]
+
+func (c *calculatorServiceClient) Pow(ctx context.Context, in *OperandsRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_Pow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) PowBatch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_PowBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ChatPow(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[OperandsRequest, ResultResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[8], CalculatorService_ChatPow_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[OperandsRequest, ResultResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatPowClient = grpc.BidiStreamingClient[OperandsRequest, ResultResponse]
+
+func (c *calculatorServiceClient) Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvaluateResponse)
+	err := c.cc.Invoke(ctx, CalculatorService_Evaluate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CalculatorServiceServer is the server API for CalculatorService service.
 // All implementations must embed UnimplementedCalculatorServiceServer
 // for forward compatibility.
 type CalculatorServiceServer interface {
 	Add(context.Context, *AddRequest) (*AddResponse, error)
 	SumStream(grpc.ClientStreamingServer[AddRequest, AddResponse]) error
+	StatsStream(grpc.ClientStreamingServer[StatsRequest, StatsResponse]) error
 	RangeAdd(*RangeRequest, grpc.ServerStreamingServer[AddResponse]) error
+	// bidirectional；带 session 字段的消息是会话命令，见 SessionCommand
 	ChatAdd(grpc.BidiStreamingServer[AddRequest, AddResponse]) error
+	// a - b
+	Subtract(context.Context, *OperandsRequest) (*ResultResponse, error)
+	SubtractBatch(context.Context, *BatchRequest) (*BatchResponse, error)
+	ChatSubtract(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error
+	// a * b
+	Multiply(context.Context, *OperandsRequest) (*ResultResponse, error)
+	MultiplyBatch(context.Context, *BatchRequest) (*BatchResponse, error)
+	ChatMultiply(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error
+	// a / b，商向零截断，余数与 a 同号；b 为 0 时返回 INVALID_ARGUMENT
+	Divide(context.Context, *OperandsRequest) (*DivideResponse, error)
+	DivideBatch(context.Context, *BatchRequest) (*DivideBatchResponse, error)
+	ChatDivide(grpc.BidiStreamingServer[OperandsRequest, DivideResponse]) error
+	// a mod b，结果总是落在 [0, |b|)；b 为 0 时返回 INVALID_ARGUMENT
+	Modulo(context.Context, *OperandsRequest) (*ResultResponse, error)
+	ModuloBatch(context.Context, *BatchRequest) (*BatchResponse, error)
+	ChatModulo(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error
+	// a ^ b，b 为负数时返回 INVALID_ARGUMENT
+	Pow(context.Context, *OperandsRequest) (*ResultResponse, error)
+	PowBatch(context.Context, *BatchRequest) (*BatchResponse, error)
+	ChatPow(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error
+	// 计算中缀表达式，支持括号、+ - * / % ^、一元负号、变量以及 min/max/abs/gcd 函数；
+	// 语法错误返回 INVALID_ARGUMENT，ErrorInfo.metadata["position"] 为出错位置的字节偏移
+	Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error)
 	mustEmbedUnimplementedCalculatorServiceServer()
 }
 
@@ -122,12 +377,63 @@ func (UnimplementedCalculatorServiceServer) Add(context.Context, *AddRequest) (*
 func (UnimplementedCalculatorServiceServer) SumStream(grpc.ClientStreamingServer[AddRequest, AddResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method SumStream not implemented")
 }
+func (UnimplementedCalculatorServiceServer) StatsStream(grpc.ClientStreamingServer[StatsRequest, StatsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StatsStream not implemented")
+}
 func (UnimplementedCalculatorServiceServer) RangeAdd(*RangeRequest, grpc.ServerStreamingServer[AddResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method RangeAdd not implemented")
 }
 func (UnimplementedCalculatorServiceServer) ChatAdd(grpc.BidiStreamingServer[AddRequest, AddResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method ChatAdd not implemented")
 }
+func (UnimplementedCalculatorServiceServer) Subtract(context.Context, *OperandsRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Subtract not implemented")
+}
+func (UnimplementedCalculatorServiceServer) SubtractBatch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubtractBatch not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ChatSubtract(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatSubtract not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Multiply(context.Context, *OperandsRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Multiply not implemented")
+}
+func (UnimplementedCalculatorServiceServer) MultiplyBatch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MultiplyBatch not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ChatMultiply(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatMultiply not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Divide(context.Context, *OperandsRequest) (*DivideResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Divide not implemented")
+}
+func (UnimplementedCalculatorServiceServer) DivideBatch(context.Context, *BatchRequest) (*DivideBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DivideBatch not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ChatDivide(grpc.BidiStreamingServer[OperandsRequest, DivideResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatDivide not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Modulo(context.Context, *OperandsRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Modulo not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ModuloBatch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModuloBatch not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ChatModulo(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatModulo not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Pow(context.Context, *OperandsRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pow not implemented")
+}
+func (UnimplementedCalculatorServiceServer) PowBatch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PowBatch not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ChatPow(grpc.BidiStreamingServer[OperandsRequest, ResultResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ChatPow not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Evaluate not implemented")
+}
 func (UnimplementedCalculatorServiceServer) mustEmbedUnimplementedCalculatorServiceServer() {}
 func (UnimplementedCalculatorServiceServer) testEmbeddedByValue()                           {}
 
@@ -174,6 +480,13 @@ func _CalculatorService_SumStream_Handler(srv interface{}, stream grpc.ServerStr
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type CalculatorService_SumStreamServer = grpc.ClientStreamingServer[AddRequest, AddResponse]
 
+func _CalculatorService_StatsStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).StatsStream(&grpc.GenericServerStream[StatsRequest, StatsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_StatsStreamServer = grpc.ClientStreamingServer[StatsRequest, StatsResponse]
+
 func _CalculatorService_RangeAdd_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(RangeRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -192,35 +505,917 @@ func _CalculatorService_ChatAdd_Handler(srv interface{}, stream grpc.ServerStrea
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type CalculatorService_ChatAddServer = grpc.BidiStreamingServer[AddRequest, AddResponse]
 
-// CalculatorService_ServiceDesc is the grpc.ServiceDesc for CalculatorService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var CalculatorService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "calculator.v1.CalculatorService",
-	HandlerType: (*CalculatorServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Add",
-			Handler:    _CalculatorService_Add_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "SumStream",
-			Handler:       _CalculatorService_SumStream_Handler,
-			ClientStreams: true,
-		},
-		{
-			StreamName:    "RangeAdd",
-			Handler:       _CalculatorService_RangeAdd_Handler,
-			ServerStreams: true,
-		},
-		{
-			StreamName:    "ChatAdd",
-			Handler:       _CalculatorService_ChatAdd_Handler,
-			ServerStreams: true,
-			ClientStreams: true,
+func _CalculatorService_Subtract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Subtract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_Subtract_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Subtract(ctx, req.(*OperandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_SubtractBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).SubtractBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_SubtractBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).SubtractBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ChatSubtract_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).ChatSubtract(&grpc.GenericServerStream[OperandsRequest, ResultResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatSubtractServer = grpc.BidiStreamingServer[OperandsRequest, ResultResponse]
+
+func _CalculatorService_Multiply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Multiply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_Multiply_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Multiply(ctx, req.(*OperandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_MultiplyBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).MultiplyBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_MultiplyBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).MultiplyBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ChatMultiply_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).ChatMultiply(&grpc.GenericServerStream[OperandsRequest, ResultResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatMultiplyServer = grpc.BidiStreamingServer[OperandsRequest, ResultResponse]
+
+func _CalculatorService_Divide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Divide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_Divide_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Divide(ctx, req.(*OperandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_DivideBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).DivideBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_DivideBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).DivideBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ChatDivide_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).ChatDivide(&grpc.GenericServerStream[OperandsRequest, DivideResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatDivideServer = grpc.BidiStreamingServer[OperandsRequest, DivideResponse]
+
+func _CalculatorService_Modulo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Modulo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_Modulo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Modulo(ctx, req.(*OperandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ModuloBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).ModuloBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_ModuloBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).ModuloBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ChatModulo_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).ChatModulo(&grpc.GenericServerStream[OperandsRequest, ResultResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatModuloServer = grpc.BidiStreamingServer[OperandsRequest, ResultResponse]
+
+func _CalculatorService_Pow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Pow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_Pow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Pow(ctx, req.(*OperandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_PowBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).PowBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_PowBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).PowBatch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ChatPow_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).ChatPow(&grpc.GenericServerStream[OperandsRequest, ResultResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorService_ChatPowServer = grpc.BidiStreamingServer[OperandsRequest, ResultResponse]
+
+func _CalculatorService_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorService_Evaluate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Evaluate(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CalculatorService_ServiceDesc is the grpc.ServiceDesc for CalculatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CalculatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.v1.CalculatorService",
+	HandlerType: (*CalculatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Add",
+			Handler:    _CalculatorService_Add_Handler,
+		},
+		{
+			MethodName: "Subtract",
+			Handler:    _CalculatorService_Subtract_Handler,
+		},
+		{
+			MethodName: "SubtractBatch",
+			Handler:    _CalculatorService_SubtractBatch_Handler,
+		},
+		{
+			MethodName: "Multiply",
+			Handler:    _CalculatorService_Multiply_Handler,
+		},
+		{
+			MethodName: "MultiplyBatch",
+			Handler:    _CalculatorService_MultiplyBatch_Handler,
+		},
+		{
+			MethodName: "Divide",
+			Handler:    _CalculatorService_Divide_Handler,
+		},
+		{
+			MethodName: "DivideBatch",
+			Handler:    _CalculatorService_DivideBatch_Handler,
+		},
+		{
+			MethodName: "Modulo",
+			Handler:    _CalculatorService_Modulo_Handler,
+		},
+		{
+			MethodName: "ModuloBatch",
+			Handler:    _CalculatorService_ModuloBatch_Handler,
+		},
+		{
+			MethodName: "Pow",
+			Handler:    _CalculatorService_Pow_Handler,
+		},
+		{
+			MethodName: "PowBatch",
+			Handler:    _CalculatorService_PowBatch_Handler,
+		},
+		{
+			MethodName: "Evaluate",
+			Handler:    _CalculatorService_Evaluate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SumStream",
+			Handler:       _CalculatorService_SumStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "StatsStream",
+			Handler:       _CalculatorService_StatsStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "RangeAdd",
+			Handler:       _CalculatorService_RangeAdd_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ChatAdd",
+			Handler:       _CalculatorService_ChatAdd_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ChatSubtract",
+			Handler:       _CalculatorService_ChatSubtract_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ChatMultiply",
+			Handler:       _CalculatorService_ChatMultiply_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ChatDivide",
+			Handler:       _CalculatorService_ChatDivide_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ChatModulo",
+			Handler:       _CalculatorService_ChatModulo_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ChatPow",
+			Handler:       _CalculatorService_ChatPow_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "calculator.proto",
+}
+
+const (
+	HistoryService_ListHistory_FullMethodName   = "/calculator.v1.HistoryService/ListHistory"
+	HistoryService_StreamHistory_FullMethodName = "/calculator.v1.HistoryService/StreamHistory"
+)
+
+// HistoryServiceClient is the client API for HistoryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 计算历史与审计：服务端记录每一次计算调用，需要 audit scope 才能读取
+type HistoryServiceClient interface {
+	// 按时间倒序分页查询，page_token 为上一页返回的 next_page_token
+	ListHistory(ctx context.Context, in *ListHistoryRequest, opts ...grpc.CallOption) (*ListHistoryResponse, error)
+	// 先按 id 升序补发 id 大于 since_id 的历史记录，再实时推送新记录；客户端跟不上时流以 RESOURCE_EXHAUSTED 结束
+	StreamHistory(ctx context.Context, in *StreamHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HistoryEntry], error)
+}
+
+type historyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHistoryServiceClient(cc grpc.ClientConnInterface) HistoryServiceClient {
+	return &historyServiceClient{cc}
+}
+
+func (c *historyServiceClient) ListHistory(ctx context.Context, in *ListHistoryRequest, opts ...grpc.CallOption) (*ListHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListHistoryResponse)
+	err := c.cc.Invoke(ctx, HistoryService_ListHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *historyServiceClient) StreamHistory(ctx context.Context, in *StreamHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HistoryEntry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &HistoryService_ServiceDesc.Streams[0], HistoryService_StreamHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamHistoryRequest, HistoryEntry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HistoryService_StreamHistoryClient = grpc.ServerStreamingClient[HistoryEntry]
+
+// HistoryServiceServer is the server API for HistoryService service.
+// All implementations must embed UnimplementedHistoryServiceServer
+// for forward compatibility.
+//
+// 计算历史与审计：服务端记录每一次计算调用，需要 audit scope 才能读取
+type HistoryServiceServer interface {
+	// 按时间倒序分页查询，page_token 为上一页返回的 next_page_token
+	ListHistory(context.Context, *ListHistoryRequest) (*ListHistoryResponse, error)
+	// 先按 id 升序补发 id 大于 since_id 的历史记录，再实时推送新记录；客户端跟不上时流以 RESOURCE_EXHAUSTED 结束
+	StreamHistory(*StreamHistoryRequest, grpc.ServerStreamingServer[HistoryEntry]) error
+	mustEmbedUnimplementedHistoryServiceServer()
+}
+
+// UnimplementedHistoryServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHistoryServiceServer struct{}
+
+func (UnimplementedHistoryServiceServer) ListHistory(context.Context, *ListHistoryRequest) (*ListHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHistory not implemented")
+}
+func (UnimplementedHistoryServiceServer) StreamHistory(*StreamHistoryRequest, grpc.ServerStreamingServer[HistoryEntry]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamHistory not implemented")
+}
+func (UnimplementedHistoryServiceServer) mustEmbedUnimplementedHistoryServiceServer() {}
+func (UnimplementedHistoryServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeHistoryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HistoryServiceServer will
+// result in compilation errors.
+type UnsafeHistoryServiceServer interface {
+	mustEmbedUnimplementedHistoryServiceServer()
+}
+
+func RegisterHistoryServiceServer(s grpc.ServiceRegistrar, srv HistoryServiceServer) {
+	// If the following call pancis, it indicates UnimplementedHistoryServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HistoryService_ServiceDesc, srv)
+}
+
+func _HistoryService_ListHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HistoryServiceServer).ListHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HistoryService_ListHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HistoryServiceServer).ListHistory(ctx, req.(*ListHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HistoryService_StreamHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HistoryServiceServer).StreamHistory(m, &grpc.GenericServerStream[StreamHistoryRequest, HistoryEntry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HistoryService_StreamHistoryServer = grpc.ServerStreamingServer[HistoryEntry]
+
+// HistoryService_ServiceDesc is the grpc.ServiceDesc for HistoryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HistoryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.v1.HistoryService",
+	HandlerType: (*HistoryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListHistory",
+			Handler:    _HistoryService_ListHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamHistory",
+			Handler:       _HistoryService_StreamHistory_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "calculator.proto",
+}
+
+const (
+	FaultService_SetFaults_FullMethodName = "/calculator.v1.FaultService/SetFaults"
+	FaultService_GetFaults_FullMethodName = "/calculator.v1.FaultService/GetFaults"
+)
+
+// FaultServiceClient is the client API for FaultService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 故障注入：按方法注入延迟、错误和流中断，用于在真实服务端上验证客户端的重试和超时处理；
+// 只有服务端开启故障注入时才注册，需要 admin scope
+type FaultServiceClient interface {
+	// 整体替换故障规则，rules 为空即清除所有规则
+	SetFaults(ctx context.Context, in *FaultConfig, opts ...grpc.CallOption) (*FaultConfig, error)
+	GetFaults(ctx context.Context, in *GetFaultsRequest, opts ...grpc.CallOption) (*FaultConfig, error)
+}
+
+type faultServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFaultServiceClient(cc grpc.ClientConnInterface) FaultServiceClient {
+	return &faultServiceClient{cc}
+}
+
+func (c *faultServiceClient) SetFaults(ctx context.Context, in *FaultConfig, opts ...grpc.CallOption) (*FaultConfig, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FaultConfig)
+	err := c.cc.Invoke(ctx, FaultService_SetFaults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *faultServiceClient) GetFaults(ctx context.Context, in *GetFaultsRequest, opts ...grpc.CallOption) (*FaultConfig, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FaultConfig)
+	err := c.cc.Invoke(ctx, FaultService_GetFaults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FaultServiceServer is the server API for FaultService service.
+// All implementations must embed UnimplementedFaultServiceServer
+// for forward compatibility.
+//
+// 故障注入：按方法注入延迟、错误和流中断，用于在真实服务端上验证客户端的重试和超时处理；
+// 只有服务端开启故障注入时才注册，需要 admin scope
+type FaultServiceServer interface {
+	// 整体替换故障规则，rules 为空即清除所有规则
+	SetFaults(context.Context, *FaultConfig) (*FaultConfig, error)
+	GetFaults(context.Context, *GetFaultsRequest) (*FaultConfig, error)
+	mustEmbedUnimplementedFaultServiceServer()
+}
+
+// UnimplementedFaultServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFaultServiceServer struct{}
+
+func (UnimplementedFaultServiceServer) SetFaults(context.Context, *FaultConfig) (*FaultConfig, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFaults not implemented")
+}
+func (UnimplementedFaultServiceServer) GetFaults(context.Context, *GetFaultsRequest) (*FaultConfig, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFaults not implemented")
+}
+func (UnimplementedFaultServiceServer) mustEmbedUnimplementedFaultServiceServer() {}
+func (UnimplementedFaultServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeFaultServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FaultServiceServer will
+// result in compilation errors.
+type UnsafeFaultServiceServer interface {
+	mustEmbedUnimplementedFaultServiceServer()
+}
+
+func RegisterFaultServiceServer(s grpc.ServiceRegistrar, srv FaultServiceServer) {
+	// If the following call pancis, it indicates UnimplementedFaultServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FaultService_ServiceDesc, srv)
+}
+
+func _FaultService_SetFaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FaultConfig)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaultServiceServer).SetFaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FaultService_SetFaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaultServiceServer).SetFaults(ctx, req.(*FaultConfig))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FaultService_GetFaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFaultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaultServiceServer).GetFaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FaultService_GetFaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaultServiceServer).GetFaults(ctx, req.(*GetFaultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// FaultService_ServiceDesc is the grpc.ServiceDesc for FaultService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FaultService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.v1.FaultService",
+	HandlerType: (*FaultServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetFaults",
+			Handler:    _FaultService_SetFaults_Handler,
+		},
+		{
+			MethodName: "GetFaults",
+			Handler:    _FaultService_GetFaults_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "calculator.proto",
+}
+
+const (
+	OperationService_StartOperation_FullMethodName  = "/calculator.v1.OperationService/StartOperation"
+	OperationService_GetOperation_FullMethodName    = "/calculator.v1.OperationService/GetOperation"
+	OperationService_ListOperations_FullMethodName  = "/calculator.v1.OperationService/ListOperations"
+	OperationService_CancelOperation_FullMethodName = "/calculator.v1.OperationService/CancelOperation"
+	OperationService_WaitOperation_FullMethodName   = "/calculator.v1.OperationService/WaitOperation"
+)
+
+// OperationServiceClient is the client API for OperationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// 长时间运行的计算任务：StartOperation 立即返回，任务在服务端后台执行，发起调用的客户端断开后仍继续运行，
+// 结果在任务结束后保留一段时间，可以用 GetOperation 或 WaitOperation 取回；保留的结果总大小有上限，
+// 超出时最早结束的任务提前被删除。任务只对发起它的调用方可见，其他调用方的任务同样返回 NOT_FOUND
+type OperationServiceClient interface {
+	// 运行中的任务过多时返回 RESOURCE_EXHAUSTED
+	StartOperation(ctx context.Context, in *StartOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	// 按创建时间倒序返回调用方的所有任务；不包含成功任务的 result，需要用 GetOperation 取回
+	ListOperations(ctx context.Context, in *ListOperationsRequest, opts ...grpc.CallOption) (*ListOperationsResponse, error)
+	// 请求取消任务，返回请求时的状态；任务退出后变为 CANCELLED，已经结束的任务不受影响
+	CancelOperation(ctx context.Context, in *CancelOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	// 等待任务结束，最多等待 timeout，到时任务还没结束则返回当前状态
+	WaitOperation(ctx context.Context, in *WaitOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+}
+
+type operationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOperationServiceClient(cc grpc.ClientConnInterface) OperationServiceClient {
+	return &operationServiceClient{cc}
+}
+
+func (c *operationServiceClient) StartOperation(ctx context.Context, in *StartOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, OperationService_StartOperation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *operationServiceClient) GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, OperationService_GetOperation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *operationServiceClient) ListOperations(ctx context.Context, in *ListOperationsRequest, opts ...grpc.CallOption) (*ListOperationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListOperationsResponse)
+	err := c.cc.Invoke(ctx, OperationService_ListOperations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *operationServiceClient) CancelOperation(ctx context.Context, in *CancelOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, OperationService_CancelOperation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *operationServiceClient) WaitOperation(ctx context.Context, in *WaitOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, OperationService_WaitOperation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OperationServiceServer is the server API for OperationService service.
+// All implementations must embed UnimplementedOperationServiceServer
+// for forward compatibility.
+//
+// 长时间运行的计算任务：StartOperation 立即返回，任务在服务端后台执行，发起调用的客户端断开后仍继续运行，
+// 结果在任务结束后保留一段时间，可以用 GetOperation 或 WaitOperation 取回；保留的结果总大小有上限，
+// 超出时最早结束的任务提前被删除。任务只对发起它的调用方可见，其他调用方的任务同样返回 NOT_FOUND
+type OperationServiceServer interface {
+	// 运行中的任务过多时返回 RESOURCE_EXHAUSTED
+	StartOperation(context.Context, *StartOperationRequest) (*Operation, error)
+	GetOperation(context.Context, *GetOperationRequest) (*Operation, error)
+	// 按创建时间倒序返回调用方的所有任务；不包含成功任务的 result，需要用 GetOperation 取回
+	ListOperations(context.Context, *ListOperationsRequest) (*ListOperationsResponse, error)
+	// 请求取消任务，返回请求时的状态；任务退出后变为 CANCELLED，已经结束的任务不受影响
+	CancelOperation(context.Context, *CancelOperationRequest) (*Operation, error)
+	// 等待任务结束，最多等待 timeout，到时任务还没结束则返回当前状态
+	WaitOperation(context.Context, *WaitOperationRequest) (*Operation, error)
+	mustEmbedUnimplementedOperationServiceServer()
+}
+
+// UnimplementedOperationServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOperationServiceServer struct{}
+
+func (UnimplementedOperationServiceServer) StartOperation(context.Context, *StartOperationRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartOperation not implemented")
+}
+func (UnimplementedOperationServiceServer) GetOperation(context.Context, *GetOperationRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOperation not implemented")
+}
+func (UnimplementedOperationServiceServer) ListOperations(context.Context, *ListOperationsRequest) (*ListOperationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOperations not implemented")
+}
+func (UnimplementedOperationServiceServer) CancelOperation(context.Context, *CancelOperationRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOperation not implemented")
+}
+func (UnimplementedOperationServiceServer) WaitOperation(context.Context, *WaitOperationRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WaitOperation not implemented")
+}
+func (UnimplementedOperationServiceServer) mustEmbedUnimplementedOperationServiceServer() {}
+func (UnimplementedOperationServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeOperationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OperationServiceServer will
+// result in compilation errors.
+type UnsafeOperationServiceServer interface {
+	mustEmbedUnimplementedOperationServiceServer()
+}
+
+func RegisterOperationServiceServer(s grpc.ServiceRegistrar, srv OperationServiceServer) {
+	// If the following call pancis, it indicates UnimplementedOperationServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OperationService_ServiceDesc, srv)
+}
+
+func _OperationService_StartOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OperationServiceServer).StartOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OperationService_StartOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OperationServiceServer).StartOperation(ctx, req.(*StartOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OperationService_GetOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OperationServiceServer).GetOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OperationService_GetOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OperationServiceServer).GetOperation(ctx, req.(*GetOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OperationService_ListOperations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOperationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OperationServiceServer).ListOperations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OperationService_ListOperations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OperationServiceServer).ListOperations(ctx, req.(*ListOperationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OperationService_CancelOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OperationServiceServer).CancelOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OperationService_CancelOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OperationServiceServer).CancelOperation(ctx, req.(*CancelOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OperationService_WaitOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OperationServiceServer).WaitOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OperationService_WaitOperation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OperationServiceServer).WaitOperation(ctx, req.(*WaitOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OperationService_ServiceDesc is the grpc.ServiceDesc for OperationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OperationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.v1.OperationService",
+	HandlerType: (*OperationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartOperation",
+			Handler:    _OperationService_StartOperation_Handler,
+		},
+		{
+			MethodName: "GetOperation",
+			Handler:    _OperationService_GetOperation_Handler,
+		},
+		{
+			MethodName: "ListOperations",
+			Handler:    _OperationService_ListOperations_Handler,
+		},
+		{
+			MethodName: "CancelOperation",
+			Handler:    _OperationService_CancelOperation_Handler,
+		},
+		{
+			MethodName: "WaitOperation",
+			Handler:    _OperationService_WaitOperation_Handler,
 		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "calculator.proto",
 }
@@ -0,0 +1,284 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v3.12.4
+// source: v2/calculator.proto
+
+package v2
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BinaryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	A     string                 `protobuf:"bytes,1,opt,name=a,proto3" json:"a,omitempty"`
+	B     string                 `protobuf:"bytes,2,opt,name=b,proto3" json:"b,omitempty"`
+	// decimal 字段保留的小数位数，0 表示使用默认值 20
+	Scale         int32 `protobuf:"varint,3,opt,name=scale,proto3" json:"scale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BinaryRequest) Reset() {
+	*x = BinaryRequest{}
+	mi := &file_v2_calculator_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BinaryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BinaryRequest) ProtoMessage() {}
+
+func (x *BinaryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_calculator_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BinaryRequest.ProtoReflect.Descriptor instead.
+func (*BinaryRequest) Descriptor() ([]byte, []int) {
+	return file_v2_calculator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BinaryRequest) GetA() string {
+	if x != nil {
+		return x.A
+	}
+	return ""
+}
+
+func (x *BinaryRequest) GetB() string {
+	if x != nil {
+		return x.B
+	}
+	return ""
+}
+
+func (x *BinaryRequest) GetScale() int32 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+type NumberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Scale         int32                  `protobuf:"varint,2,opt,name=scale,proto3" json:"scale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NumberRequest) Reset() {
+	*x = NumberRequest{}
+	mi := &file_v2_calculator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NumberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NumberRequest) ProtoMessage() {}
+
+func (x *NumberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_calculator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NumberRequest.ProtoReflect.Descriptor instead.
+func (*NumberRequest) Descriptor() ([]byte, []int) {
+	return file_v2_calculator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *NumberRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *NumberRequest) GetScale() int32 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+type NumberResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 精确结果：整数或最简分数，如 "42"、"-1/3"
+	Result string `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	// 按 scale 四舍五入后的小数表示
+	Decimal string `protobuf:"bytes,2,opt,name=decimal,proto3" json:"decimal,omitempty"`
+	// decimal 是否与 result 完全相等
+	Exact         bool `protobuf:"varint,3,opt,name=exact,proto3" json:"exact,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NumberResponse) Reset() {
+	*x = NumberResponse{}
+	mi := &file_v2_calculator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NumberResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NumberResponse) ProtoMessage() {}
+
+func (x *NumberResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_calculator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NumberResponse.ProtoReflect.Descriptor instead.
+func (*NumberResponse) Descriptor() ([]byte, []int) {
+	return file_v2_calculator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *NumberResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *NumberResponse) GetDecimal() string {
+	if x != nil {
+		return x.Decimal
+	}
+	return ""
+}
+
+func (x *NumberResponse) GetExact() bool {
+	if x != nil {
+		return x.Exact
+	}
+	return false
+}
+
+var File_v2_calculator_proto protoreflect.FileDescriptor
+
+const file_v2_calculator_proto_rawDesc = "" +
+	"\n" +
+	"\x13v2/calculator.proto\x12\rcalculator.v2\"A\n" +
+	"\rBinaryRequest\x12\f\n" +
+	"\x01a\x18\x01 \x01(\tR\x01a\x12\f\n" +
+	"\x01b\x18\x02 \x01(\tR\x01b\x12\x14\n" +
+	"\x05scale\x18\x03 \x01(\x05R\x05scale\";\n" +
+	"\rNumberRequest\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
+	"\x05scale\x18\x02 \x01(\x05R\x05scale\"X\n" +
+	"\x0eNumberResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\x12\x18\n" +
+	"\adecimal\x18\x02 \x01(\tR\adecimal\x12\x14\n" +
+	"\x05exact\x18\x03 \x01(\bR\x05exact2\xc0\x03\n" +
+	"\x11CalculatorService\x12B\n" +
+	"\x03Add\x12\x1c.calculator.v2.BinaryRequest\x1a\x1d.calculator.v2.NumberResponse\x12G\n" +
+	"\bSubtract\x12\x1c.calculator.v2.BinaryRequest\x1a\x1d.calculator.v2.NumberResponse\x12G\n" +
+	"\bMultiply\x12\x1c.calculator.v2.BinaryRequest\x1a\x1d.calculator.v2.NumberResponse\x12E\n" +
+	"\x06Divide\x12\x1c.calculator.v2.BinaryRequest\x1a\x1d.calculator.v2.NumberResponse\x12B\n" +
+	"\x03Pow\x12\x1c.calculator.v2.BinaryRequest\x1a\x1d.calculator.v2.NumberResponse\x12J\n" +
+	"\tSumStream\x12\x1c.calculator.v2.NumberRequest\x1a\x1d.calculator.v2.NumberResponse(\x01B/Z-github.com/MorseWayne/grpc-demo/api/gen/v2;v2b\x06proto3"
+
+var (
+	file_v2_calculator_proto_rawDescOnce sync.Once
+	file_v2_calculator_proto_rawDescData []byte
+)
+
+func file_v2_calculator_proto_rawDescGZIP() []byte {
+	file_v2_calculator_proto_rawDescOnce.Do(func() {
+		file_v2_calculator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v2_calculator_proto_rawDesc), len(file_v2_calculator_proto_rawDesc)))
+	})
+	return file_v2_calculator_proto_rawDescData
+}
+
+var file_v2_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_v2_calculator_proto_goTypes = []any{
+	(*BinaryRequest)(nil),  // 0: calculator.v2.BinaryRequest
+	(*NumberRequest)(nil),  // 1: calculator.v2.NumberRequest
+	(*NumberResponse)(nil), // 2: calculator.v2.NumberResponse
+}
+var file_v2_calculator_proto_depIdxs = []int32{
+	0, // 0: calculator.v2.CalculatorService.Add:input_type -> calculator.v2.BinaryRequest
+	0, // 1: calculator.v2.CalculatorService.Subtract:input_type -> calculator.v2.BinaryRequest
+	0, // 2: calculator.v2.CalculatorService.Multiply:input_type -> calculator.v2.BinaryRequest
+	0, // 3: calculator.v2.CalculatorService.Divide:input_type -> calculator.v2.BinaryRequest
+	0, // 4: calculator.v2.CalculatorService.Pow:input_type -> calculator.v2.BinaryRequest
+	1, // 5: calculator.v2.CalculatorService.SumStream:input_type -> calculator.v2.NumberRequest
+	2, // 6: calculator.v2.CalculatorService.Add:output_type -> calculator.v2.NumberResponse
+	2, // 7: calculator.v2.CalculatorService.Subtract:output_type -> calculator.v2.NumberResponse
+	2, // 8: calculator.v2.CalculatorService.Multiply:output_type -> calculator.v2.NumberResponse
+	2, // 9: calculator.v2.CalculatorService.Divide:output_type -> calculator.v2.NumberResponse
+	2, // 10: calculator.v2.CalculatorService.Pow:output_type -> calculator.v2.NumberResponse
+	2, // 11: calculator.v2.CalculatorService.SumStream:output_type -> calculator.v2.NumberResponse
+	6, // [6:12] is the sub-list for method output_type
+	0, // [0:6] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_v2_calculator_proto_init() }
+func file_v2_calculator_proto_init() {
+	if File_v2_calculator_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v2_calculator_proto_rawDesc), len(file_v2_calculator_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_v2_calculator_proto_goTypes,
+		DependencyIndexes: file_v2_calculator_proto_depIdxs,
+		MessageInfos:      file_v2_calculator_proto_msgTypes,
+	}.Build()
+	File_v2_calculator_proto = out.File
+	file_v2_calculator_proto_goTypes = nil
+	file_v2_calculator_proto_depIdxs = nil
+}
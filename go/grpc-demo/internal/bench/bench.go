@@ -0,0 +1,256 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// Config 压测参数
+type Config struct {
+	// Concurrency 并发调用的 worker 数
+	Concurrency int
+	// QPS 目标每秒请求数，0 表示每个 worker 收到响应后立即发下一个（闭环）。
+	// 指定 QPS 时按计划时间发请求，延迟从计划时间算起，避免服务端变慢时低估延迟；
+	// 所有 worker 都忙时来不及发出的请求计入 Dropped
+	QPS      float64
+	Duration time.Duration
+	// Interval 时间线的统计粒度
+	Interval time.Duration
+}
+
+// Call 执行一次调用，流式 RPC 的一次调用包含整个流
+type Call func(ctx context.Context) error
+
+type sample struct {
+	at      time.Duration // 计划开始时间，相对压测开始
+	latency time.Duration
+	code    string
+}
+
+// Run 按 cfg 反复执行 call，直到 Duration 结束或 ctx 取消；已经发出的调用会等它完成
+func Run(ctx context.Context, cfg Config, call Call) *Report {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	start := time.Now()
+	stop := start.Add(cfg.Duration)
+	runCtx, cancel := context.WithDeadline(ctx, stop)
+	defer cancel()
+
+	var schedule chan time.Time
+	dropped := make(chan int, 1)
+	if cfg.QPS > 0 {
+		schedule = make(chan time.Time, cfg.Concurrency)
+		go func() { dropped <- pace(runCtx, schedule, start, stop, cfg.QPS) }()
+	} else {
+		dropped <- 0
+	}
+
+	samples := make([][]sample, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for w := range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var at time.Time
+				if schedule != nil {
+					var ok bool
+					if at, ok = <-schedule; !ok {
+						return
+					}
+				} else {
+					if runCtx.Err() != nil {
+						return
+					}
+					at = time.Now()
+				}
+				// 调用本身使用外层 ctx，压测结束时正在进行的调用不会被取消
+				err := call(ctx)
+				samples[w] = append(samples[w], sample{
+					at:      at.Sub(start),
+					latency: time.Since(at),
+					code:    status.Code(err).String(),
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	// worker 都在 schedule 关闭后才退出，此时 pace 已经返回
+	return newReport(slices.Concat(samples...), time.Since(start), cfg.Interval, <-dropped)
+}
+
+// pace 按计划时间把 [start, stop) 内的请求放进 schedule，返回因所有 worker 都忙而丢弃的请求数
+func pace(ctx context.Context, schedule chan<- time.Time, start, stop time.Time, qps float64) int {
+	defer close(schedule)
+	interval := time.Duration(float64(time.Second) / qps)
+	dropped := 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for i := 0; ; i++ {
+		at := start.Add(time.Duration(i) * interval)
+		if !at.Before(stop) {
+			return dropped
+		}
+		timer.Reset(time.Until(at))
+		select {
+		case <-ctx.Done():
+			return dropped
+		case <-timer.C:
+		}
+		select {
+		case schedule <- at:
+		default:
+			dropped++
+		}
+	}
+}
+
+// Latency 延迟分布
+type Latency struct {
+	Min  time.Duration `json:"min_ns"`
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P95  time.Duration `json:"p95_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	P999 time.Duration `json:"p999_ns"`
+	Max  time.Duration `json:"max_ns"`
+}
+
+// Bucket 时间线中的一段
+type Bucket struct {
+	Start    time.Duration `json:"start_ns"`
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	P50      time.Duration `json:"p50_ns"`
+	P99      time.Duration `json:"p99_ns"`
+}
+
+// Report 压测结果
+type Report struct {
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`
+	Dropped    int            `json:"dropped"`
+	Duration   time.Duration  `json:"duration_ns"`
+	Throughput float64        `json:"throughput"` // 每秒完成的请求数
+	Latency    Latency        `json:"latency"`
+	Codes      map[string]int `json:"codes"`
+	Timeline   []Bucket       `json:"timeline"`
+}
+
+func newReport(samples []sample, elapsed, interval time.Duration, dropped int) *Report {
+	r := &Report{
+		Requests: len(samples),
+		Dropped:  dropped,
+		Duration: elapsed,
+		Codes:    make(map[string]int),
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	// 样本的计划时间都在 Duration 之内，最后一段不会因为等待收尾的调用而多出空桶
+	var last time.Duration
+	for _, s := range samples {
+		last = max(last, s.at)
+	}
+	buckets := make([][]time.Duration, int(last/interval)+1)
+	errors := make([]int, len(buckets))
+	latencies := make([]time.Duration, 0, len(samples))
+	var total time.Duration
+	for _, s := range samples {
+		r.Codes[s.code]++
+		latencies = append(latencies, s.latency)
+		total += s.latency
+		i := int(s.at / interval)
+		buckets[i] = append(buckets[i], s.latency)
+		if s.code != "OK" {
+			r.Errors++
+			errors[i]++
+		}
+	}
+	sortDurations(latencies)
+	if len(latencies) > 0 {
+		r.Latency = Latency{
+			Min:  latencies[0],
+			Mean: total / time.Duration(len(latencies)),
+			P50:  percentile(latencies, 0.5),
+			P90:  percentile(latencies, 0.9),
+			P95:  percentile(latencies, 0.95),
+			P99:  percentile(latencies, 0.99),
+			P999: percentile(latencies, 0.999),
+			Max:  latencies[len(latencies)-1],
+		}
+	}
+	for i, b := range buckets {
+		sortDurations(b)
+		r.Timeline = append(r.Timeline, Bucket{
+			Start:    time.Duration(i) * interval,
+			Requests: len(b),
+			Errors:   errors[i],
+			P50:      percentile(b, 0.5),
+			P99:      percentile(b, 0.99),
+		})
+	}
+	return r
+}
+
+func sortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
+
+// percentile 最近秩法，sorted 必须已经升序排列
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// WriteText 输出便于阅读的报告
+func (r *Report) WriteText(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("requests    %d (errors %d, dropped %d)\n", r.Requests, r.Errors, r.Dropped)
+	ew.printf("duration    %s\n", r.Duration.Round(time.Millisecond))
+	ew.printf("throughput  %.1f req/s\n", r.Throughput)
+	l := r.Latency
+	ew.printf("latency     min %s  mean %s  max %s\n", l.Min, l.Mean, l.Max)
+	ew.printf("            p50 %s  p90 %s  p95 %s  p99 %s  p99.9 %s\n", l.P50, l.P90, l.P95, l.P99, l.P999)
+	codes := make([]string, 0, len(r.Codes))
+	for c := range r.Codes {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	ew.printf("codes      ")
+	for _, c := range codes {
+		ew.printf(" %s=%d", c, r.Codes[c])
+	}
+	ew.printf("\ntimeline\n")
+	for _, b := range r.Timeline {
+		ew.printf("  %8s  %6d req  %4d err  p50 %-12s p99 %s\n", b.Start, b.Requests, b.Errors, b.P50, b.P99)
+	}
+	return ew.err
+}
+
+// errWriter 记住第一次写入错误，之后的写入都忽略
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 100; i++ {
+		d = append(d, time.Duration(i))
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{{0, 1}, {0.5, 50}, {0.9, 90}, {0.99, 99}, {0.999, 100}, {1, 100}}
+	for _, tt := range tests {
+		if got := percentile(d, tt.p); got != tt.want {
+			t.Errorf("percentile(%g) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile of no samples = %d", got)
+	}
+}
+
+func TestClosedLoop(t *testing.T) {
+	var n atomic.Int64
+	r := Run(context.Background(), Config{Concurrency: 4, Duration: 200 * time.Millisecond, Interval: 50 * time.Millisecond},
+		func(ctx context.Context) error {
+			time.Sleep(5 * time.Millisecond)
+			// 每 4 次调用失败一次
+			if n.Add(1)%4 == 0 {
+				return status.Error(codes.Unavailable, "down")
+			}
+			return nil
+		})
+	if r.Requests != int(n.Load()) || r.Requests < 40 {
+		t.Fatalf("requests = %d, calls = %d", r.Requests, n.Load())
+	}
+	if r.Codes["OK"]+r.Codes["Unavailable"] != r.Requests || r.Errors != r.Codes["Unavailable"] || r.Errors == 0 {
+		t.Fatalf("codes = %v, errors = %d", r.Codes, r.Errors)
+	}
+	if r.Latency.Min < 5*time.Millisecond || r.Latency.P50 > r.Latency.P99 || r.Latency.P99 > r.Latency.Max {
+		t.Fatalf("latency = %+v", r.Latency)
+	}
+	total := 0
+	for _, b := range r.Timeline {
+		total += b.Requests
+	}
+	if len(r.Timeline) < 4 || total != r.Requests {
+		t.Fatalf("timeline has %d buckets with %d requests", len(r.Timeline), total)
+	}
+}
+
+func TestOpenLoop(t *testing.T) {
+	r := Run(context.Background(), Config{Concurrency: 2, QPS: 200, Duration: 300 * time.Millisecond},
+		func(ctx context.Context) error { return nil })
+	// 200 qps 跑 300ms 约 60 次
+	if r.Requests < 45 || r.Requests > 65 || r.Dropped != 0 {
+		t.Fatalf("requests = %d, dropped = %d", r.Requests, r.Dropped)
+	}
+}
+
+func TestOpenLoopDropsWhenBusy(t *testing.T) {
+	// 一个 worker 每次调用 50ms，跟不上 200 qps
+	r := Run(context.Background(), Config{Concurrency: 1, QPS: 200, Duration: 300 * time.Millisecond},
+		func(ctx context.Context) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+	if r.Dropped == 0 || r.Requests > 10 {
+		t.Fatalf("requests = %d, dropped = %d", r.Requests, r.Dropped)
+	}
+	// 延迟从计划时间算起，包括在队列中等待的时间
+	if r.Latency.Max < 50*time.Millisecond {
+		t.Fatalf("max latency = %s", r.Latency.Max)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	r := Run(ctx, Config{Concurrency: 2, Duration: time.Minute}, func(ctx context.Context) error { return nil })
+	if time.Since(start) > 5*time.Second || r.Requests == 0 {
+		t.Fatalf("run took %s with %d requests", time.Since(start), r.Requests)
+	}
+}
+
+func TestReportOutput(t *testing.T) {
+	r := Run(context.Background(), Config{Concurrency: 1, Duration: 20 * time.Millisecond},
+		func(ctx context.Context) error { return status.Error(codes.InvalidArgument, "bad") })
+	var text bytes.Buffer
+	if err := r.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"throughput", "p99", "InvalidArgument=", "timeline"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text report lacks %q:\n%s", want, text.String())
+		}
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(b, &decoded); err != nil || decoded.Codes["InvalidArgument"] != r.Requests {
+		t.Fatalf("json report = %s, %v", b, err)
+	}
+}
@@ -0,0 +1,202 @@
+package bigcalc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// 限制输入和结果规模，防止超大数耗尽 CPU 和内存
+const (
+	MaxDigits       = 4096
+	MaxResultBits   = 1 << 20
+	DefaultScale    = 20
+	MaxScale        = 1000
+	maxExponentBits = 31
+)
+
+// MaxOperationResultBits PowContext 在后台计算，结果上限比同步调用宽松；
+// 结果以十进制字符串返回（约 250 万位），仍在 gRPC 默认 4MB 的消息上限之内
+const MaxOperationResultBits = 1 << 23
+
+// numberPattern 只接受普通十进制整数、小数和分数，拒绝 "1e9999999" 这类科学计数法
+var numberPattern = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$|^[+-]?\d+/\d+$`)
+
+// InvalidNumberError 操作数不是合法的十进制数
+type InvalidNumberError struct {
+	Field string
+	Value string
+	Msg   string
+}
+
+func (e *InvalidNumberError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Msg)
+}
+
+// TooLargeError 计算结果超出允许的规模
+type TooLargeError struct {
+	Op  string
+	Msg string
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Op, e.Msg)
+}
+
+// Parse 解析十进制字符串，field 用于错误信息
+func Parse(field, s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, &InvalidNumberError{Field: field, Value: s, Msg: "empty number"}
+	}
+	if len(s) > MaxDigits {
+		return nil, &InvalidNumberError{Field: field, Value: s[:32] + "...", Msg: fmt.Sprintf("longer than %d characters", MaxDigits)}
+	}
+	if !numberPattern.MatchString(s) {
+		return nil, &InvalidNumberError{Field: field, Value: s, Msg: fmt.Sprintf("%q is not a decimal integer, decimal or fraction", s)}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		// 分母为 0，例如 "1/0"
+		return nil, &InvalidNumberError{Field: field, Value: s, Msg: fmt.Sprintf("%q has a zero denominator", s)}
+	}
+	return r, nil
+}
+
+// Format 返回精确表示（整数或最简分数）、保留 scale 位小数的表示，以及两者是否相等
+func Format(r *big.Rat, scale int) (exact, decimal string, isExact bool) {
+	if scale <= 0 {
+		scale = DefaultScale
+	}
+	scale = min(scale, MaxScale)
+	exact = Exact(r)
+	decimal = r.FloatString(scale)
+	// 把四舍五入后的小数解析回来与原值比较，相等说明没有截断
+	back, _ := new(big.Rat).SetString(decimal)
+	return exact, decimal, back.Cmp(r) == 0
+}
+
+// Exact 精确结果：整数或最简分数
+func Exact(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return r.String()
+}
+
+// Add a + b
+func Add(a, b *big.Rat) (*big.Rat, error) {
+	return checkSize("add", new(big.Rat).Add(a, b))
+}
+
+// Subtract a - b
+func Subtract(a, b *big.Rat) (*big.Rat, error) {
+	return checkSize("subtract", new(big.Rat).Sub(a, b))
+}
+
+// Multiply a * b
+func Multiply(a, b *big.Rat) (*big.Rat, error) {
+	if bits(a)+bits(b) > MaxResultBits {
+		return nil, &TooLargeError{Op: "multiply", Msg: fmt.Sprintf("result exceeds %d bits", MaxResultBits)}
+	}
+	return new(big.Rat).Mul(a, b), nil
+}
+
+// Divide a / b，b 为 0 时返回 InvalidNumberError
+func Divide(a, b *big.Rat) (*big.Rat, error) {
+	if b.Sign() == 0 {
+		return nil, &InvalidNumberError{Field: "b", Value: "0", Msg: "division by zero"}
+	}
+	if bits(a)+bits(b) > MaxResultBits {
+		return nil, &TooLargeError{Op: "divide", Msg: fmt.Sprintf("result exceeds %d bits", MaxResultBits)}
+	}
+	return new(big.Rat).Quo(a, b), nil
+}
+
+// Pow a ^ b，b 必须是整数，负指数得到倒数
+func Pow(a, b *big.Rat) (*big.Rat, error) {
+	r, exp, err := powSpecial(a, b, MaxResultBits)
+	if r != nil || err != nil {
+		return r, err
+	}
+	num := new(big.Int).Exp(a.Num(), exp, nil)
+	den := new(big.Int).Exp(a.Denom(), exp, nil)
+	return powResult(num, den, b), nil
+}
+
+// PowContext 同 Pow，但结果上限为 MaxOperationResultBits；按指数的二进制位逐位平方，
+// 每处理一位通过 progress 报告 0~100 的进度，ctx 取消时返回 ctx.Err()；用于在后台计算很大的幂
+func PowContext(ctx context.Context, a, b *big.Rat, progress func(percent float64)) (*big.Rat, error) {
+	r, exp, err := powSpecial(a, b, MaxOperationResultBits)
+	if r != nil || err != nil {
+		return r, err
+	}
+	num, den := big.NewInt(1), big.NewInt(1)
+	n := exp.BitLen()
+	for i := n - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		num.Mul(num, num)
+		den.Mul(den, den)
+		if exp.Bit(i) == 1 {
+			num.Mul(num, a.Num())
+			den.Mul(den, a.Denom())
+		}
+		if progress != nil {
+			progress(float64(n-i) * 100 / float64(n))
+		}
+	}
+	return powResult(num, den, b), nil
+}
+
+// powSpecial 检查参数并处理结果不需要计算的情况：r 不为 nil 时即为结果，否则返回指数的绝对值；
+// 结果超过 maxBits 位时返回 TooLargeError
+func powSpecial(a, b *big.Rat, maxBits int) (r *big.Rat, exp *big.Int, err error) {
+	if !b.IsInt() {
+		return nil, nil, &InvalidNumberError{Field: "b", Value: b.RatString(), Msg: "exponent must be an integer"}
+	}
+	exp = new(big.Int).Abs(b.Num())
+	if a.Sign() == 0 && b.Sign() < 0 {
+		return nil, nil, &InvalidNumberError{Field: "b", Value: b.RatString(), Msg: "zero cannot be raised to a negative power"}
+	}
+	// 0、1、-1 的任意次幂都不会变大，其余情况先估算结果位数
+	if isUnit(a) {
+		if a.Sign() < 0 && exp.Bit(0) == 1 {
+			return big.NewRat(-1, 1), nil, nil
+		}
+		if a.Sign() == 0 && exp.Sign() > 0 {
+			return new(big.Rat), nil, nil
+		}
+		return big.NewRat(1, 1), nil, nil
+	}
+	if exp.BitLen() > maxExponentBits || int64(bits(a))*exp.Int64() > int64(maxBits) {
+		return nil, nil, &TooLargeError{Op: "pow", Msg: fmt.Sprintf("result exceeds %d bits", maxBits)}
+	}
+	return nil, exp, nil
+}
+
+// powResult 负指数得到倒数
+func powResult(num, den *big.Int, b *big.Rat) *big.Rat {
+	if b.Sign() < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den)
+}
+
+func checkSize(op string, r *big.Rat) (*big.Rat, error) {
+	if bits(r) > MaxResultBits {
+		return nil, &TooLargeError{Op: op, Msg: fmt.Sprintf("result exceeds %d bits", MaxResultBits)}
+	}
+	return r, nil
+}
+
+func bits(r *big.Rat) int {
+	return r.Num().BitLen() + r.Denom().BitLen()
+}
+
+func isUnit(r *big.Rat) bool {
+	return r.Sign() == 0 || (r.IsInt() && r.Num().CmpAbs(big.NewInt(1)) == 0)
+}
@@ -0,0 +1,128 @@
+package bigcalc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestOps(t *testing.T) {
+	cases := []struct {
+		name    string
+		op      func(a, b string) (string, error)
+		a, b    string
+		want    string
+		decimal string
+	}{
+		{"add", wrap(Add), "9223372036854775807", "1", "9223372036854775808", "9223372036854775808.00000000000000000000"},
+		{"subtract", wrap(Subtract), "0.1", "0.3", "-1/5", "-0.20000000000000000000"},
+		{"multiply", wrap(Multiply), "1/3", "3", "1", "1.00000000000000000000"},
+		{"divide", wrap(Divide), "1", "3", "1/3", "0.33333333333333333333"},
+		{"pow", wrap(Pow), "2", "100", "1267650600228229401496703205376", ""},
+		{"pow", wrap(Pow), "-2/3", "-3", "-27/8", "-3.37500000000000000000"},
+		{"pow", wrap(Pow), "-1", "1000000000001", "-1", ""},
+	}
+	for _, c := range cases {
+		got, err := c.op(c.a, c.b)
+		if err != nil {
+			t.Errorf("%s(%s, %s) unexpected err %v", c.name, c.a, c.b, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s(%s, %s) = %s, expected %s", c.name, c.a, c.b, got, c.want)
+		}
+		if c.decimal == "" {
+			continue
+		}
+		r, _ := Parse("r", got)
+		if _, decimal, _ := Format(r, 0); decimal != c.decimal {
+			t.Errorf("%s(%s, %s) decimal = %s, expected %s", c.name, c.a, c.b, decimal, c.decimal)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var ie *InvalidNumberError
+	for _, s := range []string{"", "1e10", "abc", "1/0", "0x10", strings.Repeat("9", MaxDigits+1)} {
+		if _, err := Parse("a", s); !errors.As(err, &ie) {
+			t.Errorf("Parse(%q) err = %v, expected invalid number", s, err)
+		}
+	}
+	if _, err := wrap(Divide)("1", "0"); !errors.As(err, &ie) {
+		t.Errorf("Divide by zero err = %v", err)
+	}
+	if _, err := wrap(Pow)("2", "1/2"); !errors.As(err, &ie) {
+		t.Errorf("Pow fractional exponent err = %v", err)
+	}
+	if _, err := wrap(Pow)("0", "-1"); !errors.As(err, &ie) {
+		t.Errorf("Pow zero negative exponent err = %v", err)
+	}
+	var te *TooLargeError
+	if _, err := wrap(Pow)("2", "99999999999"); !errors.As(err, &te) {
+		t.Errorf("Pow huge exponent err = %v", err)
+	}
+}
+
+func TestPowContext(t *testing.T) {
+	for _, c := range [][2]string{{"2", "100"}, {"-2/3", "-3"}, {"7", "12345"}, {"-1", "3"}, {"5", "0"}} {
+		a, _ := Parse("a", c[0])
+		b, _ := Parse("b", c[1])
+		want, _ := Pow(a, b)
+		last := 0.0
+		got, err := PowContext(context.Background(), a, b, func(p float64) { last = p })
+		if err != nil || got.Cmp(want) != 0 {
+			t.Errorf("PowContext(%s, %s) = %v, %v, expected %v", c[0], c[1], got, err, want)
+		}
+		if b.Sign() != 0 && !isUnit(a) && last != 100 {
+			t.Errorf("PowContext(%s, %s) last progress = %v", c[0], c[1], last)
+		}
+	}
+	// 后台计算允许比 Pow 更大的结果，但同样有上限
+	a, b := big.NewRat(3, 1), big.NewRat(1000000, 1)
+	var tooLarge *TooLargeError
+	if _, err := Pow(a, b); !errors.As(err, &tooLarge) {
+		t.Errorf("Pow(3, 1000000) err = %v", err)
+	}
+	if r, err := PowContext(context.Background(), a, b, nil); err != nil || bits(r) <= MaxResultBits {
+		t.Errorf("PowContext(3, 1000000) err = %v", err)
+	}
+	if _, err := PowContext(context.Background(), a, big.NewRat(10000000, 1), nil); !errors.As(err, &tooLarge) {
+		t.Errorf("PowContext(3, 10000000) err = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := PowContext(ctx, big.NewRat(3, 1), big.NewRat(1000, 1), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("PowContext after cancel err = %v", err)
+	}
+}
+
+func TestFormatExact(t *testing.T) {
+	r, _ := Parse("a", "1/8")
+	if exact, decimal, isExact := Format(r, 3); exact != "1/8" || decimal != "0.125" || !isExact {
+		t.Errorf("Format(1/8, 3) = %s, %s, %v", exact, decimal, isExact)
+	}
+	if _, _, isExact := Format(r, 2); isExact {
+		t.Error("Format(1/8, 2) should not be exact")
+	}
+}
+
+func wrap(op func(a, b *big.Rat) (*big.Rat, error)) func(a, b string) (string, error) {
+	return func(a, b string) (string, error) {
+		x, err := Parse("a", a)
+		if err != nil {
+			return "", err
+		}
+		y, err := Parse("b", b)
+		if err != nil {
+			return "", err
+		}
+		r, err := op(x, y)
+		if err != nil {
+			return "", err
+		}
+		exact, _, _ := Format(r, 0)
+		return exact, nil
+	}
+}
@@ -0,0 +1,118 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// OverflowError 运算结果超出 int64 范围
+type OverflowError struct {
+	Op   string
+	A, B int64
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("%s(%d, %d) overflows int64", e.Op, e.A, e.B)
+}
+
+// Add 带溢出检查的加法
+func Add(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, &OverflowError{Op: "add", A: a, B: b}
+	}
+	return a + b, nil
+}
+
+// 操作数不合法的原因，同时用作 ErrorInfo 的 reason
+const (
+	ReasonDivisionByZero   = "DIVISION_BY_ZERO"
+	ReasonNegativeExponent = "NEGATIVE_EXPONENT"
+)
+
+// InvalidOperandError 操作数不合法，例如除数为 0 或指数为负数，出错的总是 b
+type InvalidOperandError struct {
+	Op     string
+	A, B   int64
+	Reason string
+}
+
+func (e *InvalidOperandError) Error() string {
+	switch e.Reason {
+	case ReasonDivisionByZero:
+		return fmt.Sprintf("%s(%d, %d): division by zero", e.Op, e.A, e.B)
+	case ReasonNegativeExponent:
+		return fmt.Sprintf("%s(%d, %d): negative exponent", e.Op, e.A, e.B)
+	}
+	return fmt.Sprintf("%s(%d, %d): invalid operand", e.Op, e.A, e.B)
+}
+
+// Subtract 带溢出检查的减法
+func Subtract(a, b int64) (int64, error) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, &OverflowError{Op: "subtract", A: a, B: b}
+	}
+	return a - b, nil
+}
+
+// Multiply 带溢出检查的乘法
+func Multiply(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	r := a * b
+	// MinInt64 * -1 时 r/b 不会 panic 但结果回绕，需要单独判断
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) || r/b != a {
+		return 0, &OverflowError{Op: "multiply", A: a, B: b}
+	}
+	return r, nil
+}
+
+// Divide 整数除法，商向零截断，余数与 a 同号
+func Divide(a, b int64) (quotient, remainder int64, err error) {
+	if b == 0 {
+		return 0, 0, &InvalidOperandError{Op: "divide", A: a, B: b, Reason: ReasonDivisionByZero}
+	}
+	if a == math.MinInt64 && b == -1 {
+		return 0, 0, &OverflowError{Op: "divide", A: a, B: b}
+	}
+	return a / b, a % b, nil
+}
+
+// Modulo 欧几里得取模，结果总是落在 [0, |b|)
+func Modulo(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, &InvalidOperandError{Op: "modulo", A: a, B: b, Reason: ReasonDivisionByZero}
+	}
+	m := a % b
+	if m < 0 {
+		if b < 0 {
+			m -= b
+		} else {
+			m += b
+		}
+	}
+	return m, nil
+}
+
+// Pow 快速幂，b 必须非负，0^0 = 1
+func Pow(a, b int64) (int64, error) {
+	if b < 0 {
+		return 0, &InvalidOperandError{Op: "pow", A: a, B: b, Reason: ReasonNegativeExponent}
+	}
+	result, base, exp := int64(1), a, b
+	for exp > 0 {
+		var err error
+		if exp&1 == 1 {
+			if result, err = Multiply(result, base); err != nil {
+				return 0, &OverflowError{Op: "pow", A: a, B: b}
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, err = Multiply(base, base); err != nil {
+				return 0, &OverflowError{Op: "pow", A: a, B: b}
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	cases := []struct {
+		a, b     int64
+		want     int64
+		overflow bool
+	}{
+		{3, 4, 7, false},
+		{-3, 4, 1, false},
+		{math.MaxInt64, 0, math.MaxInt64, false},
+		{math.MaxInt64, 1, 0, true},
+		{math.MinInt64, -1, 0, true},
+		{math.MinInt64, math.MaxInt64, -1, false},
+	}
+	for _, c := range cases {
+		got, err := Add(c.a, c.b)
+		var oe *OverflowError
+		if c.overflow != errors.As(err, &oe) {
+			t.Errorf("Add(%d, %d) err = %v, overflow expected %v", c.a, c.b, err, c.overflow)
+			continue
+		}
+		if !c.overflow && got != c.want {
+			t.Errorf("Add(%d, %d) = %d, expected %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestBinaryOps(t *testing.T) {
+	cases := []struct {
+		name   string
+		op     func(a, b int64) (int64, error)
+		a, b   int64
+		want   int64
+		reason string // "" 表示成功，"overflow" 表示溢出，其余为 InvalidOperandError.Reason
+	}{
+		{"subtract", Subtract, 3, 4, -1, ""},
+		{"subtract", Subtract, math.MinInt64, 1, 0, "overflow"},
+		{"subtract", Subtract, 0, math.MinInt64, 0, "overflow"},
+		{"subtract", Subtract, -1, math.MinInt64, math.MaxInt64, ""},
+		{"multiply", Multiply, -6, 7, -42, ""},
+		{"multiply", Multiply, math.MaxInt64, 2, 0, "overflow"},
+		{"multiply", Multiply, math.MinInt64, -1, 0, "overflow"},
+		{"multiply", Multiply, -1, math.MinInt64, 0, "overflow"},
+		{"multiply", Multiply, math.MinInt64, 1, math.MinInt64, ""},
+		{"modulo", Modulo, 7, 3, 1, ""},
+		{"modulo", Modulo, -7, 3, 2, ""},
+		{"modulo", Modulo, -7, -3, 2, ""},
+		{"modulo", Modulo, math.MinInt64, -1, 0, ""},
+		{"modulo", Modulo, 1, 0, 0, ReasonDivisionByZero},
+		{"pow", Pow, 2, 10, 1024, ""},
+		{"pow", Pow, -3, 3, -27, ""},
+		{"pow", Pow, 0, 0, 1, ""},
+		{"pow", Pow, 2, 62, 1 << 62, ""},
+		{"pow", Pow, 2, 63, 0, "overflow"},
+		{"pow", Pow, -2, 63, math.MinInt64, ""},
+		{"pow", Pow, 2, -1, 0, ReasonNegativeExponent},
+	}
+	for _, c := range cases {
+		got, err := c.op(c.a, c.b)
+		var oe *OverflowError
+		var ie *InvalidOperandError
+		switch {
+		case c.reason == "" && err != nil:
+			t.Errorf("%s(%d, %d) unexpected err %v", c.name, c.a, c.b, err)
+		case c.reason == "" && got != c.want:
+			t.Errorf("%s(%d, %d) = %d, expected %d", c.name, c.a, c.b, got, c.want)
+		case c.reason == "overflow" && !errors.As(err, &oe):
+			t.Errorf("%s(%d, %d) err = %v, expected overflow", c.name, c.a, c.b, err)
+		case c.reason != "" && c.reason != "overflow" && (!errors.As(err, &ie) || ie.Reason != c.reason):
+			t.Errorf("%s(%d, %d) err = %v, expected %s", c.name, c.a, c.b, err, c.reason)
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	q, r, err := Divide(-7, 2)
+	if err != nil || q != -3 || r != -1 {
+		t.Errorf("Divide(-7, 2) = %d, %d, %v", q, r, err)
+	}
+	var ie *InvalidOperandError
+	if _, _, err := Divide(1, 0); !errors.As(err, &ie) || ie.Reason != ReasonDivisionByZero {
+		t.Errorf("Divide(1, 0) err = %v, expected division by zero", err)
+	}
+	var oe *OverflowError
+	if _, _, err := Divide(math.MinInt64, -1); !errors.As(err, &oe) {
+		t.Errorf("Divide(MinInt64, -1) err = %v, expected overflow", err)
+	}
+}
@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/bench"
+	"github.com/MorseWayne/grpc-demo/internal/client"
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
+	"google.golang.org/grpc"
+)
+
+const benchUsage = `usage: grpc-demo bench [flags]
+
+drives one CalculatorService rpc for -duration and reports latency percentiles,
+a throughput timeline and the status codes of all calls.
+
+without -qps every worker sends the next call as soon as the previous one returns.
+with -qps calls are started on a fixed schedule and latency is measured from the
+scheduled time; calls that cannot start because every worker is busy are dropped.
+
+rpcs: %s
+
+payload: unary rpcs send (-a, -b); batch rpcs send -messages pairs; client and
+bidirectional streams send -messages messages, bidirectional ones waiting for each
+response; RangeAdd streams 1..-messages; Evaluate sends -expr.
+
+flags:
+`
+
+// payload bench 每次调用发送的内容
+type payload struct {
+	a, b     int64
+	messages int
+	expr     string
+}
+
+func (p *payload) operands() *v1.OperandsRequest {
+	return &v1.OperandsRequest{A: p.a, B: p.b}
+}
+
+func (p *payload) batch() *v1.BatchRequest {
+	req := &v1.BatchRequest{}
+	for range p.messages {
+		req.Items = append(req.Items, p.operands())
+	}
+	return req
+}
+
+// benchRPCs 按方法名构造一次调用
+var benchRPCs = map[string]func(c v1.CalculatorServiceClient, p *payload) bench.Call{
+	"Add": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Add, &v1.AddRequest{A: p.a, B: p.b})
+	},
+	"Subtract": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Subtract, p.operands())
+	},
+	"Multiply": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Multiply, p.operands())
+	},
+	"Divide": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Divide, p.operands())
+	},
+	"Modulo": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Modulo, p.operands())
+	},
+	"Pow": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Pow, p.operands())
+	},
+	"Evaluate": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.Evaluate, &v1.EvaluateRequest{Expression: p.expr})
+	},
+	"SubtractBatch": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.SubtractBatch, p.batch())
+	},
+	"MultiplyBatch": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.MultiplyBatch, p.batch())
+	},
+	"DivideBatch": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.DivideBatch, p.batch())
+	},
+	"ModuloBatch": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.ModuloBatch, p.batch())
+	},
+	"PowBatch": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return unaryCall(c.PowBatch, p.batch())
+	},
+	"SumStream": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return clientStreamCall(c.SumStream, &v1.AddRequest{A: p.a, B: p.b}, p.messages)
+	},
+	"StatsStream": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return clientStreamCall(c.StatsStream, &v1.StatsRequest{Value: p.a}, p.messages)
+	},
+	"RangeAdd": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return serverStreamCall(c.RangeAdd, &v1.RangeRequest{Start: 1, End: int64(p.messages)})
+	},
+	"ChatAdd": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return bidiCall(c.ChatAdd, &v1.AddRequest{A: p.a, B: p.b}, p.messages)
+	},
+	"ChatSubtract": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return bidiCall(c.ChatSubtract, p.operands(), p.messages)
+	},
+	"ChatMultiply": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return bidiCall(c.ChatMultiply, p.operands(), p.messages)
+	},
+	"ChatDivide": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return bidiCall(c.ChatDivide, p.operands(), p.messages)
+	},
+	"ChatModulo": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return bidiCall(c.ChatModulo, p.operands(), p.messages)
+	},
+	"ChatPow": func(c v1.CalculatorServiceClient, p *payload) bench.Call {
+		return bidiCall(c.ChatPow, p.operands(), p.messages)
+	},
+}
+
+func unaryCall[Req, Resp any](rpc func(context.Context, *Req, ...grpc.CallOption) (*Resp, error), req *Req) bench.Call {
+	return func(ctx context.Context) error {
+		_, err := rpc(ctx, req)
+		return err
+	}
+}
+
+func clientStreamCall[Req, Resp any](open func(context.Context, ...grpc.CallOption) (grpc.ClientStreamingClient[Req, Resp], error), req *Req, n int) bench.Call {
+	return func(ctx context.Context) error {
+		s, err := open(ctx)
+		if err != nil {
+			return err
+		}
+		for range n {
+			// Send 失败时真正的错误由 CloseAndRecv 返回
+			if s.Send(req) != nil {
+				break
+			}
+		}
+		_, err = s.CloseAndRecv()
+		return err
+	}
+}
+
+func serverStreamCall[Req, Resp any](open func(context.Context, *Req, ...grpc.CallOption) (grpc.ServerStreamingClient[Resp], error), req *Req) bench.Call {
+	return func(ctx context.Context) error {
+		s, err := open(ctx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err := s.Recv(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// bidiCall 一问一答，衡量的是 n 次往返的总时延
+func bidiCall[Req, Resp any](open func(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[Req, Resp], error), req *Req, n int) bench.Call {
+	return func(ctx context.Context) error {
+		// 中途返回时取消流，释放底层的 stream
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		s, err := open(ctx)
+		if err != nil {
+			return err
+		}
+		for range n {
+			// Send 失败时真正的错误由 Recv 返回
+			if s.Send(req) != nil {
+				break
+			}
+			if _, err := s.Recv(); err != nil {
+				return err
+			}
+		}
+		if err := s.CloseSend(); err != nil {
+			return err
+		}
+		if _, err := s.Recv(); err != io.EOF {
+			return err
+		}
+		return nil
+	}
+}
+
+func benchRPCNames() string {
+	names := make([]string, 0, len(benchRPCs))
+	for name := range benchRPCs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// benchResult -output json 时输出的报告
+type benchResult struct {
+	RPC         string  `json:"rpc"`
+	Concurrency int     `json:"concurrency"`
+	QPS         float64 `json:"qps"`
+	*bench.Report
+}
+
+func runBench(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, benchUsage, benchRPCNames())
+		fs.PrintDefaults()
+	}
+	conn := newConnFlags(fs)
+	output := fs.String("output", envString("GRPC_DEMO_OUTPUT", "text"), "output format, text or json ($GRPC_DEMO_OUTPUT)")
+	rpc := fs.String("rpc", "Add", "CalculatorService method to call")
+	cfg := bench.Config{}
+	fs.IntVar(&cfg.Concurrency, "concurrency", 10, "number of concurrent workers")
+	fs.Float64Var(&cfg.QPS, "qps", 0, "target calls per second, 0 to call as fast as the workers can")
+	fs.DurationVar(&cfg.Duration, "duration", 10*time.Second, "how long to run")
+	fs.DurationVar(&cfg.Interval, "interval", time.Second, "width of each throughput timeline bucket")
+	p := &payload{}
+	fs.Int64Var(&p.a, "a", 6, "first operand")
+	fs.Int64Var(&p.b, "b", 3, "second operand")
+	fs.IntVar(&p.messages, "messages", 10, "messages per stream, items per batch or length of RangeAdd")
+	fs.StringVar(&p.expr, "expr", "(1 + 2) * 3 ^ 2", "expression sent to Evaluate")
+	retry := fs.Bool("retry", false, "keep the client retry and hedging policies instead of measuring single attempts")
+	verbose := fs.Bool("v", false, "log every call to stderr")
+	metricsAddr := fs.String("metrics-addr", "", "HTTP address serving the client side Prometheus /metrics while the benchmark runs")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "bench: unexpected arguments %v\n", fs.Args())
+		return exitUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "bench: unknown output format %q\n", *output)
+		return exitUsage
+	}
+	newCall, ok := benchRPCs[*rpc]
+	if !ok {
+		fmt.Fprintf(stderr, "bench: unknown rpc %q, want one of %s\n", *rpc, benchRPCNames())
+		return exitUsage
+	}
+	if cfg.Concurrency < 1 || cfg.QPS < 0 || cfg.Duration <= 0 || cfg.Interval <= 0 || p.messages < 1 {
+		fmt.Fprintln(stderr, "bench: -concurrency and -messages must be positive, -duration and -interval greater than 0, -qps not negative")
+		return exitUsage
+	}
+
+	opts, err := conn.options()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if !*retry {
+		opts = append(opts,
+			client.WithRetryPolicy(client.RetryPolicy{}),
+			client.WithHedging(client.HedgingPolicy{}))
+	}
+	if !*verbose {
+		prev := log.Writer()
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(prev)
+	}
+	// Ctrl-C 提前结束压测，仍然输出已有的结果
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		opts = append(opts, client.WithMetrics(reg))
+		go func() {
+			if err := metrics.Serve(ctx, *metricsAddr, reg); err != nil {
+				fmt.Fprintf(stderr, "bench: metrics: %v\n", err)
+			}
+		}()
+	}
+	cc, err := client.Dial(conn.addr, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer cc.Close()
+
+	report := bench.Run(ctx, cfg, newCall(v1.NewCalculatorServiceClient(cc), p))
+
+	if *output == "json" {
+		err = json.NewEncoder(stdout).Encode(benchResult{RPC: *rpc, Concurrency: cfg.Concurrency, QPS: cfg.QPS, Report: report})
+	} else {
+		fmt.Fprintf(stdout, "rpc         %s (concurrency %d, qps %g)\n", *rpc, cfg.Concurrency, cfg.QPS)
+		err = report.WriteText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	// 一次都没成功通常是地址或凭证不对
+	if report.Requests > 0 && report.Errors == report.Requests {
+		return exitError
+	}
+	return exitOK
+}
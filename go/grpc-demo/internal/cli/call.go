@@ -0,0 +1,481 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/client"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"github.com/MorseWayne/grpc-demo/pkg/tlsutil"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const callUsage = `usage: grpc-demo call [flags] <rpc> [args]
+
+rpcs:
+  add|sub|mul|div|mod|pow [a b]  one call; without operands, one call per pair read from -input
+  sum [a b ...]                  SumStream over pairs from args or -input
+  stats [v ...]                  StatsStream over values from args or -input
+  range [-start n] [-end n] [-step n] [-rate r] [-resume token]
+                                 RangeAdd, one result per line
+  chat [-op add] [a b ...]       bidirectional stream, results are printed as they arrive
+  eval [-var name=value] [expr]  Evaluate; without expr, one expression per line of -input
+  session [-id id]               accumulator session on ChatAdd, one command per line of -input:
+                                 add n, sub n, reset [n], undo or snapshot; prints value, version
+                                 and undo depth after each, and the session id on stderr
+  history [-caller c] [-method m] [-code n] [-limit n] [-follow [-since-id id]]
+                                 recorded calculations, newest first; -follow tails new ones
+  faults [-set file | -clear]    show or replace the fault injection rules of a server
+                                 started with -faults or -fault-injection
+  ops [-wait] start primes N | start pow A B
+                                 start a long-running operation in the background and print
+                                 its id; -wait blocks until it is done
+  ops get ID | list | cancel ID | wait ID
+                                 id, kind, state, progress and result of operations
+  demo                           run the built-in demo script
+
+exit status is 0 on success, 1 if a call fails and 2 on bad flags or input.
+
+flags:
+`
+
+// errFlags 子命令参数解析失败，flag 包已经打印了错误信息
+var errFlags = errors.New("bad flags")
+
+// command 一个 call 子命令
+type command func(c *caller, args []string) error
+
+var commands = map[string]command{
+	"add": func(c *caller, args []string) error {
+		return c.binary(args, func(a, b int64) (proto.Message, string, error) {
+			r, err := c.client.Add(c.ctx, &v1.AddRequest{A: a, B: b})
+			return r, strconv.FormatInt(r.GetResult(), 10), err
+		})
+	},
+	"sub": resultCommand(func(c v1.CalculatorServiceClient) resultRPC { return c.Subtract }),
+	"mul": resultCommand(func(c v1.CalculatorServiceClient) resultRPC { return c.Multiply }),
+	"mod": resultCommand(func(c v1.CalculatorServiceClient) resultRPC { return c.Modulo }),
+	"pow": resultCommand(func(c v1.CalculatorServiceClient) resultRPC { return c.Pow }),
+	"div": func(c *caller, args []string) error {
+		return c.binary(args, func(a, b int64) (proto.Message, string, error) {
+			r, err := c.client.Divide(c.ctx, &v1.OperandsRequest{A: a, B: b})
+			return r, fmt.Sprintf("%d %d", r.GetQuotient(), r.GetRemainder()), err
+		})
+	},
+	"sum":     (*caller).sum,
+	"stats":   (*caller).stats,
+	"range":   (*caller).rangeAdd,
+	"chat":    (*caller).chat,
+	"eval":    (*caller).eval,
+	"session": (*caller).session,
+	"history": (*caller).history,
+	"faults":  (*caller).faults,
+	"ops":     (*caller).ops,
+}
+
+type resultRPC = func(context.Context, *v1.OperandsRequest, ...grpc.CallOption) (*v1.ResultResponse, error)
+
+// resultCommand 返回 ResultResponse 的一元调用
+func resultCommand(pick func(v1.CalculatorServiceClient) resultRPC) command {
+	return func(c *caller, args []string) error {
+		rpc := pick(c.client)
+		return c.binary(args, func(a, b int64) (proto.Message, string, error) {
+			r, err := rpc(c.ctx, &v1.OperandsRequest{A: a, B: b})
+			return r, strconv.FormatInt(r.GetResult(), 10), err
+		})
+	}
+}
+
+func runCall(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("call", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, callUsage)
+		fs.PrintDefaults()
+	}
+	conn := newConnFlags(fs)
+	output := fs.String("output", envString("GRPC_DEMO_OUTPUT", "text"), "output format, text or json ($GRPC_DEMO_OUTPUT)")
+	idempotencyKey := fs.String("idempotency-key", "", "key sent with the calls; the server answers a repeated unary call with the same key and payload from its stored result")
+	traceFile := fs.String("trace-file", envString("GRPC_DEMO_TRACE_FILE", ""), "JSON lines file the client spans are appended to ($GRPC_DEMO_TRACE_FILE)")
+	input := fs.String("input", "-", "file to read operands from, - for stdin")
+	verbose := fs.Bool("v", false, "log every call to stderr")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "call: unknown output format %q\n", *output)
+		return exitUsage
+	}
+	rpc, rest := fs.Arg(0), fs.Args()[1:]
+	cmd, ok := commands[rpc]
+	if !ok && rpc != "demo" {
+		fmt.Fprintf(stderr, "call: unknown rpc %q\n", rpc)
+		return exitUsage
+	}
+
+	opts, err := conn.options()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if *traceFile != "" {
+		exp, err := tracing.NewFileExporter(*traceFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer exp.Close()
+		opts = append(opts, client.WithTracer(tracing.NewTracer("grpc-demo-cli", exp)))
+	}
+	if rpc == "demo" {
+		if err := client.Run(conn.addr, opts...); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	// 拦截器的日志只在 -v 时输出，避免混进脚本读取的结果
+	if !*verbose {
+		prev := log.Writer()
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(prev)
+	}
+	in := stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer f.Close()
+		in = f
+	}
+	cc, err := client.Dial(conn.addr, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	defer cc.Close()
+
+	// Ctrl-C 取消正在进行的调用
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *idempotencyKey != "" {
+		ctx = interceptor.WithIdempotencyKey(ctx, *idempotencyKey)
+	}
+	c := &caller{
+		ctx:    ctx,
+		conn:   cc,
+		client: v1.NewCalculatorServiceClient(cc),
+		in:     in,
+		p:      &printer{out: stdout, errOut: stderr, json: *output == "json"},
+	}
+	return c.exit(rpc, cmd(c, rest))
+}
+
+// connFlags call 和 bench 共用的连接参数
+type connFlags struct {
+	addr    string
+	token   string
+	timeout time.Duration
+	tls     *tlsutil.Config
+}
+
+func newConnFlags(fs *flag.FlagSet) *connFlags {
+	f := &connFlags{tls: tlsFlags(fs)}
+	fs.StringVar(&f.addr, "addr", envString("GRPC_DEMO_ADDR", defaultAddr), "server address, or comma separated addresses balanced round robin ($GRPC_DEMO_ADDR)")
+	fs.StringVar(&f.tls.ServerName, "tls-server-name", envString("GRPC_DEMO_TLS_SERVER_NAME", ""), "name used to verify the server certificate ($GRPC_DEMO_TLS_SERVER_NAME)")
+	fs.StringVar(&f.token, "token", envString("GRPC_DEMO_TOKEN", ""), "bearer token sent with every call ($GRPC_DEMO_TOKEN)")
+	fs.DurationVar(&f.timeout, "timeout", envDuration("GRPC_DEMO_TIMEOUT", 10*time.Second), "timeout of each call, 0 disables ($GRPC_DEMO_TIMEOUT)")
+	return f
+}
+
+// options 转换成 client 的配置，证书文件有问题时返回错误
+func (f *connFlags) options() ([]client.Option, error) {
+	opts := []client.Option{client.WithTimeout(f.timeout)}
+	if f.tls.Enabled() {
+		reloader, err := tlsutil.NewReloader(*f.tls)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithTLS(reloader.ClientTLSConfig()))
+	}
+	if f.token != "" {
+		opts = append(opts, client.WithToken(f.token))
+	}
+	return opts, nil
+}
+
+// caller 执行 call 子命令的上下文
+type caller struct {
+	ctx    context.Context
+	conn   *grpc.ClientConn
+	client v1.CalculatorServiceClient
+	in     io.Reader
+	p      *printer
+}
+
+// exit 打印错误并返回退出码
+func (c *caller) exit(rpc string, err error) int {
+	var ue *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errFlags):
+		return exitUsage
+	case errors.As(err, &ue):
+		fmt.Fprintf(c.p.errOut, "%s: %v\n", rpc, err)
+		return exitUsage
+	}
+	c.p.error(rpc, err)
+	return exitError
+}
+
+// flags 子命令自己的参数，错误信息写到标准错误
+func (c *caller) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.p.errOut)
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errFlags
+	}
+	return nil
+}
+
+// binary 一元调用：给出两个操作数时调用一次，否则对输入中的每一对操作数各调用一次，遇到错误即停止
+func (c *caller) binary(args []string, call func(a, b int64) (proto.Message, string, error)) error {
+	if len(args) != 0 && len(args) != 2 {
+		return &usageError{"want two operands, or none to read pairs from input"}
+	}
+	ns := newNumbers(args, c.in)
+	for {
+		a, b, ok, err := ns.pair()
+		if err != nil || !ok {
+			return err
+		}
+		m, text, err := call(a, b)
+		if err != nil {
+			return err
+		}
+		if err := c.p.result(m, text); err != nil {
+			return err
+		}
+	}
+}
+
+// sum 客户端流：把所有操作数对发给 SumStream
+func (c *caller) sum(args []string) error {
+	ns := newNumbers(args, c.in)
+	s, err := c.client.SumStream(c.ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		a, b, ok, err := ns.pair()
+		if err != nil {
+			return err
+		}
+		// Send 失败时真正的错误由 CloseAndRecv 返回
+		if !ok || s.Send(&v1.AddRequest{A: a, B: b}) != nil {
+			break
+		}
+	}
+	r, err := s.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	return c.p.result(r, strconv.FormatInt(r.GetResult(), 10))
+}
+
+// stats 客户端流：文本输出为每行一个 "名称<TAB>值"
+func (c *caller) stats(args []string) error {
+	ns := newNumbers(args, c.in)
+	s, err := c.client.StatsStream(c.ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		v, ok, err := ns.next()
+		if err != nil {
+			return err
+		}
+		if !ok || s.Send(&v1.StatsRequest{Value: v}) != nil {
+			break
+		}
+	}
+	r, err := s.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "count\t%d\nsum\t%d\nmin\t%d\nmax\t%d\nmean\t%g\nvariance\t%g",
+		r.GetCount(), r.GetSum(), r.GetMin(), r.GetMax(), r.GetMean(), r.GetVariance())
+	for _, p := range r.GetPercentiles() {
+		fmt.Fprintf(&b, "\np%g\t%g", p.GetQuantile()*100, p.GetValue())
+	}
+	return c.p.result(r, b.String())
+}
+
+// rangeAdd 服务端流：每收到一个结果输出一行
+func (c *caller) rangeAdd(args []string) error {
+	fs := c.flags("range")
+	req := &v1.RangeRequest{}
+	fs.Int64Var(&req.Start, "start", 1, "first value")
+	fs.Int64Var(&req.End, "end", 10, "last value, inclusive")
+	fs.Int64Var(&req.Step, "step", 1, "step between values")
+	fs.Float64Var(&req.Rate, "rate", 0, "results per second, 0 for no limit")
+	fs.StringVar(&req.ResumeToken, "resume", "", "continuation token of an interrupted stream")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected arguments %v", fs.Args())}
+	}
+	s, err := c.client.RangeAdd(c.ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		m, err := s.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.p.result(m, strconv.FormatInt(m.GetResult(), 10)); err != nil {
+			return err
+		}
+	}
+}
+
+type resultChatRPC = func(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[v1.OperandsRequest, v1.ResultResponse], error)
+
+// chat 双向流：-op 选择运算，操作数来自参数或输入
+func (c *caller) chat(args []string) error {
+	fs := c.flags("chat")
+	op := fs.String("op", "add", "operation: add, sub, mul, div, mod or pow")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg()%2 != 0 {
+		return &usageError{"operands must come in pairs"}
+	}
+	ns := newNumbers(fs.Args(), c.in)
+	var open resultChatRPC
+	switch *op {
+	case "add":
+		s, err := c.client.ChatAdd(c.ctx)
+		if err != nil {
+			return err
+		}
+		return chat(c, s, ns,
+			func(a, b int64) *v1.AddRequest { return &v1.AddRequest{A: a, B: b} },
+			func(r *v1.AddResponse) (proto.Message, string) { return r, strconv.FormatInt(r.GetResult(), 10) })
+	case "div":
+		s, err := c.client.ChatDivide(c.ctx)
+		if err != nil {
+			return err
+		}
+		return chat(c, s, ns,
+			func(a, b int64) *v1.OperandsRequest { return &v1.OperandsRequest{A: a, B: b} },
+			func(r *v1.DivideResponse) (proto.Message, string) {
+				return r, fmt.Sprintf("%d %d", r.GetQuotient(), r.GetRemainder())
+			})
+	case "sub":
+		open = c.client.ChatSubtract
+	case "mul":
+		open = c.client.ChatMultiply
+	case "mod":
+		open = c.client.ChatModulo
+	case "pow":
+		open = c.client.ChatPow
+	default:
+		return &usageError{fmt.Sprintf("unknown op %q", *op)}
+	}
+	s, err := open(c.ctx)
+	if err != nil {
+		return err
+	}
+	return chat(c, s, ns,
+		func(a, b int64) *v1.OperandsRequest { return &v1.OperandsRequest{A: a, B: b} },
+		func(r *v1.ResultResponse) (proto.Message, string) { return r, strconv.FormatInt(r.GetResult(), 10) })
+}
+
+// chat 一边发送输入中的操作数一边按到达顺序打印响应，输入读完后关闭发送端
+func chat[Req, Resp any](c *caller, s grpc.BidiStreamingClient[Req, Resp], ns *numbers,
+	req func(a, b int64) *Req, format func(*Resp) (proto.Message, string)) error {
+	sendErr := make(chan error, 1)
+	go func() {
+		defer s.CloseSend()
+		for {
+			a, b, ok, err := ns.pair()
+			// Send 失败时真正的错误由 Recv 返回
+			if err != nil || !ok || s.Send(req(a, b)) != nil {
+				sendErr <- err
+				return
+			}
+		}
+	}()
+	for {
+		m, err := s.Recv()
+		if err == io.EOF {
+			return <-sendErr
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.p.result(format(m)); err != nil {
+			return err
+		}
+	}
+}
+
+// eval 求值命令行给出的表达式；没有给出时逐行求值输入中的表达式
+func (c *caller) eval(args []string) error {
+	fs := c.flags("eval")
+	vars := variables{}
+	fs.Var(vars, "var", "variable as name=value, can be repeated")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	eval := func(expression string) error {
+		r, err := c.client.Evaluate(c.ctx, &v1.EvaluateRequest{Expression: expression, Variables: vars})
+		if err != nil {
+			return err
+		}
+		return c.p.result(r, strconv.FormatInt(r.GetResult(), 10))
+	}
+	if fs.NArg() > 0 {
+		return eval(strings.Join(fs.Args(), " "))
+	}
+	return lines(c.in, eval)
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 退出码
+const (
+	exitOK    = 0
+	exitError = 1 // 调用失败
+	exitUsage = 2 // 参数错误
+)
+
+const defaultAddr = "localhost:12345"
+
+const usage = `usage: grpc-demo <command> [flags]
+
+commands:
+  serve    start the calculator server
+  call     call the calculator server, run "grpc-demo call -h" for details
+  bench    load test one rpc, run "grpc-demo bench -h" for details
+
+every flag can also be set through the environment variable shown in its help.
+`
+
+// Main 命令行入口，返回进程退出码
+func Main(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "serve", "server":
+		return runServe(args[1:], stderr)
+	case "call":
+		return runCall(args[1:], stdin, stdout, stderr)
+	case "bench":
+		return runBench(args[1:], stdout, stderr)
+	case "client":
+		// 兼容旧用法：grpc-demo client 等同于 grpc-demo call demo
+		return runCall(append([]string{"demo"}, args[1:]...), stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+	return exitUsage
+}
+
+// envString 环境变量未设置时返回 def
+func envString(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil {
+		return d
+	}
+	return def
+}
+
+func envFloat(name string, def float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil {
+		return f
+	}
+	return def
+}
+
+func envInt(name string, def int) int {
+	if i, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return i
+	}
+	return def
+}
+
+func envBool(name string, def bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(name)); err == nil {
+		return b
+	}
+	return def
+}
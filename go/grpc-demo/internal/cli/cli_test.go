@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MorseWayne/grpc-demo/internal/server"
+)
+
+// startServer 在本机随机端口上启动服务端，返回监听地址
+func startServer(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, lis)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return lis.Addr().String()
+}
+
+// setenv 设置测试期间的环境变量
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func noSpace(s string) string {
+	return strings.ReplaceAll(s, " ", "")
+}
+
+func TestRunCallFlags(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    int
+		wantErr string
+	}{
+		{"help", []string{"-h"}, nil, exitOK, "usage: grpc-demo call"},
+		{"no rpc", nil, nil, exitUsage, "usage: grpc-demo call"},
+		{"unknown flag", []string{"-bogus", "add"}, nil, exitUsage, "flag provided but not defined"},
+		{"unknown rpc", []string{"frobnicate"}, nil, exitUsage, `unknown rpc "frobnicate"`},
+		{"unknown output", []string{"-output", "yaml", "add"}, nil, exitUsage, `unknown output format "yaml"`},
+		{"output from env", []string{"add"}, map[string]string{"GRPC_DEMO_OUTPUT": "xml"}, exitUsage, `unknown output format "xml"`},
+		{"flag overrides env", []string{"-output", "csv", "add"}, map[string]string{"GRPC_DEMO_OUTPUT": "json"}, exitUsage, `unknown output format "csv"`},
+		{"missing input file", []string{"-input", missing, "add"}, nil, exitUsage, "no such file"},
+		{"missing tls cert", []string{"-tls-ca", missing, "add", "1", "2"}, nil, exitUsage, "no such file"},
+		{"tls cert from env", []string{"add", "1", "2"}, map[string]string{"GRPC_DEMO_TLS_CA": missing}, exitUsage, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+			var stdout, stderr bytes.Buffer
+			if got := runCall(tt.args, strings.NewReader(""), &stdout, &stderr); got != tt.want {
+				t.Fatalf("exit = %d, want %d; stderr:\n%s", got, tt.want, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr = %q, want it to contain %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunCall(t *testing.T) {
+	addr := startServer(t)
+	input := filepath.Join(t.TempDir(), "pairs.txt")
+	if err := os.WriteFile(input, []byte("10 20\n30 40\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		stdin   string
+		want    int
+		wantOut string
+		wantErr string
+	}{
+		{"operands", []string{"-addr", addr, "add", "1", "2"}, nil, "", exitOK, "3\n", ""},
+		{"stdin", []string{"-addr", addr, "add"}, nil, "1 2\n3 4\n", exitOK, "3\n7\n", ""},
+		{"input file", []string{"-addr", addr, "-input", input, "sub"}, nil, "1 2\n", exitOK, "-10\n-10\n", ""},
+		{"json", []string{"-addr", addr, "-output", "json", "div", "7", "2"}, nil, "", exitOK, `{"quotient":"3","remainder":"1"}` + "\n", ""},
+		{"addr and output from env", []string{"add", "1", "2"}, map[string]string{"GRPC_DEMO_ADDR": addr, "GRPC_DEMO_OUTPUT": "json"}, "", exitOK, `{"result":"3"}` + "\n", ""},
+		{"output flag overrides env", []string{"-addr", addr, "-output", "text", "add", "1", "2"}, map[string]string{"GRPC_DEMO_OUTPUT": "json"}, "", exitOK, "3\n", ""},
+		{"server stream", []string{"-addr", addr, "range", "-start", "1", "-end", "3"}, nil, "", exitOK, "1\n2\n3\n", ""},
+		{"call fails", []string{"-addr", addr, "div", "1", "0"}, nil, "", exitError, "", "code = InvalidArgument"},
+		{"call fails as json", []string{"-addr", addr, "-output", "json", "div", "1", "0"}, nil, "", exitError, "", `"code":3`},
+		{"stops at the first failure", []string{"-addr", addr, "div"}, nil, "4 2\n1 0\n9 3\n", exitError, "2 0\n", "code = InvalidArgument"},
+		{"timeout from env", []string{"-addr", addr, "add", "1", "2"}, map[string]string{"GRPC_DEMO_TIMEOUT": "1ns"}, "", exitError, "", "DeadlineExceeded"},
+		{"timeout flag overrides env", []string{"-addr", addr, "-timeout", "10s", "add", "1", "2"}, map[string]string{"GRPC_DEMO_TIMEOUT": "1ns"}, "", exitOK, "3\n", ""},
+		{"one operand", []string{"-addr", addr, "add", "1"}, nil, "", exitUsage, "", "want two operands"},
+		{"bad operand", []string{"-addr", addr, "add"}, nil, "1 x\n", exitUsage, "", `"x" is not an int64`},
+		{"missing second operand", []string{"-addr", addr, "add"}, nil, "1 2 3\n", exitUsage, "3\n", "missing second operand"},
+		{"bad rpc flag", []string{"-addr", addr, "range", "-bogus"}, nil, "", exitUsage, "", "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+			var stdout, stderr bytes.Buffer
+			if got := runCall(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr); got != tt.want {
+				t.Fatalf("exit = %d, want %d; stderr:\n%s", got, tt.want, stderr.String())
+			}
+			// protojson 的输出会随机插入空格，比较时去掉
+			if noSpace(stdout.String()) != noSpace(tt.wantOut) {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantOut)
+			}
+			if !strings.Contains(noSpace(stderr.String()), noSpace(tt.wantErr)) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunServeFlags(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    int
+		wantErr string
+	}{
+		{"unknown flag", []string{"-bogus"}, nil, exitUsage, "flag provided but not defined"},
+		{"unexpected argument", []string{"extra"}, nil, exitUsage, "unexpected arguments [extra]"},
+		{"bad duration", []string{"-shutdown-timeout", "soon"}, nil, exitUsage, "invalid value"},
+		{"invalid concurrency range", []string{"-concurrency-limit", "5", "-concurrency-min", "0"}, nil, exitUsage, "invalid concurrency range [0, 1000]"},
+		{"concurrency from env", nil, map[string]string{"GRPC_DEMO_CONCURRENCY_LIMIT": "5", "GRPC_DEMO_CONCURRENCY_MAX": "1"}, exitUsage, "invalid concurrency range [10, 1]"},
+		{"flag overrides env", []string{"-concurrency-min", "6", "-concurrency-max", "5"}, map[string]string{"GRPC_DEMO_CONCURRENCY_LIMIT": "5", "GRPC_DEMO_CONCURRENCY_MAX": "100"}, exitUsage, "invalid concurrency range [6, 5]"},
+		{"unparsable env falls back to the default", []string{"-concurrency-limit", "5", "-concurrency-max", "5"}, map[string]string{"GRPC_DEMO_CONCURRENCY_MIN": "many"}, exitUsage, "invalid concurrency range [10, 5]"},
+		{"missing tls cert", []string{"-tls-cert", missing, "-tls-key", missing}, nil, exitUsage, "no such file"},
+		{"history file in a missing directory", []string{"-history-file", filepath.Join(missing, "history.log")}, nil, exitUsage, "no such file"},
+		{"history file from env", nil, map[string]string{"GRPC_DEMO_HISTORY_FILE": filepath.Join(missing, "history.log")}, exitUsage, "no such file"},
+		{"missing faults file", []string{"-faults", missing}, nil, exitUsage, "no such file"},
+		{"listen fails", []string{"-addr", "127.0.0.1:-1"}, nil, exitError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+			var stderr bytes.Buffer
+			if got := runServe(tt.args, &stderr); got != tt.want {
+				t.Fatalf("exit = %d, want %d; stderr:\n%s", got, tt.want, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Fatalf("stderr = %q, want it to contain %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/server"
+	"google.golang.org/grpc/codes"
+)
+
+// faults 打印服务端当前的故障注入规则；-set 用文件中的规则整体替换，-clear 清除所有规则
+func (c *caller) faults(args []string) error {
+	fs := c.flags("faults")
+	set := fs.String("set", "", "replace the rules with the ones in this JSON file, same format as serve -faults")
+	clearRules := fs.Bool("clear", false, "remove all rules")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected arguments %v", fs.Args())}
+	}
+	if *set != "" && *clearRules {
+		return &usageError{"-set and -clear are mutually exclusive"}
+	}
+	client := v1.NewFaultServiceClient(c.conn)
+
+	var cfg *v1.FaultConfig
+	var err error
+	switch {
+	case *set != "":
+		var req *v1.FaultConfig
+		if req, err = server.ReadFaultConfig(*set); err != nil {
+			return &usageError{err.Error()}
+		}
+		cfg, err = client.SetFaults(c.ctx, req)
+	case *clearRules:
+		cfg, err = client.SetFaults(c.ctx, &v1.FaultConfig{})
+	default:
+		cfg, err = client.GetFaults(c.ctx, &v1.GetFaultsRequest{})
+	}
+	if err != nil {
+		return err
+	}
+	return c.p.result(cfg, faultLines(cfg))
+}
+
+// faultLines 文本输出，每条规则一行
+func faultLines(cfg *v1.FaultConfig) string {
+	if len(cfg.Rules) == 0 {
+		return "no fault rules"
+	}
+	lines := make([]string, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		line := r.Method
+		if d := r.Delay.AsDuration(); d > 0 {
+			line += fmt.Sprintf("\tdelay %s%s", d, percent(r.DelayPercent))
+		}
+		if r.AbortCode != 0 || r.CutAfter > 0 {
+			code := codes.Code(r.AbortCode)
+			if code == codes.OK {
+				code = codes.Unavailable
+			}
+			line += fmt.Sprintf("\tabort %s%s", code, percent(r.AbortPercent))
+			if r.CutAfter > 0 {
+				line += fmt.Sprintf(" after %d messages", r.CutAfter)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func percent(p float64) string {
+	if p == 0 || p >= 100 {
+		return ""
+	}
+	return fmt.Sprintf(" %g%%", p)
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"google.golang.org/grpc/codes"
+)
+
+// history 倒序列出计算记录；-follow 时改为按 ID 升序持续输出新记录
+func (c *caller) history(args []string) error {
+	fs := c.flags("history")
+	filter := &v1.HistoryFilter{}
+	fs.StringVar(&filter.Caller, "caller", "", "only calls from this caller, e.g. sub:alice or ip:127.0.0.1")
+	fs.StringVar(&filter.Method, "method", "", "only this method, full name or just the method such as Add")
+	code := fs.Int("code", -1, "only calls that ended with this gRPC status code")
+	limit := fs.Int("limit", 20, "number of entries to list")
+	follow := fs.Bool("follow", false, "stream new entries as they are recorded")
+	sinceID := fs.Int64("since-id", -1, "with -follow, first replay entries after this id")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected arguments %v", fs.Args())}
+	}
+	if *code >= 0 {
+		filter.Codes = []uint32{uint32(*code)}
+	}
+	client := v1.NewHistoryServiceClient(c.conn)
+
+	if *follow {
+		req := &v1.StreamHistoryRequest{Filter: filter}
+		if *sinceID >= 0 {
+			id := uint64(*sinceID)
+			req.SinceId = &id
+		}
+		s, err := client.StreamHistory(c.ctx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			e, err := s.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := c.p.result(e, historyLine(e)); err != nil {
+				return err
+			}
+		}
+	}
+
+	req := &v1.ListHistoryRequest{Filter: filter}
+	for n := 0; n < *limit; {
+		req.PageSize = int32(min(*limit-n, 1000))
+		r, err := client.ListHistory(c.ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, e := range r.Entries {
+			if err := c.p.result(e, historyLine(e)); err != nil {
+				return err
+			}
+		}
+		n += len(r.Entries)
+		if r.NextPageToken == "" {
+			return nil
+		}
+		req.PageToken = r.NextPageToken
+	}
+	return nil
+}
+
+// historyLine 文本输出：id、时间、调用方、方法、状态、耗时，然后是请求和响应（或错误）
+func historyLine(e *v1.HistoryEntry) string {
+	method := e.Method[strings.LastIndex(e.Method, "/")+1:]
+	result := e.Response
+	if codes.Code(e.Code) != codes.OK {
+		result = e.Error
+	}
+	return fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s\t%s -> %s",
+		e.Id, e.Time.AsTime().Local().Format(time.RFC3339), e.Caller, method,
+		codes.Code(e.Code), e.Latency.AsDuration(), e.Request, result)
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// usageError 参数或输入数据格式错误，对应退出码 2
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// numbers 依次读取整数：命令行给出了操作数时只读参数，否则从输入中按空白分隔读取，
+// 每读到一个就返回一个，交互输入时不必等到 EOF
+type numbers struct {
+	args []string
+	sc   *bufio.Scanner
+	n    int // 已读取的个数，用于错误提示
+}
+
+func newNumbers(args []string, in io.Reader) *numbers {
+	if len(args) > 0 {
+		return &numbers{args: args}
+	}
+	sc := bufio.NewScanner(in)
+	sc.Split(bufio.ScanWords)
+	return &numbers{sc: sc}
+}
+
+// next 读取下一个整数，没有更多输入时 ok 为 false
+func (n *numbers) next() (v int64, ok bool, err error) {
+	var word string
+	switch {
+	case n.sc == nil:
+		if len(n.args) == 0 {
+			return 0, false, nil
+		}
+		word, n.args = n.args[0], n.args[1:]
+	case n.sc.Scan():
+		word = n.sc.Text()
+	default:
+		return 0, false, n.sc.Err()
+	}
+	n.n++
+	v, err = strconv.ParseInt(word, 10, 64)
+	if err != nil {
+		return 0, false, &usageError{fmt.Sprintf("operand #%d: %q is not an int64", n.n, word)}
+	}
+	return v, true, nil
+}
+
+// pair 读取一对操作数，输入在两个数之间结束时报错
+func (n *numbers) pair() (a, b int64, ok bool, err error) {
+	if a, ok, err = n.next(); !ok || err != nil {
+		return 0, 0, false, err
+	}
+	if b, ok, err = n.next(); err != nil {
+		return 0, 0, false, err
+	}
+	if !ok {
+		return 0, 0, false, &usageError{fmt.Sprintf("operand #%d: missing second operand", n.n)}
+	}
+	return a, b, true, nil
+}
+
+// lines 逐行读取非空输入，首尾空白会被去掉
+func lines(in io.Reader, fn func(line string) error) error {
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// variables 解析 -var name=value，可以重复出现
+type variables map[string]int64
+
+func (v variables) String() string {
+	return fmt.Sprint(map[string]int64(v))
+}
+
+func (v variables) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("variable %s: %q is not an int64", name, value)
+	}
+	v[name] = i
+	return nil
+}
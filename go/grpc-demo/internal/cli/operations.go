@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const opsUsage = "want start primes N, start pow A B, get ID, list, cancel ID or wait ID"
+
+// ops 管理服务端的长时间运行任务：start 启动后打印任务 id，-wait 时等到任务结束；
+// get、list、cancel、wait 打印任务状态，每个任务一行
+func (c *caller) ops(args []string) error {
+	fs := c.flags("ops")
+	wait := fs.Bool("wait", false, "with start, wait until the operation is done")
+	timeout := fs.Duration("wait-timeout", 5*time.Minute, "longest time wait and start -wait block before printing the current state")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return &usageError{opsUsage}
+	}
+	client := v1.NewOperationServiceClient(c.conn)
+	sub, rest := fs.Arg(0), fs.Args()[1:]
+	var op *v1.Operation
+	var err error
+	switch {
+	case sub == "start":
+		req, uerr := startRequest(rest)
+		if uerr != nil {
+			return uerr
+		}
+		if op, err = client.StartOperation(c.ctx, req); err != nil || !*wait {
+			break
+		}
+		op, err = c.waitOperation(client, op.Id, *timeout)
+	case sub == "list" && len(rest) == 0:
+		resp, err := client.ListOperations(c.ctx, &v1.ListOperationsRequest{})
+		if err != nil {
+			return err
+		}
+		lines := make([]string, 0, len(resp.Operations))
+		for _, op := range resp.Operations {
+			lines = append(lines, operationLine(op))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "no operations")
+		}
+		return c.p.result(resp, strings.Join(lines, "\n"))
+	case sub == "get" && len(rest) == 1:
+		op, err = client.GetOperation(c.ctx, &v1.GetOperationRequest{Id: rest[0]})
+	case sub == "cancel" && len(rest) == 1:
+		op, err = client.CancelOperation(c.ctx, &v1.CancelOperationRequest{Id: rest[0]})
+	case sub == "wait" && len(rest) == 1:
+		op, err = c.waitOperation(client, rest[0], *timeout)
+	default:
+		return &usageError{fmt.Sprintf("unexpected arguments %v, %s", fs.Args(), opsUsage)}
+	}
+	if err != nil {
+		return err
+	}
+	return c.p.result(op, operationLine(op))
+}
+
+// waitOperation 调用本身的截止时间比等待时间稍长，不受 -timeout 限制
+func (c *caller) waitOperation(client v1.OperationServiceClient, id string, timeout time.Duration) (*v1.Operation, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, timeout+5*time.Second)
+	defer cancel()
+	return client.WaitOperation(ctx, &v1.WaitOperationRequest{Id: id, Timeout: durationpb.New(timeout)})
+}
+
+func startRequest(args []string) (*v1.StartOperationRequest, error) {
+	switch {
+	case len(args) == 2 && args[0] == "primes":
+		below, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, &usageError{fmt.Sprintf("invalid number %q", args[1])}
+		}
+		return &v1.StartOperationRequest{Job: &v1.StartOperationRequest_CountPrimes{CountPrimes: &v1.CountPrimesJob{Below: below}}}, nil
+	case len(args) == 3 && args[0] == "pow":
+		return &v1.StartOperationRequest{Job: &v1.StartOperationRequest_Pow{Pow: &v1.PowJob{A: args[1], B: args[2]}}}, nil
+	}
+	return nil, &usageError{fmt.Sprintf("unexpected arguments %v, %s", args, opsUsage)}
+}
+
+// operationLine 文本输出：id、种类、状态、进度，结束的任务再加上结果或错误
+func operationLine(op *v1.Operation) string {
+	line := fmt.Sprintf("%s\t%s\t%s\t%.1f%%", op.Id, op.Kind, strings.ToLower(op.State.String()), op.Progress)
+	switch {
+	case op.GetResult() != nil && op.GetResult().GetPow() != "":
+		line += "\t" + op.GetResult().GetPow()
+	case op.GetResult() != nil:
+		line += "\t" + strconv.FormatInt(op.GetResult().GetPrimeCount(), 10)
+	case op.GetError() != nil:
+		line += fmt.Sprintf("\t%s: %s", codes.Code(op.GetError().Code), op.GetError().Message)
+	}
+	return line
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// printer 按 -output 输出结果：text 每行一个可读结果，json 每行一个响应消息（NDJSON）
+type printer struct {
+	out, errOut io.Writer
+	json        bool
+}
+
+// result 输出一条响应，text 为文本模式下的内容
+func (p *printer) result(m proto.Message, text string) error {
+	if !p.json {
+		_, err := fmt.Fprintln(p.out, text)
+		return err
+	}
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.out, "%s\n", b)
+	return err
+}
+
+// error 将 gRPC 错误及其 details 写到标准错误；json 模式下输出 google.rpc.Status
+func (p *printer) error(op string, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		fmt.Fprintf(p.errOut, "%s: %v\n", op, err)
+		return
+	}
+	if p.json {
+		if b, err := protojson.Marshal(st.Proto()); err == nil {
+			fmt.Fprintf(p.errOut, "%s\n", b)
+			return
+		}
+	}
+	fmt.Fprintf(p.errOut, "%s: code = %s, message = %s\n", op, st.Code(), st.Message())
+	for _, d := range st.Details() {
+		switch detail := d.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range detail.GetFieldViolations() {
+				fmt.Fprintf(p.errOut, "  bad request: field = %s, %s\n", v.GetField(), v.GetDescription())
+			}
+		case *errdetails.ErrorInfo:
+			fmt.Fprintf(p.errOut, "  error info: reason = %s, domain = %s, metadata = %v\n",
+				detail.GetReason(), detail.GetDomain(), detail.GetMetadata())
+		case *errdetails.RetryInfo:
+			fmt.Fprintf(p.errOut, "  retry info: retry after %s\n", detail.GetRetryDelay().AsDuration())
+		default:
+			fmt.Fprintf(p.errOut, "  detail: %v\n", detail)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/history"
+	"github.com/MorseWayne/grpc-demo/internal/operation"
+	"github.com/MorseWayne/grpc-demo/internal/server"
+	"github.com/MorseWayne/grpc-demo/internal/session"
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"github.com/MorseWayne/grpc-demo/pkg/concurrency"
+	"github.com/MorseWayne/grpc-demo/pkg/idempotency"
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
+	"github.com/MorseWayne/grpc-demo/pkg/ratelimit"
+	"github.com/MorseWayne/grpc-demo/pkg/tlsutil"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+)
+
+func runServe(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", envString("GRPC_DEMO_ADDR", defaultAddr), "listen address ($GRPC_DEMO_ADDR)")
+	tlsCfg := tlsFlags(fs)
+	jwtKey := fs.String("jwt-key", envString("GRPC_DEMO_JWT_KEY", ""), "HMAC key, enables JWT authentication ($GRPC_DEMO_JWT_KEY)")
+	jwtIssuer := fs.String("jwt-issuer", envString("GRPC_DEMO_JWT_ISSUER", ""), "required token issuer ($GRPC_DEMO_JWT_ISSUER)")
+	rate := fs.Float64("rate-limit", envFloat("GRPC_DEMO_RATE_LIMIT", 0), "requests per second per caller and method, 0 disables ($GRPC_DEMO_RATE_LIMIT)")
+	burst := fs.Int("rate-burst", envInt("GRPC_DEMO_RATE_BURST", 0), "rate limit burst, defaults to the rate ($GRPC_DEMO_RATE_BURST)")
+	concurrencyLimit := fs.Int("concurrency-limit", envInt("GRPC_DEMO_CONCURRENCY_LIMIT", 0), "initial limit of concurrent calls, adjusted from observed latency; calls over the limit fail with Unavailable, 0 disables ($GRPC_DEMO_CONCURRENCY_LIMIT)")
+	concurrencyMin := fs.Int("concurrency-min", envInt("GRPC_DEMO_CONCURRENCY_MIN", 10), "lowest adaptive concurrency limit ($GRPC_DEMO_CONCURRENCY_MIN)")
+	concurrencyMax := fs.Int("concurrency-max", envInt("GRPC_DEMO_CONCURRENCY_MAX", 1000), "highest adaptive concurrency limit ($GRPC_DEMO_CONCURRENCY_MAX)")
+	historyFile := fs.String("history-file", envString("GRPC_DEMO_HISTORY_FILE", ""), "append-only file keeping the calculation history across restarts, in memory if empty ($GRPC_DEMO_HISTORY_FILE)")
+	historyLimit := fs.Int("history-limit", envInt("GRPC_DEMO_HISTORY_LIMIT", history.DefaultFileLimit), "entries of -history-file kept in memory and queryable; the file is rotated to FILE.1 after this many entries, so at most twice as many stay on disk, 0 keeps everything ($GRPC_DEMO_HISTORY_LIMIT)")
+	idempotencyTTL := fs.Duration("idempotency-ttl", envDuration("GRPC_DEMO_IDEMPOTENCY_TTL", idempotency.DefaultTTL), "how long results of calls with an idempotency-key are kept, 0 ignores the key ($GRPC_DEMO_IDEMPOTENCY_TTL)")
+	sessionIdle := fs.Duration("session-idle", envDuration("GRPC_DEMO_SESSION_IDLE", session.DefaultIdleTimeout), "how long a ChatAdd session is kept after its last stream ends ($GRPC_DEMO_SESSION_IDLE)")
+	opRetention := fs.Duration("operation-retention", envDuration("GRPC_DEMO_OPERATION_RETENTION", operation.DefaultRetention), "how long results of finished long-running operations are kept ($GRPC_DEMO_OPERATION_RETENTION)")
+	opRunning := fs.Int("max-running-operations", envInt("GRPC_DEMO_MAX_RUNNING_OPERATIONS", operation.DefaultMaxRunning), "long-running operations allowed to run at the same time ($GRPC_DEMO_MAX_RUNNING_OPERATIONS)")
+	traceFile := fs.String("trace-file", envString("GRPC_DEMO_TRACE_FILE", ""), "JSON lines file the finished spans are appended to, tracing disabled if empty ($GRPC_DEMO_TRACE_FILE)")
+	faults := fs.String("faults", envString("GRPC_DEMO_FAULTS", ""), "JSON file with fault injection rules, e.g. {\"rules\":[{\"method\":\"Add\",\"abortCode\":14,\"abortPercent\":50}]}; also enables the FaultService admin rpc ($GRPC_DEMO_FAULTS)")
+	faultInjection := fs.Bool("fault-injection", envBool("GRPC_DEMO_FAULT_INJECTION", false), "enable fault injection without initial rules, set them with grpc-demo call faults ($GRPC_DEMO_FAULT_INJECTION)")
+	gw := fs.Bool("gateway", envBool("GRPC_DEMO_GATEWAY", false), "also serve the HTTP/JSON gateway on the listen address, e.g. curl -d '{\"a\":1,\"b\":2}' http://ADDR/v1/add ($GRPC_DEMO_GATEWAY)")
+	web := fs.Bool("web", envBool("GRPC_DEMO_WEB", false), "also accept gRPC-Web and Connect requests from browsers on the listen address ($GRPC_DEMO_WEB)")
+	corsOrigins := fs.String("cors-origins", envString("GRPC_DEMO_CORS_ORIGINS", ""), "comma separated origins allowed to call the gateway and gRPC-Web/Connect from a browser, * for any ($GRPC_DEMO_CORS_ORIGINS)")
+	metricsAddr := fs.String("metrics-addr", envString("GRPC_DEMO_METRICS_ADDR", ""), "separate HTTP address serving Prometheus /metrics, disabled if empty ($GRPC_DEMO_METRICS_ADDR)")
+	shutdown := fs.Duration("shutdown-timeout", envDuration("GRPC_DEMO_SHUTDOWN_TIMEOUT", 10e9), "graceful shutdown timeout ($GRPC_DEMO_SHUTDOWN_TIMEOUT)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "serve: unexpected arguments %v\n", fs.Args())
+		return exitUsage
+	}
+
+	opts := []server.Option{
+		server.WithShutdownTimeout(*shutdown),
+		server.WithSessionIdleTimeout(*sessionIdle),
+		server.WithIdempotencyTTL(*idempotencyTTL),
+	}
+	// 服务端配置 CA 即要求客户端证书（mTLS）
+	if tlsCfg.Enabled() {
+		reloader, err := tlsutil.NewReloader(*tlsCfg)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		opts = append(opts, server.WithTLS(reloader.ServerTLSConfig()))
+	}
+	if *jwtKey != "" {
+		opts = append(opts, server.WithAuth(auth.NewVerifier([]byte(*jwtKey), *jwtIssuer), server.DefaultPolicy()))
+	}
+	if *historyFile != "" {
+		store, err := history.OpenFileStore(*historyFile, *historyLimit)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer store.Close()
+		opts = append(opts, server.WithHistory(store))
+	}
+	// 退出时取消还在运行的任务
+	operations := operation.NewStore(*opRetention, 0, *opRunning, 0)
+	defer operations.Close()
+	opts = append(opts, server.WithOperations(operations))
+	if *traceFile != "" {
+		exp, err := tracing.NewFileExporter(*traceFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		defer exp.Close()
+		opts = append(opts, server.WithTracer(tracing.NewTracer("grpc-demo", exp)))
+	}
+	if *faults != "" || *faultInjection {
+		var cfg *v1.FaultConfig
+		if *faults != "" {
+			var err error
+			if cfg, err = server.ReadFaultConfig(*faults); err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitUsage
+			}
+		}
+		inj, err := server.NewFaultInjector(cfg)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitUsage
+		}
+		log.Printf("fault injection enabled with %d rules", len(inj.Rules()))
+		opts = append(opts, server.WithFaults(inj))
+	}
+	if *gw {
+		opts = append(opts, server.WithGateway())
+	}
+	if *web {
+		opts = append(opts, server.WithWeb())
+	}
+	if *corsOrigins != "" {
+		var origins []string
+		for _, o := range strings.Split(*corsOrigins, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				origins = append(origins, o)
+			}
+		}
+		opts = append(opts, server.WithCORS(origins...))
+	}
+	if *rate > 0 {
+		opts = append(opts, server.WithRateLimit(ratelimit.Limits{
+			Default: ratelimit.Limit{Rate: *rate, Burst: max(*burst, int(*rate))},
+		}))
+	}
+	if *concurrencyLimit > 0 {
+		if *concurrencyMin <= 0 || *concurrencyMax < *concurrencyMin {
+			fmt.Fprintf(stderr, "serve: invalid concurrency range [%d, %d]\n", *concurrencyMin, *concurrencyMax)
+			return exitUsage
+		}
+		log.Printf("adaptive concurrency limit enabled, initial %d in [%d, %d]", *concurrencyLimit, *concurrencyMin, *concurrencyMax)
+		opts = append(opts, server.WithConcurrencyLimit(concurrency.Config{
+			Initial: *concurrencyLimit,
+			Min:     *concurrencyMin,
+			Max:     *concurrencyMax,
+		}))
+	}
+
+	// 收到 SIGINT/SIGTERM 后优雅退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// 指标监听失败时 gRPC 服务也一起退出
+	metricsErr := make(chan error, 1)
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		opts = append(opts, server.WithMetrics(reg))
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			metricsErr <- metrics.Serve(ctx, *metricsAddr, reg)
+			cancel()
+		}()
+	}
+	if err := server.Run(ctx, *addr, opts...); err != nil {
+		log.Println(err)
+		return exitError
+	}
+	select {
+	case err := <-metricsErr:
+		if err != nil {
+			log.Printf("metrics: %v", err)
+			return exitError
+		}
+	default:
+	}
+	return exitOK
+}
+
+// tlsFlags 注册 serve 和 call 共用的 TLS 参数
+func tlsFlags(fs *flag.FlagSet) *tlsutil.Config {
+	cfg := &tlsutil.Config{}
+	fs.StringVar(&cfg.CertFile, "tls-cert", envString("GRPC_DEMO_TLS_CERT", ""), "certificate file ($GRPC_DEMO_TLS_CERT)")
+	fs.StringVar(&cfg.KeyFile, "tls-key", envString("GRPC_DEMO_TLS_KEY", ""), "private key file ($GRPC_DEMO_TLS_KEY)")
+	fs.StringVar(&cfg.CAFile, "tls-ca", envString("GRPC_DEMO_TLS_CA", ""), "CA file used to verify the peer ($GRPC_DEMO_TLS_CA)")
+	return cfg
+}
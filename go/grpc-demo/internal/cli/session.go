@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+)
+
+// sessionCommands 输入中的命令名
+var sessionCommands = map[string]v1.SessionCommand_Op{
+	"add":      v1.SessionCommand_ADD,
+	"sub":      v1.SessionCommand_SUBTRACT,
+	"reset":    v1.SessionCommand_RESET,
+	"undo":     v1.SessionCommand_UNDO,
+	"snapshot": v1.SessionCommand_SNAPSHOT,
+}
+
+// session 在 ChatAdd 上打开或恢复一个累加器会话，输入的每一行是一条命令，
+// 文本输出为每条命令之后的 "值<TAB>版本<TAB>可撤销步数"，会话 ID 打印到标准错误
+func (c *caller) session(args []string) error {
+	fs := c.flags("session")
+	id := fs.String("id", "", "resume this session instead of starting a new one")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected arguments %v", fs.Args())}
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	s, err := c.client.ChatAdd(ctx)
+	if err != nil {
+		return err
+	}
+	call := func(cmd *v1.SessionCommand) (*v1.AddResponse, error) {
+		// Send 失败时真正的错误由 Recv 返回
+		_ = s.Send(&v1.AddRequest{Session: cmd})
+		return s.Recv()
+	}
+	r, err := call(&v1.SessionCommand{Op: v1.SessionCommand_OPEN, SessionId: *id})
+	if err != nil {
+		return err
+	}
+	if !c.p.json {
+		fmt.Fprintf(c.p.errOut, "session %s\n", r.GetSession().GetSessionId())
+	}
+	if err := c.p.result(r, sessionLine(r)); err != nil {
+		return err
+	}
+	err = lines(c.in, func(line string) error {
+		cmd, err := parseSessionCommand(line)
+		if err != nil {
+			return err
+		}
+		r, err := call(cmd)
+		if err != nil {
+			return err
+		}
+		return c.p.result(r, sessionLine(r))
+	})
+	if err != nil {
+		return err
+	}
+	return s.CloseSend()
+}
+
+// parseSessionCommand 解析 "add 5"、"reset"、"undo" 这样的一行
+func parseSessionCommand(line string) (*v1.SessionCommand, error) {
+	fields := strings.Fields(line)
+	op, ok := sessionCommands[fields[0]]
+	if !ok {
+		return nil, &usageError{fmt.Sprintf("unknown session command %q, want add, sub, reset, undo or snapshot", fields[0])}
+	}
+	cmd := &v1.SessionCommand{Op: op}
+	switch {
+	case len(fields) == 2 && (op == v1.SessionCommand_ADD || op == v1.SessionCommand_SUBTRACT || op == v1.SessionCommand_RESET):
+		v, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, &usageError{fmt.Sprintf("invalid value %q", fields[1])}
+		}
+		cmd.Value = v
+	case len(fields) == 1 && op != v1.SessionCommand_ADD && op != v1.SessionCommand_SUBTRACT:
+		// reset 不带值时清零
+	default:
+		return nil, &usageError{fmt.Sprintf("malformed session command %q", line)}
+	}
+	return cmd, nil
+}
+
+func sessionLine(r *v1.AddResponse) string {
+	return fmt.Sprintf("%d\t%d\t%d", r.GetResult(), r.GetSession().GetVersion(), r.GetSession().GetUndoDepth())
+}
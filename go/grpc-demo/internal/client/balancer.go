@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/health" // 注册客户端健康检查，服务端排空时不再向它分配请求
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// staticScheme 多个后端地址使用的 resolver scheme
+const staticScheme = "static"
+
+// RetryPolicy 服务配置中的重试策略，对计算器服务的所有方法生效；
+// grpc 只会在收到第一条响应之前重试，流式调用已经收到数据后不会重放
+type RetryPolicy struct {
+	MaxAttempts       int // 包括第一次调用，小于 2 时不重试
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	Codes             []codes.Code // 可以重试的状态码
+}
+
+// DefaultRetryPolicy 后端不可用时最多再试 3 次
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       4,
+		InitialBackoff:    50 * time.Millisecond,
+		MaxBackoff:        time.Second,
+		BackoffMultiplier: 2,
+		Codes:             []codes.Code{codes.Unavailable},
+	}
+}
+
+// splitTarget 将逗号分隔的地址列表拆开，去掉空项
+func splitTarget(addr string) []string {
+	var addrs []string
+	for _, a := range strings.Split(addr, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+// staticResolver 为多个后端创建静态 resolver，返回拨号使用的 target；只有一个地址时直接拨号
+func staticResolver(addr string) (string, []grpc.DialOption) {
+	addrs := splitTarget(addr)
+	if len(addrs) <= 1 {
+		return addr, nil
+	}
+	state := resolver.State{}
+	for _, a := range addrs {
+		state.Endpoints = append(state.Endpoints, resolver.Endpoint{Addresses: []resolver.Address{{Addr: a}}})
+	}
+	// 每个连接使用独立的 resolver，互不影响
+	r := manual.NewBuilderWithScheme(staticScheme)
+	r.InitialState(state)
+	return staticScheme + ":///" + strings.Join(addrs, ","), []grpc.DialOption{grpc.WithResolvers(r)}
+}
+
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	HealthCheckConfig   *healthCheckConfig    `json:"healthCheckConfig,omitempty"`
+}
+
+type methodConfig struct {
+	Name        []methodName     `json:"name"`
+	RetryPolicy *retryPolicyJSON `json:"retryPolicy"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+}
+
+type retryPolicyJSON struct {
+	MaxAttempts          int          `json:"maxAttempts"`
+	InitialBackoff       string       `json:"initialBackoff"`
+	MaxBackoff           string       `json:"maxBackoff"`
+	BackoffMultiplier    float64      `json:"backoffMultiplier"`
+	RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+}
+
+type healthCheckConfig struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// serviceConfigJSON 生成 round_robin 负载均衡、重试和健康检查的默认服务配置
+func serviceConfigJSON(p RetryPolicy) (string, error) {
+	sc := serviceConfig{
+		LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}},
+		// 服务端优雅退出时先把健康状态置为 NOT_SERVING，客户端据此摘掉该后端
+		HealthCheckConfig: &healthCheckConfig{ServiceName: v1.CalculatorService_ServiceDesc.ServiceName},
+	}
+	if p.MaxAttempts >= 2 {
+		mc := methodConfig{RetryPolicy: &retryPolicyJSON{
+			MaxAttempts:          p.MaxAttempts,
+			InitialBackoff:       seconds(p.InitialBackoff),
+			MaxBackoff:           seconds(p.MaxBackoff),
+			BackoffMultiplier:    p.BackoffMultiplier,
+			RetryableStatusCodes: p.Codes,
+		}}
+		for _, desc := range []grpc.ServiceDesc{v1.CalculatorService_ServiceDesc, v2.CalculatorService_ServiceDesc} {
+			mc.Name = append(mc.Name, methodName{Service: desc.ServiceName})
+		}
+		sc.MethodConfig = []methodConfig{mc}
+	}
+	b, err := json.Marshal(sc)
+	return string(b), err
+}
+
+// seconds 服务配置中的时长格式，如 "0.05s"
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// backend 进程内的计算器服务，记录收到的一元调用次数
+type backend struct {
+	addr  string
+	s     *grpc.Server
+	calls atomic.Int64
+}
+
+// startBackend delay 大于 0 时每个一元调用先等待 delay；fail 为 true 时一元调用总是返回 Unavailable
+func startBackend(t *testing.T, delay time.Duration, fail bool) *backend {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &backend{addr: lis.Addr().String()}
+	b.s = grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := req.(*healthpb.HealthCheckRequest); ok {
+			return handler(ctx, req)
+		}
+		b.calls.Add(1)
+		if fail {
+			return nil, status.Error(codes.Unavailable, "backend unavailable")
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return handler(ctx, req)
+	}))
+	v1.RegisterCalculatorServiceServer(b.s, &server.CalculatorSerer{})
+	hs := health.NewServer()
+	hs.SetServingStatus(v1.CalculatorService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(b.s, hs)
+	go b.s.Serve(lis)
+	t.Cleanup(b.s.Stop)
+	return b
+}
+
+func dialBackends(t *testing.T, backends []*backend, opts ...Option) v1.CalculatorServiceClient {
+	t.Helper()
+	addr := ""
+	for i, b := range backends {
+		if i > 0 {
+			addr += ","
+		}
+		addr += b.addr
+	}
+	conn, err := Dial(addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return v1.NewCalculatorServiceClient(conn)
+}
+
+func add(c v1.CalculatorServiceClient, a, b int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	r, err := c.Add(ctx, &v1.AddRequest{A: a, B: b})
+	if err == nil && r.Result != a+b {
+		return status.Errorf(codes.Internal, "add(%d, %d) = %d", a, b, r.Result)
+	}
+	return err
+}
+
+func TestRoundRobin(t *testing.T) {
+	backends := []*backend{startBackend(t, 0, false), startBackend(t, 0, false), startBackend(t, 0, false)}
+	c := dialBackends(t, backends)
+	// 连接是并行建立的，最早的几次调用可能只落在已就绪的后端上
+	for i := 0; i < 100; i++ {
+		if err := add(c, int64(i), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, b := range backends {
+		if n := b.calls.Load(); n < 10 {
+			t.Errorf("backend %d served %d calls, want round robin", i, n)
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	backends := []*backend{startBackend(t, 0, false), startBackend(t, 0, false), startBackend(t, 0, false)}
+	c := dialBackends(t, backends, WithHedging(HedgingPolicy{}))
+
+	// 多个 goroutine 持续调用，中途依次强制关闭两个后端，所有调用都应当成功
+	var (
+		done   atomic.Bool
+		total  atomic.Int64
+		failed atomic.Int64
+		wg     sync.WaitGroup
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int64(0); !done.Load(); i++ {
+				if err := add(c, i, 1); err != nil {
+					t.Errorf("call failed: %v", err)
+					failed.Add(1)
+				}
+				total.Add(1)
+			}
+		}()
+	}
+	waitCalls := func(n int64) {
+		for start := total.Load(); total.Load()-start < n; {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitCalls(200)
+	backends[0].s.Stop()
+	waitCalls(200)
+	backends[1].s.Stop()
+	waitCalls(200)
+	done.Store(true)
+	wg.Wait()
+
+	if failed.Load() > 0 {
+		t.Fatalf("%d of %d calls failed", failed.Load(), total.Load())
+	}
+	// 之后的一元调用和服务端流都落在剩下的后端上
+	last := backends[2].calls.Load()
+	if err := add(c, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if backends[2].calls.Load() != last+1 {
+		t.Fatal("call after failover did not reach the surviving backend")
+	}
+	ss, err := c.RangeAdd(context.Background(), &v1.RangeRequest{Start: 1, End: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := ss.Recv(); err != nil {
+			t.Fatalf("RangeAdd after failover: %v", err)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	bad, good := startBackend(t, 0, true), startBackend(t, 0, false)
+	c := dialBackends(t, []*backend{bad, good}, WithHedging(HedgingPolicy{}))
+	for i := 0; i < 20; i++ {
+		if err := add(c, int64(i), 1); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if bad.calls.Load() == 0 {
+		t.Fatal("unavailable backend never picked, retry not exercised")
+	}
+
+	// 关闭重试后，落到故障后端的调用直接失败
+	c = dialBackends(t, []*backend{bad, good}, WithHedging(HedgingPolicy{}), WithRetryPolicy(RetryPolicy{}))
+	failed := 0
+	for i := 0; i < 20; i++ {
+		if err := add(c, int64(i), 1); status.Code(err) == codes.Unavailable {
+			failed++
+		}
+	}
+	if failed == 0 {
+		t.Fatal("expected failures without retry policy")
+	}
+}
+
+func TestHedging(t *testing.T) {
+	slow, fast := startBackend(t, time.Second, false), startBackend(t, 0, false)
+	c := dialBackends(t, []*backend{slow, fast}, WithHedging(HedgingPolicy{
+		MaxAttempts:   3,
+		Delay:         20 * time.Millisecond,
+		NonFatalCodes: []codes.Code{codes.Unavailable},
+	}))
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		if err := add(c, int64(i), 1); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d > 500*time.Millisecond {
+			t.Fatalf("call %d took %s, hedge did not fire", i, d)
+		}
+	}
+	if slow.calls.Load() == 0 {
+		t.Fatal("slow backend never picked, hedging not exercised")
+	}
+}
@@ -4,15 +4,50 @@ import (
 	"context"
 	"io"
 	"log"
+	"math"
 	"time"
 
 	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-func Run(addr string) error {
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// Dial 创建到计算器服务的连接，addr 可以是逗号分隔的多个后端地址，按 round_robin 分配请求；
+// 连接带上请求 ID、日志、默认超时、限流重试、失败重试和对冲，配置了 WithMetrics、WithTracer 时还会记录指标和 span
+func Dial(addr string, opts ...Option) (*grpc.ClientConn, error) {
+	o := newOptions(opts)
+	sc, err := serviceConfigJSON(o.retry)
+	if err != nil {
+		return nil, err
+	}
+	target, resolverOpts := staticResolver(addr)
+	cfg := interceptor.Config{
+		Timeouts: interceptor.Timeouts{
+			Default:       o.timeout,
+			StreamDefault: o.timeout,
+			// 跟踪历史记录的流一直持续到调用方取消
+			PerMethod: map[string]time.Duration{v1.HistoryService_StreamHistory_FullMethodName: 0},
+		},
+		Tracer: o.tracer,
+	}
+	if o.metrics != nil {
+		if cfg.Metrics, err = metrics.NewClient(o.metrics); err != nil {
+			return nil, err
+		}
+	}
+	dialOpts := append(o.dialOptions(), resolverOpts...)
+	return grpc.NewClient(target, append(dialOpts,
+		grpc.WithDefaultServiceConfig(sc),
+		grpc.WithChainUnaryInterceptor(append(interceptor.UnaryClientChain(cfg), unaryRetryAfter(retryAfterAttempts), unaryHedging(o.hedging))...),
+		grpc.WithChainStreamInterceptor(append(interceptor.StreamClientChain(cfg), streamRetryAfter(retryAfterAttempts))...),
+	)...)
+}
+
+// Run 依次演示每一种调用方式
+func Run(addr string, opts ...Option) error {
+	conn, err := Dial(addr, opts...)
 	if err != nil {
 		return err
 	}
@@ -25,30 +60,85 @@ func Run(addr string) error {
 	// unray
 	resp, err := c1.Add(ctx, &v1.AddRequest{A: 3, B: 4})
 	if err != nil {
-		log.Println("Add error:", err)
+		logError("Add", err)
 	} else {
 		log.Println("Add result:", resp.Result)
 	}
 
+	// 溢出时服务端返回 OutOfRange 以及 BadRequest/ErrorInfo details
+	if _, err := c1.Add(ctx, &v1.AddRequest{A: math.MaxInt64, B: 1}); err != nil {
+		logError("Add overflow", err)
+	}
+
 	// client streaming
-	cs, _ := c1.SumStream(ctx)
-	cs.Send(&v1.AddRequest{A: 1, B: 2})
-	cs.Send(&v1.AddRequest{A: 3, B: 4})
-	sumResp, err := cs.CloseAndRecv()
-	log.Println("SumStream:", sumResp.GetResult(), err)
+	if cs, err := c1.SumStream(ctx); err != nil {
+		logError("SumStream create", err)
+	} else {
+		_ = cs.Send(&v1.AddRequest{A: 1, B: 2})
+		_ = cs.Send(&v1.AddRequest{A: 3, B: 4})
+		// Send 出错时真正的错误由 CloseAndRecv 返回
+		if sumResp, err := cs.CloseAndRecv(); err != nil {
+			logError("SumStream", err)
+		} else {
+			log.Println("SumStream:", sumResp.GetResult())
+		}
+	}
 
-	// server streaming
-	ss, _ := c1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 3})
-	for {
-		m, err := ss.Recv()
-		if err == io.EOF {
-			break
+	// client streaming: 统计
+	st, err := c1.StatsStream(ctx)
+	if err != nil {
+		logError("StatsStream create", err)
+	} else {
+		// Send 失败说明流已中断，错误由 CloseAndRecv 返回
+		for i := int64(1); i <= 100; i++ {
+			if err := st.Send(&v1.StatsRequest{Value: i}); err != nil {
+				break
+			}
 		}
-		if err != nil {
-			log.Println("RangeAdd error: ", err)
-			break
+		if r, err := st.CloseAndRecv(); err != nil {
+			logError("StatsStream", err)
+		} else {
+			log.Printf("StatsStream: count = %d, sum = %d, min = %d, max = %d, mean = %.2f, variance = %.2f",
+				r.Count, r.Sum, r.Min, r.Max, r.Mean, r.Variance)
+			for _, p := range r.Percentiles {
+				log.Printf("StatsStream: p%g = %.2f", p.Quantile*100, p.Value)
+			}
+		}
+	}
+
+	// server streaming: 收到两个元素后断开，再用 continuation token 续传
+	rangeReq := &v1.RangeRequest{Start: 1, End: 9, Step: 2, Rate: 20}
+	var token string
+	rctx, rcancel := context.WithCancel(ctx)
+	if ss, err := c1.RangeAdd(rctx, rangeReq); err != nil {
+		logError("RangeAdd", err)
+	} else {
+		for i := 0; i < 2; i++ {
+			m, err := ss.Recv()
+			if err != nil {
+				logError("RangeAdd", err)
+				break
+			}
+			token = m.ContinuationToken
+			log.Println("RangeAdd recv: ", m.Result)
+		}
+	}
+	rcancel()
+	rangeReq.ResumeToken = token
+	if ss, err := c1.RangeAdd(ctx, rangeReq); err != nil {
+		logError("RangeAdd resume", err)
+	} else {
+		for {
+			m, err := ss.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				logError("RangeAdd resume", err)
+				break
+			}
+			log.Println("RangeAdd resumed recv: ", m.Result)
 		}
-		log.Println("RangeAdd recv: ", m.Result)
 	}
 
 	// bidirectional
@@ -58,21 +148,100 @@ func Run(addr string) error {
 	if err != nil {
 		log.Println("ChatAdd create error:", err)
 	} else {
-		bs.Send(&v1.AddRequest{A: 10, B: 5})
-		bs.Send(&v1.AddRequest{A: 10, B: 6})
-		bs.CloseSend()
+		// Send 失败时真正的错误由 Recv 返回
+		_ = bs.Send(&v1.AddRequest{A: 10, B: 5})
+		_ = bs.Send(&v1.AddRequest{A: 10, B: 6})
+		_ = bs.CloseSend()
 		for {
 			m, err := bs.Recv()
 			if err == io.EOF {
 				break
 			}
 			if err != nil {
-				log.Println("ChatAdd error: ", err)
+				logError("ChatAdd", err)
 				break
 			}
 			log.Println("ChatAdd recv: ", m.Result)
 		}
 	}
 
+	// 其余运算：一元、批量与双向流
+	if r, err := c1.Subtract(ctx, &v1.OperandsRequest{A: 3, B: 4}); err != nil {
+		logError("Subtract", err)
+	} else {
+		log.Println("Subtract result:", r.Result)
+	}
+	if r, err := c1.Multiply(ctx, &v1.OperandsRequest{A: 6, B: 7}); err != nil {
+		logError("Multiply", err)
+	} else {
+		log.Println("Multiply result:", r.Result)
+	}
+	if r, err := c1.Divide(ctx, &v1.OperandsRequest{A: 17, B: 5}); err != nil {
+		logError("Divide", err)
+	} else {
+		log.Println("Divide result:", r.Quotient, "remainder", r.Remainder)
+	}
+	if _, err := c1.Divide(ctx, &v1.OperandsRequest{A: 17, B: 0}); err != nil {
+		logError("Divide by zero", err)
+	}
+	if r, err := c1.Modulo(ctx, &v1.OperandsRequest{A: -7, B: 3}); err != nil {
+		logError("Modulo", err)
+	} else {
+		log.Println("Modulo result:", r.Result)
+	}
+	if r, err := c1.Pow(ctx, &v1.OperandsRequest{A: 2, B: 10}); err != nil {
+		logError("Pow", err)
+	} else {
+		log.Println("Pow result:", r.Result)
+	}
+	batch := &v1.BatchRequest{Items: []*v1.OperandsRequest{{A: 2, B: 3}, {A: 4, B: 5}}}
+	if r, err := c1.MultiplyBatch(ctx, batch); err != nil {
+		logError("MultiplyBatch", err)
+	} else {
+		log.Println("MultiplyBatch results:", r.Results)
+	}
+	ps, err := c1.ChatPow(ctx)
+	if err != nil {
+		logError("ChatPow create", err)
+	} else {
+		_ = ps.Send(&v1.OperandsRequest{A: 3, B: 2})
+		_ = ps.Send(&v1.OperandsRequest{A: 3, B: -1})
+		_ = ps.CloseSend()
+		for {
+			m, err := ps.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				logError("ChatPow", err)
+				break
+			}
+			log.Println("ChatPow recv: ", m.Result)
+		}
+	}
+
+	// 表达式求值，语法错误时 details 中带出错位置
+	for _, e := range []string{"(x + 2) * max(3, y) ^ 2", "1 + * 2"} {
+		r, err := c1.Evaluate(ctx, &v1.EvaluateRequest{Expression: e, Variables: map[string]int64{"x": 1, "y": 4}})
+		if err != nil {
+			logError("Evaluate", err)
+			continue
+		}
+		log.Printf("Evaluate %q = %d", e, r.Result)
+	}
+
+	// calculator.v2：任意精度
+	c2 := v2.NewCalculatorServiceClient(conn)
+	if r, err := c2.Multiply(ctx, &v2.BinaryRequest{A: "9223372036854775807", B: "9223372036854775807"}); err != nil {
+		logError("v2 Multiply", err)
+	} else {
+		log.Println("v2 Multiply result:", r.Result)
+	}
+	if r, err := c2.Divide(ctx, &v2.BinaryRequest{A: "1", B: "3", Scale: 10}); err != nil {
+		logError("v2 Divide", err)
+	} else {
+		log.Println("v2 Divide result:", r.Result, "≈", r.Decimal, "exact:", r.Exact)
+	}
+
 	return nil
 }
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"log"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// logError 打印 gRPC 错误及其携带的 error details
+func logError(op string, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		log.Printf("%s error: %v", op, err)
+		return
+	}
+	log.Printf("%s error: code = %s, message = %s", op, st.Code(), st.Message())
+	for _, d := range st.Details() {
+		switch detail := d.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range detail.GetFieldViolations() {
+				log.Printf("  bad request: field = %s, %s", v.GetField(), v.GetDescription())
+			}
+		case *errdetails.ErrorInfo:
+			log.Printf("  error info: reason = %s, domain = %s, metadata = %v",
+				detail.GetReason(), detail.GetDomain(), detail.GetMetadata())
+		default:
+			log.Printf("  detail: %v", detail)
+		}
+	}
+}
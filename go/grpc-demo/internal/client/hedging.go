@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// HedgingPolicy 幂等一元调用的对冲策略：Delay 内没有响应就再发一次，round_robin 会把它分给下一个后端，
+// 采用最先成功的响应并取消其余调用。grpc-go 不支持服务配置中的 hedgingPolicy，因此用拦截器实现
+type HedgingPolicy struct {
+	MaxAttempts int // 包括第一次调用，小于 2 时不对冲
+	Delay       time.Duration
+	// NonFatalCodes 返回这些状态码时立即发出下一次调用，其余错误直接返回
+	NonFatalCodes []codes.Code
+}
+
+// DefaultHedgingPolicy 100ms 没有响应时对冲，最多同时发出 3 次
+func DefaultHedgingPolicy() HedgingPolicy {
+	return HedgingPolicy{
+		MaxAttempts:   3,
+		Delay:         100 * time.Millisecond,
+		NonFatalCodes: []codes.Code{codes.Unavailable},
+	}
+}
+
+// idempotentMethods 计算器服务的一元方法都只依赖请求本身，重复执行是安全的
+var idempotentMethods = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, desc := range []grpc.ServiceDesc{v1.CalculatorService_ServiceDesc, v2.CalculatorService_ServiceDesc} {
+		for _, md := range desc.Methods {
+			m["/"+desc.ServiceName+"/"+md.MethodName] = true
+		}
+	}
+	return m
+}()
+
+type hedgeResult struct {
+	reply proto.Message
+	err   error
+}
+
+// unaryHedging 对幂等一元调用做对冲
+func unaryHedging(p HedgingPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		out, ok := reply.(proto.Message)
+		if p.MaxAttempts < 2 || !ok || !idempotentMethods[method] || writesCallInfo(opts) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make(chan hedgeResult, p.MaxAttempts)
+		sent, pending := 0, 0
+		send := func() {
+			sent++
+			pending++
+			// 每次调用使用独立的响应消息，避免并发写入
+			r := proto.Clone(out)
+			go func() {
+				results <- hedgeResult{reply: r, err: invoker(ctx, method, req, r, cc, opts...)}
+			}()
+		}
+		send()
+		timer := time.NewTimer(p.Delay)
+		defer timer.Stop()
+		var lastErr error
+		for {
+			select {
+			case <-timer.C:
+				if sent < p.MaxAttempts {
+					send()
+					timer.Reset(p.Delay)
+				}
+			case res := <-results:
+				pending--
+				if res.err == nil {
+					proto.Reset(out)
+					proto.Merge(out, res.reply)
+					return nil
+				}
+				lastErr = res.err
+				if !slices.Contains(p.NonFatalCodes, status.Code(res.err)) {
+					return res.err
+				}
+				if sent < p.MaxAttempts {
+					send()
+					timer.Reset(p.Delay)
+				} else if pending == 0 {
+					return lastErr
+				}
+			}
+		}
+	}
+}
+
+// writesCallInfo 调用选项会把响应头、trailer 或对端地址写回调用方时不对冲，
+// 多次调用并发写同一个变量会产生数据竞争
+func writesCallInfo(opts []grpc.CallOption) bool {
+	for _, o := range opts {
+		switch o.(type) {
+		case grpc.HeaderCallOption, grpc.TrailerCallOption, grpc.PeerCallOption:
+			return true
+		}
+	}
+	return false
+}
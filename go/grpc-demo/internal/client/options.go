@@ -0,0 +1,107 @@
+package client
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Option Run 的可选配置
+type Option func(*options)
+
+type options struct {
+	tlsConfig *tls.Config
+	token     string
+	timeout   time.Duration
+	retry     RetryPolicy
+	hedging   HedgingPolicy
+	metrics   prometheus.Registerer
+	tracer    *tracing.Tracer
+}
+
+// WithTLS 使用 TLS 连接服务端；tls.Config 中提供客户端证书时即为 mTLS
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithToken 每次调用都附带 Bearer token
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTimeout 没有设置截止时间的调用使用的默认超时
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetryPolicy 替换默认的重试策略，MaxAttempts 小于 2 时关闭重试
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = p
+	}
+}
+
+// WithHedging 替换默认的对冲策略，MaxAttempts 小于 2 时关闭对冲
+func WithHedging(p HedgingPolicy) Option {
+	return func(o *options) {
+		o.hedging = p
+	}
+}
+
+// WithMetrics 把 grpc_client_* 指标注册到 reg
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(o *options) {
+		o.metrics = reg
+	}
+}
+
+// WithTracer 为每次调用创建 client span，并通过 traceparent 传给服务端
+func WithTracer(t *tracing.Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		timeout: 3 * time.Second,
+		retry:   DefaultRetryPolicy(),
+		hedging: DefaultHedgingPolicy(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// credentials 未配置 TLS 时退回明文
+func (o *options) credentials() credentials.TransportCredentials {
+	if o.tlsConfig == nil {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(o.tlsConfig)
+}
+
+// dialOptions 连接使用的传输层和调用级凭证；未开启 TLS 时允许明文发送 token，便于本地调试
+func (o *options) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(o.credentials())}
+	if o.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(auth.TokenCredentials{
+			Token:         o.token,
+			AllowInsecure: o.tlsConfig == nil,
+		}))
+	}
+	return opts
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryAfterAttempts 被限流后最多重试的次数
+const retryAfterAttempts = 3
+
+// unaryRetryAfter 调用返回 ResourceExhausted 且带有 RetryInfo 时，等待服务端建议的时间后重试
+func unaryRetryAfter(attempts int) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		for i := 0; ; i++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || i >= attempts || !waitRetryAfter(ctx, method, err) {
+				return err
+			}
+		}
+	}
+}
+
+// streamRetryAfter 只重试服务端流：请求只有一条，可以在收到第一条响应之前安全地重新建流；
+// 客户端流和双向流已经发出的消息无法重放，直接返回错误
+func streamRetryAfter(attempts int) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil || desc.ClientStreams || !desc.ServerStreams {
+			return cs, err
+		}
+		return &retryStream{
+			ClientStream: cs,
+			ctx:          ctx,
+			desc:         desc,
+			cc:           cc,
+			method:       method,
+			streamer:     streamer,
+			opts:         opts,
+			attempts:     attempts,
+		}, nil
+	}
+}
+
+type retryStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	cc       *grpc.ClientConn
+	method   string
+	streamer grpc.Streamer
+	opts     []grpc.CallOption
+
+	req      interface{}
+	received bool
+	attempts int
+}
+
+func (s *retryStream) SendMsg(m interface{}) error {
+	s.req = m
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *retryStream) RecvMsg(m interface{}) error {
+	for {
+		err := s.ClientStream.RecvMsg(m)
+		if err == nil {
+			s.received = true
+			return nil
+		}
+		if s.received || s.req == nil || s.attempts <= 0 || !waitRetryAfter(s.ctx, s.method, err) {
+			return err
+		}
+		s.attempts--
+		cs, serr := s.streamer(s.ctx, s.desc, s.cc, s.method, s.opts...)
+		if serr != nil {
+			return err
+		}
+		if serr := cs.SendMsg(s.req); serr != nil {
+			return err
+		}
+		if serr := cs.CloseSend(); serr != nil {
+			return err
+		}
+		s.ClientStream = cs
+	}
+}
+
+// waitRetryAfter 错误可按 RetryInfo 重试时等待相应时间并返回 true；
+// 等待时间超过调用截止时间时不再等待
+func waitRetryAfter(ctx context.Context, method string, err error) bool {
+	if status.Code(err) != codes.ResourceExhausted {
+		return false
+	}
+	delay, ok := interceptor.RetryDelay(err)
+	if !ok {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	log.Printf("[CLIENT RETRY] method = %s, rate limited, retry after %s", method, delay)
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
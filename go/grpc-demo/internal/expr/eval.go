@@ -0,0 +1,172 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+)
+
+// 求值错误的原因，同时用作 ErrorInfo 的 reason
+const (
+	ReasonUndefinedVariable = "UNDEFINED_VARIABLE"
+	ReasonUnknownFunction   = "UNKNOWN_FUNCTION"
+	ReasonArgumentCount     = "BAD_ARGUMENT_COUNT"
+	ReasonArithmetic        = "ARITHMETIC_ERROR"
+)
+
+// EvalError 求值错误，Pos 指向出错的运算符、变量或函数名；
+// 算术错误时 Err 为 calc 包返回的错误
+type EvalError struct {
+	Pos    int
+	Reason string
+	Msg    string
+	Err    error
+}
+
+func (e *EvalError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("evaluation error at position %d: %v", e.Pos, e.Err)
+	}
+	return fmt.Sprintf("evaluation error at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// function 内置函数，minArgs/maxArgs 为参数个数限制，maxArgs < 0 表示不限
+type function struct {
+	minArgs, maxArgs int
+	call             func(args []int64) (int64, error)
+}
+
+var functions = map[string]function{
+	"min": {1, -1, func(args []int64) (int64, error) {
+		m := args[0]
+		for _, v := range args[1:] {
+			m = min(m, v)
+		}
+		return m, nil
+	}},
+	"max": {1, -1, func(args []int64) (int64, error) {
+		m := args[0]
+		for _, v := range args[1:] {
+			m = max(m, v)
+		}
+		return m, nil
+	}},
+	"abs": {1, 1, func(args []int64) (int64, error) {
+		return abs("abs", args[0], 0)
+	}},
+	"gcd": {2, -1, func(args []int64) (int64, error) {
+		g := args[0]
+		for _, v := range args[1:] {
+			var err error
+			if g, err = gcd(g, v); err != nil {
+				return 0, err
+			}
+		}
+		return g, nil
+	}},
+}
+
+// Evaluate 解析并计算表达式，vars 为变量取值
+func Evaluate(s string, vars map[string]int64) (int64, error) {
+	n, err := Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	return Eval(n, vars)
+}
+
+// Eval 计算语法树，所有运算都做溢出检查；"/" 向零截断，"%" 与 Modulo 一样结果非负
+func Eval(n Node, vars map[string]int64) (int64, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, nil
+	case *varNode:
+		v, ok := vars[n.name]
+		if !ok {
+			return 0, &EvalError{Pos: n.pos, Reason: ReasonUndefinedVariable, Msg: fmt.Sprintf("undefined variable %q", n.name)}
+		}
+		return v, nil
+	case *unaryNode:
+		v, err := Eval(n.operand, vars)
+		if err != nil {
+			return 0, err
+		}
+		r, err := calc.Subtract(0, v)
+		return r, arithmetic(n.pos, err)
+	case *binaryNode:
+		a, err := Eval(n.left, vars)
+		if err != nil {
+			return 0, err
+		}
+		b, err := Eval(n.right, vars)
+		if err != nil {
+			return 0, err
+		}
+		var r int64
+		switch n.op {
+		case '+':
+			r, err = calc.Add(a, b)
+		case '-':
+			r, err = calc.Subtract(a, b)
+		case '*':
+			r, err = calc.Multiply(a, b)
+		case '/':
+			r, _, err = calc.Divide(a, b)
+		case '%':
+			r, err = calc.Modulo(a, b)
+		case '^':
+			r, err = calc.Pow(a, b)
+		}
+		return r, arithmetic(n.pos, err)
+	case *callNode:
+		fn, ok := functions[n.name]
+		if !ok {
+			return 0, &EvalError{Pos: n.pos, Reason: ReasonUnknownFunction, Msg: fmt.Sprintf("unknown function %q", n.name)}
+		}
+		if len(n.args) < fn.minArgs || (fn.maxArgs >= 0 && len(n.args) > fn.maxArgs) {
+			return 0, &EvalError{Pos: n.pos, Reason: ReasonArgumentCount,
+				Msg: fmt.Sprintf("%s() does not accept %d arguments", n.name, len(n.args))}
+		}
+		args := make([]int64, 0, len(n.args))
+		for _, a := range n.args {
+			v, err := Eval(a, vars)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, v)
+		}
+		r, err := fn.call(args)
+		return r, arithmetic(n.pos, err)
+	}
+	return 0, fmt.Errorf("unknown node %T", n)
+}
+
+func arithmetic(pos int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &EvalError{Pos: pos, Reason: ReasonArithmetic, Err: err}
+}
+
+func abs(op string, a, b int64) (int64, error) {
+	if a == math.MinInt64 {
+		return 0, &calc.OverflowError{Op: op, A: a, B: b}
+	}
+	if a < 0 {
+		return -a, nil
+	}
+	return a, nil
+}
+
+// gcd 结果非负，gcd(0, 0) = 0
+func gcd(a, b int64) (int64, error) {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return abs("gcd", a, b)
+}
@@ -0,0 +1,118 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	vars := map[string]int64{"x": 3, "y_2": -4}
+	cases := []struct {
+		expr string
+		want int64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-2 ^ 2", -4},
+		{"2 ^ 3 ^ 2", 512},
+		{"2 ^ -0", 1},
+		{"--x", 3},
+		{"x * y_2 + 1", -11},
+		{"7 / 2 - 7 % -3", 2},
+		{"-7 / 2", -3},
+		{"min(5, x, 9) + max(y_2, 0)", 3},
+		{"abs(y_2) + gcd(12, 18, -8)", 6},
+		{"gcd(0, 0)", 0},
+		{"  42  ", 42},
+	}
+	for _, c := range cases {
+		got, err := Evaluate(c.expr, vars)
+		if err != nil {
+			t.Errorf("Evaluate(%q) unexpected err %v", c.expr, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Evaluate(%q) = %d, expected %d", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	cases := []struct {
+		expr string
+		pos  int
+	}{
+		{"", 0},
+		{"1 +", 3},
+		{"(1 + 2", 6},
+		{"1 $ 2", 2},
+		{"max(1,", 6},
+		{"1 2", 2},
+		{"99999999999999999999", 0},
+		{strings.Repeat("(", MaxDepth+1) + "1", MaxDepth},
+	}
+	for _, c := range cases {
+		_, err := Evaluate(c.expr, nil)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Evaluate(%q) err = %v, expected syntax error", c.expr, err)
+			continue
+		}
+		if se.Pos != c.pos {
+			t.Errorf("Evaluate(%q) error position = %d, expected %d", c.expr, se.Pos, c.pos)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	cases := []struct {
+		expr   string
+		reason string
+		pos    int
+	}{
+		{"1 + z", ReasonUndefinedVariable, 4},
+		{"foo(1)", ReasonUnknownFunction, 0},
+		{"1 + abs(1, 2)", ReasonArgumentCount, 4},
+		{"1 / (2 - 2)", ReasonArithmetic, 2},
+		{"9223372036854775807 + 1", ReasonArithmetic, 20},
+		{"2 ^ -1", ReasonArithmetic, 2},
+	}
+	for _, c := range cases {
+		_, err := Evaluate(c.expr, nil)
+		var ee *EvalError
+		if !errors.As(err, &ee) {
+			t.Errorf("Evaluate(%q) err = %v, expected eval error", c.expr, err)
+			continue
+		}
+		if ee.Reason != c.reason || ee.Pos != c.pos {
+			t.Errorf("Evaluate(%q) = %s at %d, expected %s at %d", c.expr, ee.Reason, ee.Pos, c.reason, c.pos)
+		}
+	}
+}
+
+func FuzzEvaluate(f *testing.F) {
+	for _, s := range []string{"1 + 2 * 3", "-(x ^ 2) % 7", "max(1, min(2, abs(-3)), gcd(4, 6))", "((((", "1 /", "2^63"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		_, err := Evaluate(s, map[string]int64{"x": 5})
+		if err == nil {
+			return
+		}
+		var se *SyntaxError
+		var ee *EvalError
+		switch {
+		case errors.As(err, &se):
+			if se.Pos < 0 || se.Pos > len(s) {
+				t.Fatalf("syntax error position %d out of range for %q", se.Pos, s)
+			}
+		case errors.As(err, &ee):
+			if ee.Pos < 0 || ee.Pos >= len(s) {
+				t.Fatalf("eval error position %d out of range for %q", ee.Pos, s)
+			}
+		default:
+			t.Fatalf("unexpected error type %T: %v", err, err)
+		}
+	})
+}
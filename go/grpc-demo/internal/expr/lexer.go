@@ -0,0 +1,95 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp // + - * / % ^
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  int64
+	pos  int // 在表达式中的字节偏移，从 0 开始
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// SyntaxError 表达式语法错误，Pos 为出错位置的字节偏移（从 0 开始）
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// tokenize 将表达式切分为 token，末尾总是 tokEOF
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+			n, err := strconv.ParseInt(s[start:i], 10, 64)
+			if err != nil {
+				return nil, &SyntaxError{Pos: start, Msg: fmt.Sprintf("number %s out of int64 range", s[start:i])}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: s[start:i], num: n, pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(s) && (isIdentStart(s[i]) || isDigit(s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: s[start:i], pos: start})
+		default:
+			var kind tokenKind
+			switch c {
+			case '+', '-', '*', '/', '%', '^':
+				kind = tokOp
+			case '(':
+				kind = tokLParen
+			case ')':
+				kind = tokRParen
+			case ',':
+				kind = tokComma
+			default:
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", rune(c))}
+			}
+			tokens = append(tokens, token{kind: kind, text: s[i : i+1], pos: i})
+			i++
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,244 @@
+package expr
+
+import "fmt"
+
+// 限制输入规模，防止恶意表达式耗尽内存或栈
+const (
+	MaxLength = 4096
+	MaxDepth  = 128
+)
+
+// Node 语法树节点
+type Node interface {
+	Pos() int
+}
+
+type numberNode struct {
+	pos   int
+	value int64
+}
+
+type varNode struct {
+	pos  int
+	name string
+}
+
+type unaryNode struct {
+	pos     int
+	operand Node
+}
+
+type binaryNode struct {
+	pos         int
+	op          byte
+	left, right Node
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []Node
+}
+
+func (n *numberNode) Pos() int { return n.pos }
+func (n *varNode) Pos() int    { return n.pos }
+func (n *unaryNode) Pos() int  { return n.pos }
+func (n *binaryNode) Pos() int { return n.pos }
+func (n *callNode) Pos() int   { return n.pos }
+
+// Parse 解析中缀表达式，语法：
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/" | "%") unary }
+//	unary   = ("-" | "+") unary | power
+//	power   = primary [ "^" unary ]            // 右结合，-2^2 = -(2^2)
+//	primary = number | ident | ident "(" [ expr { "," expr } ] ")" | "(" expr ")"
+func Parse(s string) (Node, error) {
+	if len(s) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("expression longer than %d bytes", MaxLength)}
+	}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+	}
+	return n, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops string) bool {
+	t := p.peek()
+	if t.kind != tokOp {
+		return false
+	}
+	for i := 0; i < len(ops); i++ {
+		if t.text[0] == ops[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// enter 记录递归深度；括号、一元运算和幂运算的递归都经过 unary，只在那里计数
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("expression nested deeper than %d", MaxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) expr() (Node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/%") {
+		op := p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if p.isOp("+-") {
+		op := p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op.text == "+" {
+			return operand, nil
+		}
+		return &unaryNode{pos: op.pos, operand: operand}, nil
+	}
+	return p.power()
+}
+
+func (p *parser) power() (Node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("^") {
+		return base, nil
+	}
+	op := p.next()
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{pos: op.pos, op: '^', left: base, right: exp}, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &numberNode{pos: t.pos, value: t.num}, nil
+	case tokIdent:
+		if p.peek().kind != tokLParen {
+			return &varNode{pos: t.pos, name: t.text}, nil
+		}
+		p.next()
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		return &callNode{pos: t.pos, name: t.text, args: args}, nil
+	case tokLParen:
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s, expected number, variable or '('", t)}
+}
+
+// args 解析函数参数列表，左括号已被消费
+func (p *parser) args() ([]Node, error) {
+	var args []Node
+	if p.peek().kind == tokRParen {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek().kind == tokComma {
+			p.next()
+			continue
+		}
+		if err := p.expect(tokRParen, "',' or ')'"); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}
+}
+
+func (p *parser) expect(kind tokenKind, want string) error {
+	if t := p.peek(); t.kind != kind {
+		return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s, expected %s", t, want)}
+	}
+	p.next()
+	return nil
+}
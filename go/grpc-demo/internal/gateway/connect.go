@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// connectError Connect 协议的错误，一元调用作为响应体，流式调用放在最后一帧中
+type connectError struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message,omitempty"`
+	Details []connectDetail `json:"details,omitempty"`
+}
+
+// connectDetail 错误详情，value 为 proto 编码后不带填充的 base64
+type connectDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// connectEndStream Connect 流的最后一帧
+type connectEndStream struct {
+	Error    *connectError       `json:"error,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+// serveConnectUnary 处理 Connect 一元调用：请求体和响应体就是消息本身，错误按状态码返回对应的 HTTP 状态码，
+// trailer 以 Trailer- 前缀写入响应头
+func (h *webHandler) serveConnectUnary(w http.ResponseWriter, r *http.Request, useJSON bool) {
+	var header metadata.MD
+	var resp []byte
+	trailer, err := h.callConnect(w, r, useJSON, false, func(md metadata.MD) {
+		header = md
+	}, func(msg []byte) error {
+		resp = msg
+		return nil
+	})
+	setHeaderMetadata(w.Header(), "", header)
+	setHeaderMetadata(w.Header(), "Trailer-", trailer)
+	if err != nil {
+		writeConnectError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// serveConnectStream 处理 Connect 流式调用：请求和响应都是帧，HTTP 状态码总是 200，
+// 状态和 trailer 以 JSON 放在标志位为 0x02 的最后一帧中
+func (h *webHandler) serveConnectStream(w http.ResponseWriter, r *http.Request, useJSON bool) {
+	rc := http.NewResponseController(w)
+	headerWritten := false
+	writeHeader := func(md metadata.MD) {
+		headerWritten = true
+		setHeaderMetadata(w.Header(), "", md)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	}
+	trailer, err := h.callConnect(w, r, useJSON, true, writeHeader, func(msg []byte) error {
+		return writeFrame(w, rc, 0, msg)
+	})
+	if !headerWritten {
+		writeHeader(nil)
+	}
+	end := connectEndStream{}
+	if err != nil {
+		end.Error = newConnectError(err)
+	}
+	if len(trailer) > 0 {
+		end.Metadata = http.Header{}
+		setHeaderMetadata(end.Metadata, "", trailer)
+	}
+	b, _ := json.Marshal(end)
+	writeFrame(w, rc, flagEndStream, b)
+}
+
+// callConnect 一元和流式调用共用的部分，stream 表示请求使用流式协议
+func (h *webHandler) callConnect(w http.ResponseWriter, r *http.Request, useJSON, stream bool, header func(metadata.MD), send func([]byte) error) (metadata.MD, error) {
+	m := lookupMethod(r.URL.Path)
+	if m == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", r.URL.Path)
+	}
+	if !stream && (m.IsStreamingClient() || m.IsStreamingServer()) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is a streaming method, use application/connect+proto or application/connect+json", m.Name())
+	}
+	encoding := r.Header.Get("Content-Encoding")
+	if stream {
+		encoding = r.Header.Get("Connect-Content-Encoding")
+	}
+	if encoding != "" && encoding != "identity" {
+		return nil, status.Errorf(codes.Unimplemented, "compression %q is not supported", encoding)
+	}
+	var timeout time.Duration
+	if v := r.Header.Get("Connect-Timeout-Ms"); v != "" {
+		ms, err := strconv.ParseUint(v, 10, 63)
+		if err != nil || len(v) > 10 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid Connect-Timeout-Ms %q", v)
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	ctx, cancel, err := webContext(r, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var next func() ([]byte, error)
+	if stream {
+		next = func() ([]byte, error) {
+			flags, msg, err := readFrame(r.Body)
+			if err == nil && flags != 0 {
+				err = status.Errorf(codes.InvalidArgument, "unexpected frame flags %#x", flags)
+			}
+			return msg, err
+		}
+	} else {
+		done := false
+		next = func() ([]byte, error) {
+			if done {
+				return nil, io.EOF
+			}
+			done = true
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "read body: %v", err)
+			}
+			if useJSON && len(bytes.TrimSpace(body)) == 0 {
+				body = []byte("{}")
+			}
+			return body, nil
+		}
+	}
+	if useJSON {
+		decode, encode := next, send
+		next = func() ([]byte, error) {
+			msg, err := decode()
+			if err != nil {
+				return nil, err
+			}
+			return jsonToProto(m.Input(), msg)
+		}
+		send = func(msg []byte) error {
+			b, err := protoToJSON(m.Output(), msg)
+			if err != nil {
+				return err
+			}
+			return encode(b)
+		}
+	}
+	return h.forward(ctx, w, r, m, webStream{next: next, header: header, send: send})
+}
+
+// writeConnectError 一元调用的错误响应，HTTP 状态码和网关相同，见 HTTPStatus
+func writeConnectError(w http.ResponseWriter, err error) {
+	b, _ := json.Marshal(newConnectError(err))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatus(status.Code(err)))
+	w.Write(b)
+}
+
+func newConnectError(err error) *connectError {
+	st := status.Convert(err)
+	ce := &connectError{Code: connectCode(st.Code()), Message: st.Message()}
+	for _, d := range st.Proto().GetDetails() {
+		ce.Details = append(ce.Details, connectDetail{
+			Type:  d.GetTypeUrl()[strings.LastIndexByte(d.GetTypeUrl(), '/')+1:],
+			Value: base64.RawStdEncoding.EncodeToString(d.GetValue()),
+		})
+	}
+	return ce
+}
+
+// connectCode Connect 用小写加下划线的状态码名，如 InvalidArgument 为 invalid_argument
+func connectCode(c codes.Code) string {
+	if c > codes.Unauthenticated {
+		return "unknown"
+	}
+	var b strings.Builder
+	for i, r := range c.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+)
+
+// exposedHeaders 允许浏览器中的脚本读取的响应头：gRPC-Web 的状态，以及服务端常用的 header metadata
+var exposedHeaders = strings.Join([]string{
+	"Grpc-Status",
+	"Grpc-Message",
+	"Grpc-Status-Details-Bin",
+	"Retry-After",
+	"WWW-Authenticate",
+	interceptor.RequestIDKey,
+	interceptor.IdempotentReplayHeader,
+	"Grpc-Metadata-" + interceptor.RequestIDKey,
+	"Grpc-Metadata-" + interceptor.IdempotentReplayHeader,
+}, ", ")
+
+// CORS 允许 origins 中的页面跨域访问 h，"*" 表示任意来源。预检请求在这里直接应答，
+// 允许的请求头即浏览器预检时申请的请求头；不允许的来源的预检请求返回 403
+func CORS(origins []string, h http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	for _, o := range origins {
+		allowed[o] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		w.Header().Add("Vary", "Origin")
+		if origin == "" || !(allowed["*"] || allowed[origin]) {
+			if preflight {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		}
+		w.Header().Set("Access-Control-Max-Age", "7200")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// forwardedHeaders 原样转发给 gRPC 服务的 HTTP 头
+var forwardedHeaders = []string{
+	"authorization",
+	interceptor.RequestIDKey,
+	interceptor.IdempotencyKeyHeader,
+	tracing.TraceparentHeader,
+	tracing.TracestateHeader,
+}
+
+// New 返回把 HTTP/JSON 请求转换成 CalculatorService、HistoryService 调用的 handler。
+//
+// 一元方法可以用 POST 发送 JSON 请求体，也可以用 GET 加查询参数，如 GET /v1/add?a=1&b=2；
+// 客户端流方法的请求体为换行分隔的 JSON（NDJSON）或 JSON 数组；服务端流方法按 Accept
+// 以 NDJSON（默认）或 SSE（text/event-stream）逐条返回。错误返回 google.rpc.Status 的 JSON，
+// HTTP 状态码由 gRPC 状态码映射，见 HTTPStatus。双向流方法需要 gRPC，不在网关中提供
+func New(conn grpc.ClientConnInterface) http.Handler {
+	calc := v1.NewCalculatorServiceClient(conn)
+	hist := v1.NewHistoryServiceClient(conn)
+	mux := http.NewServeMux()
+
+	handleUnary(mux, "/v1/add", calc.Add)
+	handleUnary(mux, "/v1/subtract", calc.Subtract)
+	handleUnary(mux, "/v1/multiply", calc.Multiply)
+	handleUnary(mux, "/v1/divide", calc.Divide)
+	handleUnary(mux, "/v1/modulo", calc.Modulo)
+	handleUnary(mux, "/v1/pow", calc.Pow)
+	handleUnary(mux, "/v1/subtract/batch", calc.SubtractBatch)
+	handleUnary(mux, "/v1/multiply/batch", calc.MultiplyBatch)
+	handleUnary(mux, "/v1/divide/batch", calc.DivideBatch)
+	handleUnary(mux, "/v1/modulo/batch", calc.ModuloBatch)
+	handleUnary(mux, "/v1/pow/batch", calc.PowBatch)
+	handleUnary(mux, "/v1/evaluate", calc.Evaluate)
+	handleUnary(mux, "/v1/history", hist.ListHistory)
+
+	mux.HandleFunc("POST /v1/sum", clientStream(calc.SumStream))
+	mux.HandleFunc("POST /v1/stats", clientStream(calc.StatsStream))
+
+	mux.HandleFunc("GET /v1/range", serverStream(calc.RangeAdd))
+	mux.HandleFunc("GET /v1/history/stream", serverStream(hist.StreamHistory))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status.Errorf(codes.NotFound, "no route for %s %s", r.Method, r.URL.Path))
+	})
+	return mux
+}
+
+// IsGRPC 请求是否为 gRPC（HTTP/2 且 Content-Type 为 application/grpc 或 application/grpc+编码），
+// 共用监听地址时据此分流；gRPC-Web 不算在内，见 IsWeb
+func IsGRPC(r *http.Request) bool {
+	if r.ProtoMajor != 2 {
+		return false
+	}
+	ct := r.Header.Get("Content-Type")
+	return ct == "application/grpc" || strings.HasPrefix(ct, "application/grpc+") || strings.HasPrefix(ct, "application/grpc;")
+}
+
+// outgoingContext 把需要转发的 HTTP 头和客户端地址放入 outgoing metadata；
+// 服务端只信任进程内连接带来的客户端地址，见 interceptor.CallerKey
+func outgoingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for _, h := range forwardedHeaders {
+		if v := r.Header.Values(h); len(v) > 0 {
+			md.Set(h, v...)
+		}
+	}
+	md.Set(interceptor.ForwardedForHeader, clientHost(r))
+	return metadata.NewOutgoingContext(r.Context(), md)
+}
+
+// clientHost HTTP 客户端的 IP
+func clientHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeHeaderMetadata 把 gRPC 响应的 header 以 Grpc-Metadata-<key> 写入 HTTP 响应头
+func writeHeaderMetadata(w http.ResponseWriter, md metadata.MD) {
+	for k, vs := range md {
+		if k == "content-type" {
+			continue
+		}
+		for _, v := range vs {
+			w.Header().Add("Grpc-Metadata-"+k, v)
+		}
+	}
+}
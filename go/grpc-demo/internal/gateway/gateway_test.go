@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	for code, want := range map[codes.Code]int{
+		codes.OK:                http.StatusOK,
+		codes.Canceled:          499,
+		codes.InvalidArgument:   http.StatusBadRequest,
+		codes.NotFound:          http.StatusNotFound,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.Unavailable:       http.StatusServiceUnavailable,
+		codes.Code(99):          http.StatusInternalServerError,
+	} {
+		if got := HTTPStatus(code); got != want {
+			t.Errorf("HTTPStatus(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestDecodeQuery(t *testing.T) {
+	q, _ := url.ParseQuery("filter.method=Add&filter.codes=0&filter.codes=3&filter.since=2024-01-02T03:04:05Z&page_size=10")
+	var got v1.ListHistoryRequest
+	if err := decodeQuery(q, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Filter.GetMethod() != "Add" || len(got.Filter.GetCodes()) != 2 || got.Filter.GetCodes()[1] != 3 || got.PageSize != 10 {
+		t.Fatalf("decoded %v", &got)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !got.Filter.Since.AsTime().Equal(want) {
+		t.Fatalf("since = %s", got.Filter.Since.AsTime())
+	}
+
+	for _, tc := range []struct {
+		query string
+		m     proto.Message
+	}{
+		{"c=1", &v1.AddRequest{}},                // 未知字段
+		{"a=x", &v1.AddRequest{}},                // 不是数字
+		{"session=1", &v1.AddRequest{}},          // 消息字段需要写成 session.<field>
+		{"a.b=1", &v1.AddRequest{}},              // a 不是消息
+		{"variables.x=1", &v1.EvaluateRequest{}}, // map 只能放在请求体里
+	} {
+		q, _ := url.ParseQuery(tc.query)
+		if err := decodeQuery(q, tc.m); err == nil {
+			t.Errorf("%s: no error, got %v", tc.query, tc.m)
+		}
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	var got []int64
+	body := "{\"value\":1}\n{\"value\":2}\n[{\"value\":3},{\"value\":4}]\n"
+	err := decodeStream(strings.NewReader(body), func(raw json.RawMessage) error {
+		var m v1.StatsRequest
+		if err := unmarshaler.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		got = append(got, m.Value)
+		return nil
+	})
+	if err != nil || len(got) != 4 || got[3] != 4 {
+		t.Fatalf("got %v, err = %v", got, err)
+	}
+	if err := decodeStream(strings.NewReader("{\"value\":1} {"), func(json.RawMessage) error { return nil }); err == nil {
+		t.Fatal("truncated body decoded without error")
+	}
+	big := `{"value":"` + strings.Repeat("9", maxBodyBytes) + `"}`
+	for _, body := range []string{"[{\"value\":1}," + big + "]", "{\"value\":1}\n" + big + "\n"} {
+		n := 0
+		err := decodeStream(strings.NewReader(body), func(json.RawMessage) error { n++; return nil })
+		if status.Code(err) != codes.InvalidArgument || n != 1 {
+			t.Fatalf("oversized message: %d decoded, err = %v", n, err)
+		}
+	}
+}
+
+func TestConnectCode(t *testing.T) {
+	for code, want := range map[codes.Code]string{
+		codes.Canceled:          "canceled",
+		codes.InvalidArgument:   "invalid_argument",
+		codes.DeadlineExceeded:  "deadline_exceeded",
+		codes.ResourceExhausted: "resource_exhausted",
+		codes.Code(99):          "unknown",
+	} {
+		if got := connectCode(code); got != want {
+			t.Errorf("connectCode(%s) = %s, want %s", code, got, want)
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for v, want := range map[string]time.Duration{"1S": time.Second, "250m": 250 * time.Millisecond, "2H": 2 * time.Hour} {
+		if d, err := parseTimeout(v); err != nil || d != want {
+			t.Errorf("parseTimeout(%s) = %s, %v", v, d, err)
+		}
+	}
+	for _, v := range []string{"", "S", "1x", "123456789S", "-1S"} {
+		if _, err := parseTimeout(v); err == nil {
+			t.Errorf("parseTimeout(%q) succeeded", v)
+		}
+	}
+}
+
+func TestTextReader(t *testing.T) {
+	// 两帧分别编码，中间带填充
+	body := base64.StdEncoding.EncodeToString([]byte("ab")) + base64.StdEncoding.EncodeToString([]byte("cde"))
+	b, err := io.ReadAll(&textReader{r: bufio.NewReader(strings.NewReader(body))})
+	if err != nil || string(b) != "abcde" {
+		t.Fatalf("decoded %q, err = %v", b, err)
+	}
+	if _, err := io.ReadAll(&textReader{r: bufio.NewReader(strings.NewReader("YWJ"))}); err == nil {
+		t.Fatal("truncated body decoded without error")
+	}
+	big := `{"value":"` + strings.Repeat("9", maxBodyBytes) + `"}`
+	for _, body := range []string{"[{\"value\":1}," + big + "]", "{\"value\":1}\n" + big + "\n"} {
+		n := 0
+		err := decodeStream(strings.NewReader(body), func(json.RawMessage) error { n++; return nil })
+		if status.Code(err) != codes.InvalidArgument || n != 1 {
+			t.Fatalf("oversized message: %d decoded, err = %v", n, err)
+		}
+	}
+}
+
+func TestGRPCWebTrailer(t *testing.T) {
+	err := status.Error(codes.InvalidArgument, "bad\r\n100%")
+	got := string(grpcWebTrailer(err, metadata.Pairs("x-a", "1", "x-b-bin", "\x00\x01")))
+	for _, want := range []string{"grpc-status: 3\r\n", "grpc-message: bad%0D%0A100%25\r\n", "x-a: 1\r\n", "x-b-bin: AAE=\r\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trailer %q does not contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// serveGRPCWeb 处理 gRPC-Web 调用：请求和响应都是 gRPC 帧，状态和 trailer 放在最后一个标志位为 0x80 的帧中，
+// HTTP 状态码总是 200。text 为 true 时请求体和响应体按 base64 编码
+func (h *webHandler) serveGRPCWeb(w http.ResponseWriter, r *http.Request, text bool) {
+	ctype := "application/grpc-web+proto"
+	var body io.Reader = r.Body
+	var out io.Writer = w
+	if text {
+		ctype = "application/grpc-web-text+proto"
+		body = &textReader{r: bufio.NewReader(r.Body)}
+		out = textWriter{w}
+	}
+	rc := http.NewResponseController(w)
+	headerWritten := false
+	writeHeader := func(md metadata.MD) {
+		headerWritten = true
+		setHeaderMetadata(w.Header(), "", md)
+		w.Header().Set("Content-Type", ctype)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	trailer, err := h.callGRPCWeb(w, r, body, writeHeader, func(msg []byte) error {
+		return writeFrame(out, rc, 0, msg)
+	})
+	if !headerWritten {
+		writeHeader(nil)
+	}
+	writeFrame(out, rc, flagTrailer, grpcWebTrailer(err, trailer))
+}
+
+func (h *webHandler) callGRPCWeb(w http.ResponseWriter, r *http.Request, body io.Reader, header func(metadata.MD), send func([]byte) error) (metadata.MD, error) {
+	m := lookupMethod(r.URL.Path)
+	if m == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", r.URL.Path)
+	}
+	var timeout time.Duration
+	if v := r.Header.Get("Grpc-Timeout"); v != "" {
+		d, err := parseTimeout(v)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		timeout = d
+	}
+	ctx, cancel, err := webContext(r, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return h.forward(ctx, w, r, m, webStream{
+		next: func() ([]byte, error) {
+			flags, msg, err := readFrame(body)
+			if err == nil && flags != 0 {
+				err = status.Errorf(codes.InvalidArgument, "unexpected frame flags %#x", flags)
+			}
+			return msg, err
+		},
+		header: header,
+		send:   send,
+	})
+}
+
+// grpcWebTrailer 按 HTTP/1.1 头的格式编码状态和 trailer，键名小写
+func grpcWebTrailer(err error, trailer metadata.MD) []byte {
+	st := status.Convert(err)
+	var b strings.Builder
+	fmt.Fprintf(&b, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&b, "grpc-message: %s\r\n", encodeGRPCMessage(st.Message()))
+	}
+	if len(st.Details()) > 0 {
+		if details, err := proto.Marshal(st.Proto()); err == nil {
+			fmt.Fprintf(&b, "grpc-status-details-bin: %s\r\n", base64.StdEncoding.EncodeToString(details))
+		}
+	}
+	h := http.Header{}
+	setHeaderMetadata(h, "", trailer)
+	for k, vs := range h {
+		for _, v := range vs {
+			fmt.Fprintf(&b, "%s: %s\r\n", strings.ToLower(k), v)
+		}
+	}
+	return []byte(b.String())
+}
+
+// encodeGRPCMessage 按 gRPC 协议对 grpc-message 做百分号编码，只保留可打印的 ASCII 字符
+func encodeGRPCMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxBodyBytes 一元调用请求体的上限；流式请求体不限总大小，但其中每条消息都不能超过它
+const maxBodyBytes = 1 << 20
+
+var (
+	// marshaler 输出标量的零值，curl 用户看到的是 {"result":"0"} 而不是 {}
+	marshaler = protojson.MarshalOptions{EmitDefaultValues: true}
+	// unmarshaler 拒绝未知字段，拼错的字段名不会被悄悄忽略
+	unmarshaler = protojson.UnmarshalOptions{}
+)
+
+// message 生成代码中的请求、响应消息，Req 为消息类型本身，PReq 为它的指针
+type message[T any] interface {
+	*T
+	proto.Message
+}
+
+// handleUnary 注册一元方法，GET 从查询参数读取请求，POST 还会读取 JSON 请求体
+func handleUnary[Req any, PReq message[Req], Resp proto.Message](mux *http.ServeMux, path string, call func(context.Context, PReq, ...grpc.CallOption) (Resp, error)) {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		req := PReq(new(Req))
+		if err := decodeRequest(w, r, req); err != nil {
+			writeError(w, err)
+			return
+		}
+		var header metadata.MD
+		resp, err := call(outgoingContext(r), req, grpc.Header(&header))
+		writeHeaderMetadata(w, header)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeMessage(w, http.StatusOK, resp)
+	}
+	mux.HandleFunc("GET "+path, h)
+	mux.HandleFunc("POST "+path, h)
+}
+
+// clientStream 把请求体中的每个 JSON 值作为一条消息发送，发完后返回唯一的响应
+func clientStream[Req, Resp any, PReq message[Req], PResp message[Resp]](open func(context.Context, ...grpc.CallOption) (grpc.ClientStreamingClient[Req, Resp], error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(outgoingContext(r))
+		defer cancel()
+		stream, err := open(ctx)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		err = decodeStream(r.Body, func(raw json.RawMessage) error {
+			req := PReq(new(Req))
+			if err := unmarshaler.Unmarshal(raw, req); err != nil {
+				return status.Errorf(codes.InvalidArgument, "decode message: %v", err)
+			}
+			return stream.Send(req)
+		})
+		// Send 返回 io.EOF 表示服务端已经结束调用，真正的状态由 CloseAndRecv 返回
+		if err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, err)
+			return
+		}
+		resp, err := stream.CloseAndRecv()
+		if header, herr := stream.Header(); herr == nil {
+			writeHeaderMetadata(w, header)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeMessage(w, http.StatusOK, PResp(resp))
+	}
+}
+
+// serverStream 按 Accept 以 NDJSON 或 SSE 逐条返回响应。第一条消息到达之前出错时返回对应的 HTTP 状态码，
+// 之后出错只能在流中写一条错误记录
+func serverStream[Req, Resp any, PReq message[Req], PResp message[Resp]](call func(context.Context, PReq, ...grpc.CallOption) (grpc.ServerStreamingClient[Resp], error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := PReq(new(Req))
+		if err := decodeRequest(w, r, req); err != nil {
+			writeError(w, err)
+			return
+		}
+		stream, err := call(outgoingContext(r), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp, err := stream.Recv()
+		if header, herr := stream.Header(); herr == nil {
+			writeHeaderMetadata(w, header)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, err)
+			return
+		}
+
+		sw := newStreamWriter(w, r)
+		for err == nil {
+			if werr := sw.message(PResp(resp)); werr != nil {
+				// 客户端已经断开，r.Context() 随之取消，调用也会结束
+				return
+			}
+			resp, err = stream.Recv()
+		}
+		if !errors.Is(err, io.EOF) {
+			sw.error(err)
+		}
+	}
+}
+
+// streamWriter 服务端流的输出格式
+type streamWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	sse bool
+}
+
+func newStreamWriter(w http.ResponseWriter, r *http.Request) *streamWriter {
+	sw := &streamWriter{w: w, rc: http.NewResponseController(w), sse: strings.Contains(r.Header.Get("Accept"), "text/event-stream")}
+	if sw.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+	return sw
+}
+
+// message NDJSON 每行为 {"result": 消息}，SSE 的 data 为消息本身
+func (sw *streamWriter) message(m proto.Message) error {
+	b, err := marshaler.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if sw.sse {
+		_, err = fmt.Fprintf(sw.w, "data: %s\n\n", b)
+	} else {
+		_, err = fmt.Fprintf(sw.w, "{\"result\":%s}\n", b)
+	}
+	if err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
+
+// error NDJSON 写一行 {"error": Status}，SSE 写一个 error 事件
+func (sw *streamWriter) error(err error) {
+	b, _ := marshaler.Marshal(status.Convert(err).Proto())
+	if sw.sse {
+		fmt.Fprintf(sw.w, "event: error\ndata: %s\n\n", b)
+	} else {
+		fmt.Fprintf(sw.w, "{\"error\":%s}\n", b)
+	}
+	sw.rc.Flush()
+}
+
+// decodeRequest 读取 POST 的 JSON 请求体，再用查询参数覆盖
+func decodeRequest(w http.ResponseWriter, r *http.Request, m proto.Message) error {
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "read body: %v", err)
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := unmarshaler.Unmarshal(body, m); err != nil {
+				return status.Errorf(codes.InvalidArgument, "decode body: %v", err)
+			}
+		}
+	}
+	if len(r.URL.Query()) == 0 {
+		return nil
+	}
+	q := m.ProtoReflect().New().Interface()
+	if err := decodeQuery(r.URL.Query(), q); err != nil {
+		return status.Errorf(codes.InvalidArgument, "decode query: %v", err)
+	}
+	proto.Merge(m, q)
+	return nil
+}
+
+// errMessageTooLarge 流式请求体中的一条消息超过了 maxBodyBytes
+var errMessageTooLarge = fmt.Errorf("message exceeds the limit of %d bytes", maxBodyBytes)
+
+// decodeStream 依次读取 NDJSON 或 JSON 数组中的每个值。数组按元素逐个解码，不会整体读入内存；
+// 每条消息最多 maxBodyBytes 字节
+func decodeStream(body io.Reader, fn func(json.RawMessage) error) error {
+	br := bufio.NewReader(body)
+	lr := &messageReader{r: br}
+	dec := json.NewDecoder(lr)
+	decode := func() (json.RawMessage, error) {
+		lr.limit = dec.InputOffset() + maxBodyBytes
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		return raw, err
+	}
+	for {
+		if nextByte(dec, br) == '[' {
+			lr.limit = dec.InputOffset() + maxBodyBytes
+			if _, err := dec.Token(); err != nil {
+				return decodeError(err)
+			}
+			for dec.More() {
+				raw, err := decode()
+				if err != nil {
+					return decodeError(err)
+				}
+				if err := fn(raw); err != nil {
+					return err
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return decodeError(err)
+			}
+			continue
+		}
+		raw, err := decode()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return decodeError(err)
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeError(err error) error {
+	if errors.Is(err, errMessageTooLarge) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Errorf(codes.InvalidArgument, "decode body: %v", err)
+}
+
+// nextByte 返回下一个值的第一个非空白字符，先看 Decoder 已经缓冲的数据，再看 br；读到结尾时返回 0
+func nextByte(dec *json.Decoder, br *bufio.Reader) byte {
+	buf, _ := io.ReadAll(dec.Buffered())
+	if t := bytes.TrimLeft(buf, " \t\r\n"); len(t) > 0 {
+		return t[0]
+	}
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			return b[0]
+		}
+		br.Discard(1)
+	}
+}
+
+// messageReader 限制 Decoder 读到的位置。Decoder 会预读，limit 以当前消息的起点加 maxBodyBytes 计算，
+// 超过时返回 errMessageTooLarge
+type messageReader struct {
+	r     io.Reader
+	n     int64 // 已读取的字节数
+	limit int64
+}
+
+func (m *messageReader) Read(p []byte) (int, error) {
+	if m.n >= m.limit {
+		return 0, errMessageTooLarge
+	}
+	if int64(len(p)) > m.limit-m.n {
+		p = p[:m.limit-m.n]
+	}
+	n, err := m.r.Read(p)
+	m.n += int64(n)
+	return n, err
+}
+
+func writeMessage(w http.ResponseWriter, code int, m proto.Message) {
+	b, err := marshaler.Marshal(m)
+	if err != nil {
+		writeError(w, status.Errorf(codes.Internal, "encode response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(b, '\n'))
+}
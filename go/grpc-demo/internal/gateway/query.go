@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// decodeQuery 把查询参数填入 m。参数名为字段的 JSON 名或 proto 名，嵌套字段用点连接（如 filter.method），
+// 重复字段可以多次出现；值按 protojson 的字符串形式解析，因此时间戳写作 RFC 3339，时长写作 1.5s
+func decodeQuery(q url.Values, m proto.Message) error {
+	obj := map[string]interface{}{}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := setQueryField(obj, m.ProtoReflect().Descriptor(), strings.Split(key, "."), q[key]); err != nil {
+			return fmt.Errorf("parameter %q: %w", key, err)
+		}
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return unmarshaler.Unmarshal(b, m)
+}
+
+// setQueryField 按路径在 obj 中写入值，obj 之后整体交给 protojson 解析
+func setQueryField(obj map[string]interface{}, md protoreflect.MessageDescriptor, path []string, values []string) error {
+	fd := md.Fields().ByJSONName(path[0])
+	if fd == nil {
+		fd = md.Fields().ByName(protoreflect.Name(path[0]))
+	}
+	if fd == nil {
+		return fmt.Errorf("unknown field")
+	}
+	name := fd.JSONName()
+	if len(path) > 1 {
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return fmt.Errorf("%s is not a message", fd.Name())
+		}
+		child, ok := obj[name].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[name] = child
+		}
+		return setQueryField(child, fd.Message(), path[1:], values)
+	}
+	if fd.IsMap() {
+		return fmt.Errorf("map fields are not supported in the query, send a JSON body")
+	}
+	if fd.IsList() {
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			jv, err := queryValue(fd, v)
+			if err != nil {
+				return err
+			}
+			list[i] = jv
+		}
+		obj[name] = list
+		return nil
+	}
+	jv, err := queryValue(fd, values[len(values)-1])
+	if err != nil {
+		return err
+	}
+	obj[name] = jv
+	return nil
+}
+
+// queryValue protojson 接受字符串形式的数字、枚举名和时间戳，只有布尔值需要转换
+func queryValue(fd protoreflect.FieldDescriptor, v string) (interface{}, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.ParseBool(v)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch fd.Message().FullName() {
+		case "google.protobuf.Timestamp", "google.protobuf.Duration":
+			return v, nil
+		}
+		return nil, fmt.Errorf("%s is a message, set its fields with %s.<field>", fd.Name(), fd.JSONName())
+	}
+	return v, nil
+}
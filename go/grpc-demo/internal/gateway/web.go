@@ -0,0 +1,394 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/mem"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// gRPC-Web 和 Connect 帧头中的标志位
+const (
+	flagCompressed = 0x01
+	flagEndStream  = 0x02 // Connect 流的最后一帧
+	flagTrailer    = 0x80 // gRPC-Web 的 trailer 帧
+)
+
+// NewWeb 返回把 gRPC-Web 和 Connect 协议的请求转发给 conn 的 handler，浏览器不需要 Envoy 之类的代理即可调用。
+//
+// 路径和 gRPC 相同，如 POST /calculator.v1.CalculatorService/Add。gRPC-Web 支持 application/grpc-web(+proto)
+// 和 application/grpc-web-text；Connect 的一元调用支持 application/proto 和 application/json，流式调用支持
+// application/connect+proto 和 application/connect+json。浏览器只能使用一元和服务端流方法，
+// 客户端流和双向流方法需要客户端支持 HTTP/2 全双工。不支持压缩
+func NewWeb(conn grpc.ClientConnInterface) http.Handler {
+	return &webHandler{conn: conn}
+}
+
+// IsWeb 请求是否为 gRPC-Web 或 Connect 调用：按 Content-Type 或 Connect-Protocol-Version 判断，
+// 路径为已注册的 gRPC 方法的 POST 请求也视为 Connect 一元调用
+func IsWeb(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(ct, "application/grpc-web"), strings.HasPrefix(ct, "application/connect+"):
+		return true
+	case r.Header.Get("Connect-Protocol-Version") != "":
+		return true
+	}
+	return r.Method == http.MethodPost && lookupMethod(r.URL.Path) != nil
+}
+
+type webHandler struct {
+	conn grpc.ClientConnInterface
+}
+
+func (h *webHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ct := r.Header.Get("Content-Type")
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	switch ct {
+	case "application/grpc-web", "application/grpc-web+proto":
+		h.serveGRPCWeb(w, r, false)
+	case "application/grpc-web-text", "application/grpc-web-text+proto":
+		h.serveGRPCWeb(w, r, true)
+	case "application/proto", "application/json":
+		h.serveConnectUnary(w, r, ct == "application/json")
+	case "application/connect+proto", "application/connect+json":
+		h.serveConnectStream(w, r, ct == "application/connect+json")
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type %q", ct), http.StatusUnsupportedMediaType)
+	}
+}
+
+// lookupMethod 按 /包名.服务/方法 查找方法描述，未注册时返回 nil
+func lookupMethod(path string) protoreflect.MethodDescriptor {
+	service, method, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok || strings.Contains(method, "/") {
+		return nil
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	return sd.Methods().ByName(protoreflect.Name(method))
+}
+
+// webStream 一次调用在具体协议上的读写方式
+type webStream struct {
+	// next 返回下一条请求消息（proto 编码），没有更多消息时返回 io.EOF
+	next func() ([]byte, error)
+	// header 在第一条响应之前、或者调用结束时调用一次，参数为响应的 header
+	header func(metadata.MD)
+	// send 写出一条响应消息（proto 编码）
+	send func([]byte) error
+}
+
+// forward 把请求消息原样转发给 m，返回调用的 trailer 和最终状态。客户端流方法边读请求边接收响应，
+// 其余方法只读取一条请求
+func (h *webHandler) forward(ctx context.Context, w http.ResponseWriter, r *http.Request, m protoreflect.MethodDescriptor, ws webStream) (metadata.MD, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	method := fmt.Sprintf("/%s/%s", m.Parent().FullName(), m.Name())
+	stream, err := h.conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, method, grpc.ForceCodecV2(rawCodec{}))
+	if err != nil {
+		ws.header(nil)
+		return nil, err
+	}
+
+	// sendErr 为读取请求时出的错，send 返回后才能读取
+	var sendErr error
+	send := func() {
+		for n := 0; ; n++ {
+			msg, err := ws.next()
+			if errors.Is(err, io.EOF) && (n > 0 || m.IsStreamingClient()) {
+				stream.CloseSend()
+				return
+			}
+			if errors.Is(err, io.EOF) {
+				err = status.Error(codes.InvalidArgument, "missing request message")
+			}
+			if err != nil {
+				sendErr = err
+				cancel()
+				return
+			}
+			// SendMsg 出错说明服务端已经结束调用，真正的状态由 RecvMsg 返回
+			if stream.SendMsg(&msg) != nil {
+				return
+			}
+			if !m.IsStreamingClient() {
+				stream.CloseSend()
+				return
+			}
+		}
+	}
+	sent := make(chan struct{})
+	if m.IsStreamingClient() {
+		// HTTP/1.1 默认开始写响应后就不能再读请求体；HTTP/2 本身是全双工的，这里返回的错误可以忽略
+		http.NewResponseController(w).EnableFullDuplex()
+		go func() {
+			defer close(sent)
+			send()
+		}()
+	} else {
+		send()
+		close(sent)
+	}
+
+	headerSent := false
+	sendHeader := func() {
+		if !headerSent {
+			headerSent = true
+			md, _ := stream.Header()
+			ws.header(md)
+		}
+	}
+	for {
+		var msg []byte
+		if err = stream.RecvMsg(&msg); err != nil {
+			break
+		}
+		sendHeader()
+		if err = ws.send(msg); err != nil {
+			// 客户端已经断开
+			cancel()
+			break
+		}
+	}
+	sendHeader()
+	// 调用提前结束时客户端可能还在发送，关闭请求体让 send 退出
+	r.Body.Close()
+	<-sent
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return stream.Trailer(), err
+}
+
+// rawCodec 原样收发已经编码的消息，只有 Connect 的 JSON 编码才需要解析消息
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) (mem.BufferSlice, error) {
+	return mem.BufferSlice{mem.SliceBuffer(*v.(*[]byte))}, nil
+}
+
+func (rawCodec) Unmarshal(data mem.BufferSlice, v interface{}) error {
+	*v.(*[]byte) = data.Materialize()
+	return nil
+}
+
+// Name 服务端按 proto 解码
+func (rawCodec) Name() string { return "proto" }
+
+// jsonToProto 把 JSON 编码的 md 类型消息转换为 proto 编码
+func jsonToProto(md protoreflect.MessageDescriptor, b []byte) ([]byte, error) {
+	m := dynamicpb.NewMessage(md)
+	if err := unmarshaler.Unmarshal(b, m); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode message: %v", err)
+	}
+	return proto.Marshal(m)
+}
+
+// protoToJSON 把 proto 编码的 md 类型消息转换为 JSON
+func protoToJSON(md protoreflect.MessageDescriptor, b []byte) ([]byte, error) {
+	m := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return marshaler.Marshal(m)
+}
+
+// readFrame 读取一帧：1 字节标志位、4 字节大端长度和消息体。gRPC-Web 和 Connect 流式协议使用相同的格式；
+// 在帧边界上读完时返回 io.EOF
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, status.Error(codes.InvalidArgument, "truncated frame header")
+		}
+		if errors.Is(err, io.EOF) {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, status.Errorf(codes.InvalidArgument, "read body: %v", err)
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxBodyBytes {
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "message of %d bytes exceeds the limit of %d", n, maxBodyBytes)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return 0, nil, status.Errorf(codes.InvalidArgument, "truncated frame: %v", err)
+	}
+	if hdr[0]&flagCompressed != 0 {
+		return 0, nil, status.Error(codes.Unimplemented, "compressed messages are not supported")
+	}
+	return hdr[0], msg, nil
+}
+
+// writeFrame 写出一帧并立即刷新，服务端流的每条消息都能马上到达客户端
+func writeFrame(w io.Writer, rc *http.ResponseController, flags byte, msg []byte) error {
+	buf := make([]byte, 5, 5+len(msg))
+	buf[0] = flags
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(msg)))
+	if _, err := w.Write(append(buf, msg...)); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// webContext 从请求头取出转发给服务端的 metadata 和超时时间
+func webContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	md := metadata.MD{}
+	for k, vs := range r.Header {
+		key := strings.ToLower(k)
+		if skipHeader(key) {
+			continue
+		}
+		if strings.HasSuffix(key, "-bin") {
+			for _, v := range vs {
+				b, err := decodeBinaryHeader(v)
+				if err != nil {
+					return nil, nil, status.Errorf(codes.InvalidArgument, "header %s: %v", k, err)
+				}
+				md.Append(key, string(b))
+			}
+			continue
+		}
+		md.Append(key, vs...)
+	}
+	md.Set(interceptor.ForwardedForHeader, clientHost(r))
+	ctx := metadata.NewOutgoingContext(r.Context(), md)
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, cancel, nil
+}
+
+// skipHeader 协议本身使用的头、逐跳头和浏览器自动添加的头不转发给服务端
+func skipHeader(key string) bool {
+	switch key {
+	case "accept", "accept-encoding", "accept-language", "connection", "content-length", "content-type",
+		"content-encoding", "cookie", "host", "keep-alive", "origin", "referer", "te", "trailer",
+		"transfer-encoding", "upgrade", "user-agent", "x-grpc-web", "x-user-agent":
+		return true
+	}
+	for _, prefix := range []string{"grpc-", "connect-", "sec-", "access-control-", "proxy-"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBinaryHeader -bin 结尾的头为 base64，带不带填充都接受
+func decodeBinaryHeader(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}
+
+// setHeaderMetadata 把 metadata 写入 HTTP 头，二进制值按 base64 编码；prefix 为 Connect 一元调用的 Trailer-
+func setHeaderMetadata(h http.Header, prefix string, md metadata.MD) {
+	for k, vs := range md {
+		if k == "content-type" {
+			continue
+		}
+		for _, v := range vs {
+			if strings.HasSuffix(k, "-bin") {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+			h.Add(prefix+k, v)
+		}
+	}
+}
+
+// parseTimeout 解析 grpc-timeout，格式为不超过 8 位的数字加单位 H/M/S/m/u/n
+func parseTimeout(v string) (time.Duration, error) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, fmt.Errorf("invalid timeout %q", v)
+	}
+	n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q", v)
+	}
+	unit, ok := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}[v[len(v)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid timeout unit in %q", v)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// textReader 解码 grpc-web-text 的请求体。客户端可能分别编码每一帧，中间会出现填充，因此按 4 个字符一组解码
+type textReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		var q [4]byte
+		if _, err := io.ReadFull(t.r, q[:]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, errors.New("truncated base64 body")
+			}
+			return 0, err
+		}
+		out := make([]byte, 3)
+		n, err := base64.StdEncoding.Decode(out, q[:])
+		if err != nil {
+			return 0, err
+		}
+		t.buf = out[:n]
+	}
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// textWriter grpc-web-text 的响应，每次写入单独编码
+type textWriter struct {
+	w io.Writer
+}
+
+func (t textWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(t.w, base64.StdEncoding.EncodeToString(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,192 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// DefaultFileLimit FileStore 默认可查询的记录数。服务端把请求和响应各截断到 1KB，每条记录不超过约 3KB，
+// 内存占用最多约 300MB，内存紧张时调小 limit
+const DefaultFileLimit = 100000
+
+// FileStore 追加写的日志文件，每行一条 JSON 记录；查询只读内存中最新的 limit 条记录，写入先落盘再更新内存。
+// 当前文件写满 limit 条后改名为 path.1（覆盖上一个），再写新文件，所以磁盘上最多保留 2*limit 条，
+// 打开时也最多读这么多行；更早的记录被丢弃。轮转失败期间继续写当前文件，它会暂时超过 limit 条
+type FileStore struct {
+	mu    sync.Mutex
+	path  string
+	limit int
+	f     *os.File
+	size  int64 // 已经完整写入的字节数
+	count int   // 当前文件中的记录数
+	mem   *MemoryStore
+	// renamed 当前文件已经改名为 path.1，但新文件还没有打开，仍然写在改名后的文件里
+	renamed bool
+	// rotateFailed 轮转正在连续失败，同一轮失败只记录一次日志
+	rotateFailed bool
+}
+
+// OpenFileStore 打开或创建日志文件，limit 为 0 时不轮转，全部记录都保留在内存中。进程在写入中途退出时
+// 文件末尾可能留下半行，打开时会截掉它；中间出现无法解析的记录则返回错误，避免悄悄丢失审计数据
+func OpenFileStore(path string, limit int) (*FileStore, error) {
+	s := &FileStore{path: path, limit: limit, mem: NewMemoryStore(limit)}
+	// 先加载轮转出去的旧文件，它的记录 ID 都比当前文件小
+	if old, err := os.Open(rotatedPath(path)); err == nil {
+		_, _, err := s.load(old)
+		old.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func rotatedPath(path string) string {
+	return path + ".1"
+}
+
+// open 打开当前文件并加载其中的记录，截掉末尾不完整的行
+func (s *FileStore) open() error {
+	f, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	offset, count, err := s.load(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return fmt.Errorf("history: %w", err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("history: %w", err)
+	}
+	s.f, s.size, s.count = f, offset, count
+	return nil
+}
+
+// load 把 f 中的记录加入内存，返回最后一条完整记录之后的位置和记录数
+func (s *FileStore) load(f *os.File) (int64, int, error) {
+	r := bufio.NewReader(f)
+	var offset int64
+	count := 0
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(b)) > 0 {
+				log.Printf("history: dropping incomplete record at the end of %s", f.Name())
+			}
+			break
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("history: read %s: %w", f.Name(), err)
+		}
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil || e.ID <= s.mem.lastID {
+			return 0, 0, fmt.Errorf("history: corrupt record at %s:%d", f.Name(), line)
+		}
+		s.mem.add(e)
+		offset += int64(len(b))
+		count++
+	}
+	return offset, count, nil
+}
+
+// rotate 把写满的当前文件改名为 path.1 并新建文件。新文件打开之前一直保留原来的句柄，
+// 任何一步失败都可以继续写原文件，下次写入再从失败的那一步重试。调用方持有 s.mu
+func (s *FileStore) rotate() error {
+	if !s.renamed {
+		if err := os.Rename(s.path, rotatedPath(s.path)); err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+		s.renamed = true
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if err := s.f.Close(); err != nil {
+		log.Printf("history: close %s: %v", rotatedPath(s.path), err)
+	}
+	s.f, s.size, s.count, s.renamed = f, 0, 0, false
+	return nil
+}
+
+// Append 写入一行后再放进内存，写失败时记录不会出现在查询结果中，也不占用 ID；轮转失败只记录日志，不影响写入
+func (s *FileStore) Append(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return errors.New("history: store closed")
+	}
+	if s.limit > 0 && s.count >= s.limit {
+		if err := s.rotate(); err != nil {
+			if !s.rotateFailed {
+				log.Printf("%v; keep writing to the current file", err)
+			}
+			s.rotateFailed = true
+		} else {
+			s.rotateFailed = false
+		}
+	}
+	// 追加只发生在 s.mu 之内，读取 lastID 不需要内存索引的锁；写盘期间查询不受影响
+	e.ID = s.mem.lastID + 1
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	b = append(b, '\n')
+	if _, err := s.f.Write(b); err != nil {
+		// 截掉可能写了一半的行，保证后续记录仍然从行首开始
+		if terr := s.f.Truncate(s.size); terr == nil {
+			_, _ = s.f.Seek(s.size, io.SeekStart)
+		}
+		return fmt.Errorf("history: %w", err)
+	}
+	s.size += int64(len(b))
+	s.count++
+	s.mem.mu.Lock()
+	s.mem.add(*e)
+	s.mem.mu.Unlock()
+	return nil
+}
+
+// List 按 ID 倒序返回
+func (s *FileStore) List(f Filter, limit int) ([]Entry, error) {
+	return s.mem.List(f, limit)
+}
+
+// Scan 按 ID 升序遍历
+func (s *FileStore) Scan(f Filter, fn func(Entry) bool) error {
+	return s.mem.Scan(f, fn)
+}
+
+// Close 把数据刷到磁盘并关闭文件
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Sync()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f = nil
+	return err
+}
@@ -0,0 +1,71 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Entry 一次计算调用的审计记录
+type Entry struct {
+	ID        uint64        `json:"id"`
+	Time      time.Time     `json:"time"`
+	Caller    string        `json:"caller"`
+	Method    string        `json:"method"`
+	RequestID string        `json:"request_id,omitempty"`
+	Request   string        `json:"request,omitempty"`  // 请求的 JSON
+	Response  string        `json:"response,omitempty"` // 响应的 JSON，出错时为空
+	Code      codes.Code    `json:"code"`
+	Error     string        `json:"error,omitempty"`
+	Latency   time.Duration `json:"latency"`
+	Received  int           `json:"received"`            // 收到的消息数
+	Sent      int           `json:"sent"`                // 发送的消息数
+	Truncated bool          `json:"truncated,omitempty"` // Request 或 Response 过长被截断，不再是完整的 JSON
+}
+
+// Filter 查询条件，零值字段不参与过滤
+type Filter struct {
+	Caller string
+	// Method 完整方法名，或者只写方法名，如 Add
+	Method string
+	Codes  []codes.Code
+	Since  time.Time // 包含
+	Until  time.Time // 不包含
+	// AfterID/BeforeID 只返回 ID 在 (AfterID, BeforeID) 之间的记录，0 表示不限
+	AfterID  uint64
+	BeforeID uint64
+}
+
+// Match 记录是否满足全部条件
+func (f *Filter) Match(e *Entry) bool {
+	switch {
+	case f.Caller != "" && e.Caller != f.Caller:
+		return false
+	case f.Method != "" && e.Method != f.Method && !strings.HasSuffix(e.Method, "/"+f.Method):
+		return false
+	case len(f.Codes) > 0 && !slices.Contains(f.Codes, e.Code):
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	case f.AfterID != 0 && e.ID <= f.AfterID:
+		return false
+	case f.BeforeID != 0 && e.ID >= f.BeforeID:
+		return false
+	}
+	return true
+}
+
+// Store 审计记录的存储，实现必须并发安全
+type Store interface {
+	// Append 分配比上一条记录大 1 的 ID 并保存记录，失败时不占用 ID
+	Append(e *Entry) error
+	// List 按 ID 倒序返回最多 limit 条匹配的记录
+	List(f Filter, limit int) ([]Entry, error)
+	// Scan 按 ID 升序遍历匹配的记录，fn 返回 false 时停止；fn 执行期间不持有锁
+	Scan(f Filter, fn func(Entry) bool) error
+	Close() error
+}
@@ -0,0 +1,344 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func ids(entries []Entry) []uint64 {
+	var out []uint64
+	for _, e := range entries {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func equal(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fill 写入 6 条记录：奇数 ID 为 alice 的 Add，偶数 ID 为 bob 的 Divide 且失败
+func fill(t *testing.T, s Store, start time.Time) {
+	t.Helper()
+	for i := 1; i <= 6; i++ {
+		e := Entry{Time: start.Add(time.Duration(i) * time.Second), Caller: "sub:alice", Method: "/calculator.v1.CalculatorService/Add"}
+		if i%2 == 0 {
+			e.Caller, e.Method, e.Code = "sub:bob", "/calculator.v1.CalculatorService/Divide", codes.InvalidArgument
+		}
+		if err := s.Append(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ID != uint64(i) {
+			t.Fatalf("id = %d, want %d", e.ID, i)
+		}
+	}
+}
+
+func testStore(t *testing.T, s Store) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fill(t, s, start)
+	tests := []struct {
+		name  string
+		f     Filter
+		limit int
+		want  []uint64
+	}{
+		{"all", Filter{}, 10, []uint64{6, 5, 4, 3, 2, 1}},
+		{"limit", Filter{}, 2, []uint64{6, 5}},
+		{"caller", Filter{Caller: "sub:alice"}, 10, []uint64{5, 3, 1}},
+		{"short method", Filter{Method: "Divide"}, 10, []uint64{6, 4, 2}},
+		{"full method", Filter{Method: "/calculator.v1.CalculatorService/Add"}, 10, []uint64{5, 3, 1}},
+		{"method prefix does not match", Filter{Method: "Ad"}, 10, nil},
+		{"codes", Filter{Codes: []codes.Code{codes.InvalidArgument}}, 10, []uint64{6, 4, 2}},
+		{"time range", Filter{Since: start.Add(2 * time.Second), Until: start.Add(4 * time.Second)}, 10, []uint64{3, 2}},
+		{"before id", Filter{BeforeID: 4}, 10, []uint64{3, 2, 1}},
+		{"after id", Filter{AfterID: 4}, 10, []uint64{6, 5}},
+	}
+	for _, tt := range tests {
+		got, err := s.List(tt.f, tt.limit)
+		if err != nil || !equal(ids(got), tt.want) {
+			t.Errorf("%s: List = %v, %v, want %v", tt.name, ids(got), err, tt.want)
+		}
+	}
+
+	var scanned []uint64
+	err := s.Scan(Filter{AfterID: 1, Caller: "sub:bob"}, func(e Entry) bool {
+		scanned = append(scanned, e.ID)
+		return len(scanned) < 2
+	})
+	if err != nil || !equal(scanned, []uint64{2, 4}) {
+		t.Fatalf("Scan = %v, %v", scanned, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(0))
+}
+
+func TestMemoryStoreLimit(t *testing.T) {
+	s := NewMemoryStore(3)
+	fill(t, s, time.Now())
+	if got, _ := s.List(Filter{}, 10); !equal(ids(got), []uint64{6, 5, 4}) {
+		t.Fatalf("List = %v", ids(got))
+	}
+}
+
+func TestMemoryStoreScanBatches(t *testing.T) {
+	s := NewMemoryStore(0)
+	for i := 0; i < scanBatch*2+10; i++ {
+		if err := s.Append(&Entry{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := 0
+	if err := s.Scan(Filter{}, func(Entry) bool { n++; return true }); err != nil || n != scanBatch*2+10 {
+		t.Fatalf("scanned %d, %v", n, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.log")
+	s, err := OpenFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后记录仍在，ID 接着递增
+	s, err = OpenFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.List(Filter{Caller: "sub:alice"}, 10)
+	if !equal(ids(got), []uint64{5, 3, 1}) || got[0].Method != "/calculator.v1.CalculatorService/Add" {
+		t.Fatalf("after reopen List = %v", got)
+	}
+	e := Entry{Caller: "sub:carol", Latency: time.Millisecond}
+	if err := s.Append(&e); err != nil || e.ID != 7 {
+		t.Fatalf("append after reopen id = %d, %v", e.ID, err)
+	}
+	s.Close()
+}
+
+func TestFileStoreTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.log")
+	s, err := OpenFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fill(t, s, time.Now())
+	s.Close()
+
+	// 模拟写到一半时进程退出
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":7,"caller":"sub:al`)
+	f.Close()
+
+	s, err = OpenFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := Entry{Caller: "sub:dave"}
+	if err := s.Append(&e); err != nil || e.ID != 7 {
+		t.Fatalf("append after truncation id = %d, %v", e.ID, err)
+	}
+	s.Close()
+	s, err = OpenFileStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, _ := s.List(Filter{}, 1); len(got) != 1 || got[0].Caller != "sub:dave" {
+		t.Fatalf("last entry = %v", got)
+	}
+}
+
+func TestFileStoreRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.log")
+	s, err := OpenFileStore(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fill(t, s, time.Now())
+	for i := 0; i < 3; i++ {
+		if err := s.Append(&Entry{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 只有最新的 4 条可以查询；每写满 4 条轮转一次，此时 .1 中是 5~8，当前文件只有 9，1~4 已经被丢弃
+	if got, _ := s.List(Filter{}, 10); !equal(ids(got), []uint64{9, 8, 7, 6}) {
+		t.Fatalf("List = %v", ids(got))
+	}
+	s.Close()
+	old, err := os.ReadFile(path + ".1")
+	if err != nil || bytes.Count(old, []byte("\n")) != 4 {
+		t.Fatalf("rotated file has %d lines, %v", bytes.Count(old, []byte("\n")), err)
+	}
+
+	// 重新打开时从两个文件恢复，ID 接着递增
+	s, err = OpenFileStore(path, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, _ := s.List(Filter{}, 10); !equal(ids(got), []uint64{9, 8, 7, 6}) {
+		t.Fatalf("after reopen List = %v", ids(got))
+	}
+	e := Entry{}
+	if err := s.Append(&e); err != nil || e.ID != 10 {
+		t.Fatalf("append after reopen id = %d, %v", e.ID, err)
+	}
+}
+
+func TestFileStoreRotateFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.log")
+	s, err := OpenFileStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := 0; i < 2; i++ {
+		if err := s.Append(&Entry{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// path.1 是非空目录，改名一定失败（以 root 运行时只读目录挡不住改名）
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Append(&Entry{}); err != nil {
+			t.Fatalf("append while rotation fails: %v", err)
+		}
+	}
+	if b, err := os.ReadFile(path); err != nil || bytes.Count(b, []byte("\n")) != 4 {
+		t.Fatalf("current file has %d lines, %v", bytes.Count(b, []byte("\n")), err)
+	}
+
+	// 障碍消失后下一次写入完成轮转
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	e := Entry{}
+	if err := s.Append(&e); err != nil || e.ID != 5 {
+		t.Fatalf("append after recovery id = %d, %v", e.ID, err)
+	}
+	old, err := os.ReadFile(path + ".1")
+	if err != nil || bytes.Count(old, []byte("\n")) != 4 {
+		t.Fatalf("rotated file has %d lines, %v", bytes.Count(old, []byte("\n")), err)
+	}
+	if got, _ := s.List(Filter{}, 10); !equal(ids(got), []uint64{5, 4}) {
+		t.Fatalf("List = %v", ids(got))
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.log")
+	if err := os.WriteFile(path, []byte("{\"id\":1}\nnot json\n{\"id\":3}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path, 0); err == nil {
+		t.Fatal("expected error for corrupt record in the middle of the log")
+	}
+}
+
+func TestJournal(t *testing.T) {
+	j := NewJournal(NewMemoryStore(0))
+	sub := j.Subscribe(4)
+	for i := 0; i < 3; i++ {
+		if err := j.Record(Entry{Caller: "sub:alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for want := uint64(1); want <= 3; want++ {
+		if e := <-sub.C; e.ID != want {
+			t.Fatalf("received id %d, want %d", e.ID, want)
+		}
+	}
+	sub.Close()
+	if _, ok := <-sub.C; ok || sub.Err() != nil {
+		t.Fatalf("closed subscription err = %v", sub.Err())
+	}
+
+	// 缓冲区满的订阅者被断开，不影响写入
+	slow := j.Subscribe(1)
+	for i := 0; i < 3; i++ {
+		if err := j.Record(Entry{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-slow.C
+	if _, ok := <-slow.C; ok || slow.Err() != ErrLagged {
+		t.Fatalf("slow subscriber err = %v", slow.Err())
+	}
+	if got, _ := j.List(Filter{}, 10); len(got) != 6 {
+		t.Fatalf("stored %d entries", len(got))
+	}
+}
+
+// blockingStore Append 等到 release 被关闭后才写入
+type blockingStore struct {
+	*MemoryStore
+	release chan struct{}
+}
+
+func (s *blockingStore) Append(e *Entry) error {
+	<-s.release
+	return s.MemoryStore.Append(e)
+}
+
+func TestJournalConcurrent(t *testing.T) {
+	store := &blockingStore{MemoryStore: NewMemoryStore(0), release: make(chan struct{})}
+	j := NewJournal(store)
+	sub := j.Subscribe(64)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := j.Record(Entry{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// 写入阻塞时订阅不受影响
+	j.Subscribe(1).Close()
+	close(store.release)
+	wg.Wait()
+	for want := uint64(1); want <= 50; want++ {
+		if e := <-sub.C; e.ID != want {
+			t.Fatalf("received id %d, want %d", e.ID, want)
+		}
+	}
+
+	// 基于已有记录的存储，推送从下一个 ID 开始
+	j = NewJournal(store.MemoryStore)
+	sub = j.Subscribe(1)
+	if err := j.Record(Entry{}); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-sub.C; e.ID != 51 {
+		t.Fatalf("received id %d, want 51", e.ID)
+	}
+}
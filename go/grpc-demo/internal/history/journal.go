@@ -0,0 +1,103 @@
+package history
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrLagged 订阅者消费太慢，缓冲区满后被断开
+var ErrLagged = errors.New("history: subscriber lagged behind")
+
+// Journal 在 Store 之上增加实时订阅：每条记录写入成功后推送给所有订阅者
+type Journal struct {
+	Store
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	// next 下一条要推送的 ID，pending 已经写入、但前面还有记录没写完的记录
+	next    uint64
+	pending map[uint64]Entry
+}
+
+// NewJournal 包装 store
+func NewJournal(store Store) *Journal {
+	j := &Journal{Store: store, subs: make(map[*Subscription]struct{}), next: 1, pending: make(map[uint64]Entry)}
+	if last, err := store.List(Filter{}, 1); err == nil && len(last) == 1 {
+		j.next = last[0].ID + 1
+	}
+	return j
+}
+
+// Record 保存记录并推送。写入不持有 j.mu，慢的存储不会挡住订阅和推送；并发写入的记录按 ID 排队推送，
+// 订阅者收到的 ID 严格递增且不会跳过
+func (j *Journal) Record(e Entry) error {
+	if err := j.Store.Append(&e); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pending[e.ID] = e
+	for {
+		e, ok := j.pending[j.next]
+		if !ok {
+			return nil
+		}
+		delete(j.pending, j.next)
+		j.next++
+		j.publish(e)
+	}
+}
+
+// publish 推送给所有订阅者，调用方持有 j.mu
+func (j *Journal) publish(e Entry) {
+	for s := range j.subs {
+		select {
+		case s.c <- e:
+		default:
+			// 不能因为一个慢订阅者阻塞计算请求，直接断开它
+			s.err = ErrLagged
+			j.remove(s)
+		}
+	}
+}
+
+// Subscribe 订阅之后写入的记录，buffer 为允许积压的条数
+func (j *Journal) Subscribe(buffer int) *Subscription {
+	s := &Subscription{c: make(chan Entry, buffer), j: j}
+	s.C = s.c
+	j.mu.Lock()
+	j.subs[s] = struct{}{}
+	j.mu.Unlock()
+	return s
+}
+
+// remove 调用方持有 j.mu
+func (j *Journal) remove(s *Subscription) {
+	if _, ok := j.subs[s]; ok {
+		delete(j.subs, s)
+		close(s.c)
+	}
+}
+
+// Subscription 一个订阅，C 被关闭表示订阅结束
+type Subscription struct {
+	C <-chan Entry
+
+	c   chan Entry
+	j   *Journal
+	err error // 由 j.mu 保护
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.j.mu.Lock()
+	s.j.remove(s)
+	s.j.mu.Unlock()
+}
+
+// Err C 被关闭之后返回断开的原因，主动 Close 时为 nil
+func (s *Subscription) Err() error {
+	s.j.mu.Lock()
+	defer s.j.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,100 @@
+package history
+
+import (
+	"sort"
+	"sync"
+)
+
+// scanBatch Scan 每次加锁取出的记录数
+const scanBatch = 256
+
+// MemoryStore 内存存储，进程退出后记录丢失
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries []Entry // 按 ID 升序
+	lastID  uint64
+	limit   int
+}
+
+// NewMemoryStore limit 大于 0 时只保留最新的 limit 条记录
+func NewMemoryStore(limit int) *MemoryStore {
+	return &MemoryStore{limit: limit}
+}
+
+// Append 分配 ID 并保存
+func (s *MemoryStore) Append(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID = s.lastID + 1
+	s.add(*e)
+	return nil
+}
+
+// add 保存已经分配了 ID 的记录，调用方持有写锁
+func (s *MemoryStore) add(e Entry) {
+	s.lastID = e.ID
+	s.entries = append(s.entries, e)
+	if s.limit > 0 && len(s.entries) > s.limit {
+		// 丢掉最旧的一条只重新切片，不搬动整个数组；容量用完时 append 会分配新数组，
+		// 只复制仍保留的记录，所以底层数组不会无限增长
+		s.entries[0] = Entry{}
+		s.entries = s.entries[1:]
+	}
+}
+
+// bounds 返回 ID 在 (after, before) 之间的下标范围，调用方持有锁
+func (s *MemoryStore) bounds(after, before uint64) (int, int) {
+	lo := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].ID > after })
+	hi := len(s.entries)
+	if before != 0 {
+		hi = sort.Search(len(s.entries), func(i int) bool { return s.entries[i].ID >= before })
+	}
+	return lo, hi
+}
+
+// List 按 ID 倒序返回
+func (s *MemoryStore) List(f Filter, limit int) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Entry
+	lo, hi := s.bounds(f.AfterID, f.BeforeID)
+	for i := hi - 1; i >= lo && len(out) < limit; i-- {
+		if f.Match(&s.entries[i]) {
+			out = append(out, s.entries[i])
+		}
+	}
+	return out, nil
+}
+
+// Scan 分批取出记录，回调期间不持有锁，遍历过程中追加的记录也会被看到
+func (s *MemoryStore) Scan(f Filter, fn func(Entry) bool) error {
+	for {
+		s.mu.RLock()
+		lo, hi := s.bounds(f.AfterID, f.BeforeID)
+		end := min(hi, lo+scanBatch)
+		var batch []Entry
+		for i := lo; i < end; i++ {
+			if f.Match(&s.entries[i]) {
+				batch = append(batch, s.entries[i])
+			}
+		}
+		if end > lo {
+			f.AfterID = s.entries[end-1].ID
+		}
+		done := end >= hi
+		s.mu.RUnlock()
+		for _, e := range batch {
+			if !fn(e) {
+				return nil
+			}
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// Close 内存存储无需释放资源
+func (s *MemoryStore) Close() error {
+	return nil
+}
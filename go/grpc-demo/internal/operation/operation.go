@@ -0,0 +1,312 @@
+package operation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetention 结束的任务保留多久，期间可以取回结果
+	DefaultRetention = time.Hour
+	// DefaultLimit 同时保留的任务数上限，包括已结束但还没过期的
+	DefaultLimit = 1000
+	// DefaultMaxRunning 同时运行的任务数上限
+	DefaultMaxRunning = 16
+	// DefaultMaxResultBytes 保留的结果总大小上限
+	DefaultMaxResultBytes = 64 << 20
+	// sweepInterval 清理过期任务的间隔
+	sweepInterval = time.Minute
+)
+
+var (
+	// ErrNotFound 任务不存在、已经过期或属于其他调用方
+	ErrNotFound = errors.New("operation not found")
+	// ErrTooMany 保留或运行中的任务数达到上限
+	ErrTooMany = errors.New("too many operations")
+	// ErrClosed Store 已经关闭
+	ErrClosed = errors.New("operation store closed")
+)
+
+// State 任务状态
+type State int
+
+const (
+	Running State = iota + 1
+	Succeeded
+	Failed
+	Cancelled
+)
+
+// Done 任务是否已经结束
+func (s State) Done() bool {
+	return s != Running
+}
+
+// Func 任务函数：通过 progress 报告 0~100 的进度，ctx 被取消时应尽快返回 ctx.Err()
+type Func func(ctx context.Context, progress func(percent float64)) (any, error)
+
+// Operation 任务在某一时刻的快照
+type Operation struct {
+	ID       string
+	Kind     string
+	State    State
+	Progress float64
+	// Result 成功时 Func 的返回值
+	Result any
+	// Err 失败时 Func 返回的错误；取消时为 context.Canceled
+	Err     error
+	Created time.Time
+	Updated time.Time
+}
+
+type job struct {
+	owner  string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// 以下字段由 Store.mu 保护
+	op   Operation
+	size int // 结果的大小，见 resultSize
+}
+
+// Store 后台运行的任务：任务不随发起它的调用结束，结束后保留 retention 时长，期间只有发起方可以查询。
+// 保留的结果总大小超过 maxResultBytes 时，先删除最早结束的任务
+type Store struct {
+	retention      time.Duration
+	limit          int
+	maxRunning     int
+	maxResultBytes int
+	now            func() time.Time
+	ctx            context.Context
+	stop           context.CancelFunc
+	wg             sync.WaitGroup
+
+	mu        sync.Mutex
+	jobs      map[string]*job
+	running   int
+	lastSweep time.Time
+	// resultBytes 保留的结果的总大小
+	resultBytes int
+}
+
+// NewStore 创建任务存储；参数不大于 0 时使用默认值
+func NewStore(retention time.Duration, limit, maxRunning, maxResultBytes int) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if maxRunning <= 0 {
+		maxRunning = DefaultMaxRunning
+	}
+	if maxResultBytes <= 0 {
+		maxResultBytes = DefaultMaxResultBytes
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &Store{
+		retention:      retention,
+		limit:          limit,
+		maxRunning:     maxRunning,
+		maxResultBytes: maxResultBytes,
+		now:            time.Now,
+		ctx:            ctx,
+		stop:           stop,
+		jobs:           make(map[string]*job),
+	}
+}
+
+// Start 为 owner 在后台启动任务 fn，kind 为任务种类，只用于展示
+func (st *Store) Start(owner, kind string, fn Func) (Operation, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.ctx.Err() != nil {
+		return Operation{}, ErrClosed
+	}
+	now := st.now()
+	st.sweep(now)
+	if len(st.jobs) >= st.limit || st.running >= st.maxRunning {
+		return Operation{}, ErrTooMany
+	}
+	ctx, cancel := context.WithCancel(st.ctx)
+	j := &job{
+		owner:  owner,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		op:     Operation{ID: newID(), Kind: kind, State: Running, Created: now, Updated: now},
+	}
+	st.jobs[j.op.ID] = j
+	st.running++
+	st.wg.Add(1)
+	go st.run(ctx, j, fn)
+	return j.op, nil
+}
+
+func (st *Store) run(ctx context.Context, j *job, fn Func) {
+	defer st.wg.Done()
+	defer j.cancel()
+	result, err := fn(ctx, func(percent float64) {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		// 进度只增不减
+		if percent > j.op.Progress && percent <= 100 {
+			j.op.Progress = percent
+			j.op.Updated = st.now()
+		}
+	})
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	switch {
+	case err == nil:
+		j.op.State, j.op.Result, j.op.Progress = Succeeded, result, 100
+		j.size = resultSize(result)
+		st.evict(j.size)
+		st.resultBytes += j.size
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		j.op.State, j.op.Err = Cancelled, context.Canceled
+	default:
+		j.op.State, j.op.Err = Failed, err
+	}
+	j.op.Updated = st.now()
+	st.running--
+	close(j.done)
+}
+
+// Get 返回 owner 的任务 id 的当前状态
+func (st *Store) Get(owner, id string) (Operation, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	j, err := st.lookup(owner, id)
+	if err != nil {
+		return Operation{}, err
+	}
+	return j.op, nil
+}
+
+// List 返回 owner 的所有任务，新创建的在前
+func (st *Store) List(owner string) []Operation {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	var ops []Operation
+	for _, j := range st.jobs {
+		if j.owner == owner && !st.expired(j, now) {
+			ops = append(ops, j.op)
+		}
+	}
+	sort.Slice(ops, func(a, b int) bool {
+		if !ops[a].Created.Equal(ops[b].Created) {
+			return ops[a].Created.After(ops[b].Created)
+		}
+		return ops[a].ID < ops[b].ID
+	})
+	return ops
+}
+
+// Cancel 请求取消任务并返回当前状态；任务在 Func 返回后才变为 Cancelled，已经结束的任务不受影响
+func (st *Store) Cancel(owner, id string) (Operation, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	j, err := st.lookup(owner, id)
+	if err != nil {
+		return Operation{}, err
+	}
+	j.cancel()
+	return j.op, nil
+}
+
+// Wait 等待任务结束后返回其状态；ctx 先结束时返回当时的状态和 ctx.Err()
+func (st *Store) Wait(ctx context.Context, owner, id string) (Operation, error) {
+	st.mu.Lock()
+	j, err := st.lookup(owner, id)
+	st.mu.Unlock()
+	if err != nil {
+		return Operation{}, err
+	}
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if j.op.State.Done() {
+		return j.op, nil
+	}
+	return j.op, ctx.Err()
+}
+
+// Close 取消所有运行中的任务并等待它们退出，之后不能再启动新任务
+func (st *Store) Close() {
+	st.stop()
+	st.wg.Wait()
+}
+
+// lookup 调用方持有 st.mu
+func (st *Store) lookup(owner, id string) (*job, error) {
+	j, ok := st.jobs[id]
+	// 其他调用方的任务同样报告不存在，不暴露它是否存在
+	if !ok || j.owner != owner || st.expired(j, st.now()) {
+		return nil, ErrNotFound
+	}
+	return j, nil
+}
+
+func (st *Store) expired(j *job, now time.Time) bool {
+	return j.op.State.Done() && now.Sub(j.op.Updated) >= st.retention
+}
+
+// sweep 删除过期任务，调用方持有 st.mu
+func (st *Store) sweep(now time.Time) {
+	if now.Sub(st.lastSweep) < min(sweepInterval, st.retention) {
+		return
+	}
+	st.lastSweep = now
+	for id, j := range st.jobs {
+		if st.expired(j, now) {
+			st.remove(id)
+		}
+	}
+}
+
+// evict 删除最早结束的任务，直到再保留 size 字节的结果也不超过上限；调用方持有 st.mu
+func (st *Store) evict(size int) {
+	for st.resultBytes > 0 && st.resultBytes+size > st.maxResultBytes {
+		var oldest *job
+		for _, j := range st.jobs {
+			if j.size > 0 && (oldest == nil || j.op.Updated.Before(oldest.op.Updated)) {
+				oldest = j
+			}
+		}
+		st.remove(oldest.op.ID)
+	}
+}
+
+// remove 调用方持有 st.mu
+func (st *Store) remove(id string) {
+	st.resultBytes -= st.jobs[id].size
+	delete(st.jobs, id)
+}
+
+// resultSize 结果占用的字节数；只计算字符串和字节切片，其他类型的结果很小，按 0 计
+func resultSize(v any) int {
+	switch r := v.(type) {
+	case string:
+		return len(r)
+	case []byte:
+		return len(r)
+	}
+	return 0
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read 不会返回错误
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,151 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blocking 报告一次进度后等待 release 或取消
+func blocking(release <-chan struct{}) Func {
+	return func(ctx context.Context, progress func(float64)) (any, error) {
+		progress(40)
+		progress(20)
+		select {
+		case <-release:
+			return "done", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	st := NewStore(0, 0, 0, 0)
+	defer st.Close()
+	release := make(chan struct{})
+	op, err := st.Start("alice", "test", blocking(release))
+	if err != nil || op.State != Running {
+		t.Fatalf("Start = %+v, %v", op, err)
+	}
+	// 其他调用方看不到
+	if _, err := st.Get("bob", op.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get by another owner = %v", err)
+	}
+	if len(st.List("bob")) != 0 {
+		t.Fatal("List by another owner is not empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	got, err := st.Wait(ctx, "alice", op.ID)
+	if !errors.Is(err, context.DeadlineExceeded) || got.State != Running || got.Progress != 40 {
+		t.Fatalf("Wait before done = %+v, %v", got, err)
+	}
+
+	close(release)
+	got, err = st.Wait(context.Background(), "alice", op.ID)
+	if err != nil || got.State != Succeeded || got.Result != "done" || got.Progress != 100 {
+		t.Fatalf("Wait = %+v, %v", got, err)
+	}
+	if ops := st.List("alice"); len(ops) != 1 || ops[0].ID != op.ID {
+		t.Fatalf("List = %+v", ops)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	st := NewStore(0, 0, 1, 0)
+	defer st.Close()
+	op, err := st.Start("alice", "test", blocking(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同时运行的任务数达到上限
+	if _, err := st.Start("alice", "test", blocking(nil)); !errors.Is(err, ErrTooMany) {
+		t.Fatalf("second Start = %v", err)
+	}
+	if _, err := st.Cancel("bob", op.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Cancel by another owner = %v", err)
+	}
+	if _, err := st.Cancel("alice", op.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.Wait(context.Background(), "alice", op.ID)
+	if err != nil || got.State != Cancelled || !errors.Is(got.Err, context.Canceled) {
+		t.Fatalf("Wait after Cancel = %+v, %v", got, err)
+	}
+	// 取消的任务释放了运行名额
+	if _, err := st.Start("alice", "test", blocking(nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailedAndExpired(t *testing.T) {
+	st := NewStore(time.Minute, 0, 0, 0)
+	defer st.Close()
+	now := time.Unix(0, 0)
+	st.now = func() time.Time { return now }
+	boom := errors.New("boom")
+	op, err := st.Start("alice", "test", func(context.Context, func(float64)) (any, error) { return nil, boom })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.Wait(context.Background(), "alice", op.ID)
+	if err != nil || got.State != Failed || got.Err != boom {
+		t.Fatalf("Wait = %+v, %v", got, err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := st.Get("alice", op.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after retention = %v", err)
+	}
+}
+
+func TestResultBudget(t *testing.T) {
+	st := NewStore(0, 0, 0, 10)
+	defer st.Close()
+	now := time.Unix(0, 0)
+	st.now = func() time.Time { return now }
+	result := func(v any) Func {
+		return func(context.Context, func(float64)) (any, error) { return v, nil }
+	}
+	var ids []string
+	for _, v := range []any{"aaaa", "bbbb", int64(7), "cccc"} {
+		now = now.Add(time.Second)
+		op, err := st.Start("alice", "test", result(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Wait(context.Background(), "alice", op.ID); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, op.ID)
+	}
+	// 第三个字符串结果放不下，最早结束的 aaaa 被删除；不占空间的结果不受影响
+	if _, err := st.Get("alice", ids[0]); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("oldest result kept past the budget: %v", err)
+	}
+	for _, id := range ids[1:] {
+		if _, err := st.Get("alice", id); err != nil {
+			t.Fatalf("Get(%s) = %v", id, err)
+		}
+	}
+	if st.resultBytes != 8 {
+		t.Fatalf("retained %d bytes, want 8", st.resultBytes)
+	}
+}
+
+func TestClose(t *testing.T) {
+	st := NewStore(0, 0, 0, 0)
+	op, err := st.Start("alice", "test", blocking(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
+	if got, _ := st.Get("alice", op.ID); got.State != Cancelled {
+		t.Fatalf("state after Close = %v", got.State)
+	}
+	if _, err := st.Start("alice", "test", blocking(nil)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Start after Close = %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// binaryOp 二元整数运算
+type binaryOp func(a, b int64) (int64, error)
+
+func (server *CalculatorSerer) Subtract(ctx context.Context, req *v1.OperandsRequest) (*v1.ResultResponse, error) {
+	return unaryOp(ctx, req, calc.Subtract)
+}
+
+func (server *CalculatorSerer) SubtractBatch(ctx context.Context, req *v1.BatchRequest) (*v1.BatchResponse, error) {
+	return batchOp(ctx, req, calc.Subtract)
+}
+
+func (server *CalculatorSerer) ChatSubtract(stream v1.CalculatorService_ChatSubtractServer) error {
+	return chatOp(stream, calc.Subtract)
+}
+
+func (server *CalculatorSerer) Multiply(ctx context.Context, req *v1.OperandsRequest) (*v1.ResultResponse, error) {
+	return unaryOp(ctx, req, calc.Multiply)
+}
+
+func (server *CalculatorSerer) MultiplyBatch(ctx context.Context, req *v1.BatchRequest) (*v1.BatchResponse, error) {
+	return batchOp(ctx, req, calc.Multiply)
+}
+
+func (server *CalculatorSerer) ChatMultiply(stream v1.CalculatorService_ChatMultiplyServer) error {
+	return chatOp(stream, calc.Multiply)
+}
+
+func (server *CalculatorSerer) Modulo(ctx context.Context, req *v1.OperandsRequest) (*v1.ResultResponse, error) {
+	return unaryOp(ctx, req, calc.Modulo)
+}
+
+func (server *CalculatorSerer) ModuloBatch(ctx context.Context, req *v1.BatchRequest) (*v1.BatchResponse, error) {
+	return batchOp(ctx, req, calc.Modulo)
+}
+
+func (server *CalculatorSerer) ChatModulo(stream v1.CalculatorService_ChatModuloServer) error {
+	return chatOp(stream, calc.Modulo)
+}
+
+func (server *CalculatorSerer) Pow(ctx context.Context, req *v1.OperandsRequest) (*v1.ResultResponse, error) {
+	return unaryOp(ctx, req, calc.Pow)
+}
+
+func (server *CalculatorSerer) PowBatch(ctx context.Context, req *v1.BatchRequest) (*v1.BatchResponse, error) {
+	return batchOp(ctx, req, calc.Pow)
+}
+
+func (server *CalculatorSerer) ChatPow(stream v1.CalculatorService_ChatPowServer) error {
+	return chatOp(stream, calc.Pow)
+}
+
+// Divide 同时返回商和余数，因此不走 binaryOp 的通用路径
+func (server *CalculatorSerer) Divide(ctx context.Context, req *v1.OperandsRequest) (*v1.DivideResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	q, r, err := calc.Divide(req.A, req.B)
+	if err != nil {
+		return nil, calcError(err)
+	}
+	return &v1.DivideResponse{Quotient: q, Remainder: r}, nil
+}
+
+func (server *CalculatorSerer) DivideBatch(ctx context.Context, req *v1.BatchRequest) (*v1.DivideBatchResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	resp := &v1.DivideBatchResponse{Results: make([]*v1.DivideResponse, 0, len(req.GetItems()))}
+	for i, item := range req.GetItems() {
+		q, r, err := calc.Divide(item.GetA(), item.GetB())
+		if err != nil {
+			return nil, calcItemError(err, i)
+		}
+		resp.Results = append(resp.Results, &v1.DivideResponse{Quotient: q, Remainder: r})
+	}
+	return resp, nil
+}
+
+func (server *CalculatorSerer) ChatDivide(stream v1.CalculatorService_ChatDivideServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "recv err : %v", err)
+		}
+		q, r, err := calc.Divide(req.A, req.B)
+		if err != nil {
+			return calcError(err)
+		}
+		if err := stream.Send(&v1.DivideResponse{Quotient: q, Remainder: r}); err != nil {
+			return status.Errorf(codes.Internal, "send err : %v", err)
+		}
+	}
+}
+
+func unaryOp(ctx context.Context, req *v1.OperandsRequest, op binaryOp) (*v1.ResultResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	result, err := op(req.A, req.B)
+	if err != nil {
+		return nil, calcError(err)
+	}
+	return &v1.ResultResponse{Result: result}, nil
+}
+
+func batchOp(ctx context.Context, req *v1.BatchRequest, op binaryOp) (*v1.BatchResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	resp := &v1.BatchResponse{Results: make([]int64, 0, len(req.GetItems()))}
+	for i, item := range req.GetItems() {
+		result, err := op(item.GetA(), item.GetB())
+		if err != nil {
+			return nil, calcItemError(err, i)
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// chatOp 与 ChatAdd 相同：每收到一组操作数就回一个结果，出错时结束整个流
+func chatOp(stream grpc.BidiStreamingServer[v1.OperandsRequest, v1.ResultResponse], op binaryOp) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "recv err : %v", err)
+		}
+		result, err := op(req.A, req.B)
+		if err != nil {
+			return calcError(err)
+		}
+		if err := stream.Send(&v1.ResultResponse{Result: result}); err != nil {
+			return status.Errorf(codes.Internal, "send err : %v", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+	"github.com/MorseWayne/grpc-demo/internal/expr"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain ErrorInfo 中使用的错误域
+const errorDomain = "calculator.v1"
+
+// calcError 将 calc 包返回的错误转换为带 error details 的 gRPC 状态
+func calcError(err error) error {
+	return calcFieldError(err, "")
+}
+
+// calcItemError 批量请求中第 index 项出错，字段名形如 items[2].b
+func calcItemError(err error, index int) error {
+	return calcFieldError(err, fmt.Sprintf("items[%d].", index))
+}
+
+func calcFieldError(err error, prefix string) error {
+	var oe *calc.OverflowError
+	if errors.As(err, &oe) {
+		a, b := strconv.FormatInt(oe.A, 10), strconv.FormatInt(oe.B, 10)
+		return withDetails(status.New(codes.OutOfRange, err.Error()),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: prefix + "a", Description: "operand a = " + a + " causes int64 overflow in " + oe.Op},
+				{Field: prefix + "b", Description: "operand b = " + b + " causes int64 overflow in " + oe.Op},
+			}},
+			&errdetails.ErrorInfo{
+				Reason:   "INT64_OVERFLOW",
+				Domain:   errorDomain,
+				Metadata: map[string]string{"op": oe.Op, "a": a, "b": b},
+			},
+		)
+	}
+	var ie *calc.InvalidOperandError
+	if errors.As(err, &ie) {
+		a, b := strconv.FormatInt(ie.A, 10), strconv.FormatInt(ie.B, 10)
+		return withDetails(status.New(codes.InvalidArgument, err.Error()),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: prefix + "b", Description: "operand b = " + b + " is not allowed in " + ie.Op},
+			}},
+			&errdetails.ErrorInfo{
+				Reason:   ie.Reason,
+				Domain:   errorDomain,
+				Metadata: map[string]string{"op": ie.Op, "a": a, "b": b},
+			},
+		)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// exprError 将表达式解析/求值错误转换为 gRPC 状态，details 中带出错位置
+func exprError(err error) error {
+	var se *expr.SyntaxError
+	if errors.As(err, &se) {
+		return withDetails(status.New(codes.InvalidArgument, err.Error()),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "expression", Description: se.Msg + " at position " + strconv.Itoa(se.Pos)},
+			}},
+			&errdetails.ErrorInfo{
+				Reason:   "SYNTAX_ERROR",
+				Domain:   errorDomain,
+				Metadata: map[string]string{"position": strconv.Itoa(se.Pos)},
+			},
+		)
+	}
+	var ee *expr.EvalError
+	if !errors.As(err, &ee) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	code, reason := codes.InvalidArgument, ee.Reason
+	md := map[string]string{"position": strconv.Itoa(ee.Pos)}
+	var oe *calc.OverflowError
+	var ie *calc.InvalidOperandError
+	switch {
+	case errors.As(err, &oe):
+		code, reason = codes.OutOfRange, "INT64_OVERFLOW"
+		md["op"], md["a"], md["b"] = oe.Op, strconv.FormatInt(oe.A, 10), strconv.FormatInt(oe.B, 10)
+	case errors.As(err, &ie):
+		reason = ie.Reason
+		md["op"], md["a"], md["b"] = ie.Op, strconv.FormatInt(ie.A, 10), strconv.FormatInt(ie.B, 10)
+	}
+	return withDetails(status.New(code, err.Error()),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "expression", Description: err.Error()},
+		}},
+		&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain, Metadata: md},
+	)
+}
+
+// withDetails 附加 details，失败时退化为不带 details 的状态
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	ds, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return ds.Err()
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Evaluate 计算中缀表达式
+func (server *CalculatorSerer) Evaluate(ctx context.Context, req *v1.EvaluateRequest) (*v1.EvaluateResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	result, err := expr.Evaluate(req.Expression, req.Variables)
+	if err != nil {
+		return nil, exprError(err)
+	}
+	return &v1.EvaluateResponse{Result: result}, nil
+}
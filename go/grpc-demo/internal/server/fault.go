@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/pkg/fault"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// FaultServer 故障注入的管理接口，只有开启故障注入时才注册
+type FaultServer struct {
+	v1.UnimplementedFaultServiceServer
+	injector *fault.Injector
+}
+
+func (s *FaultServer) SetFaults(ctx context.Context, req *v1.FaultConfig) (*v1.FaultConfig, error) {
+	rules := faultRules(req)
+	var violations []*errdetails.BadRequest_FieldViolation
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       fmt.Sprintf("rules[%d]", i),
+				Description: err.Error(),
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return nil, withDetails(status.New(codes.InvalidArgument, "invalid fault rules"),
+			&errdetails.BadRequest{FieldViolations: violations})
+	}
+	if err := s.injector.Set(rules); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return faultConfig(s.injector.Rules()), nil
+}
+
+func (s *FaultServer) GetFaults(ctx context.Context, req *v1.GetFaultsRequest) (*v1.FaultConfig, error) {
+	return faultConfig(s.injector.Rules()), nil
+}
+
+// ReadFaultConfig 读取 FaultConfig 的 JSON 文件，格式与 SetFaults 的请求相同，如
+// {"rules": [{"method": "Add", "delay": "0.2s", "abortCode": 14, "abortPercent": 50}]}
+func ReadFaultConfig(path string) (*v1.FaultConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &v1.FaultConfig{}
+	if err := protojson.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, r := range faultRules(cfg) {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// NewFaultInjector 用 FaultConfig 中的规则创建注入器，cfg 为 nil 时没有规则
+func NewFaultInjector(cfg *v1.FaultConfig) (*fault.Injector, error) {
+	return fault.New(faultRules(cfg)...)
+}
+
+func faultRules(cfg *v1.FaultConfig) []fault.Rule {
+	rules := make([]fault.Rule, 0, len(cfg.GetRules()))
+	for _, r := range cfg.GetRules() {
+		rules = append(rules, fault.Rule{
+			Method:       r.Method,
+			Delay:        r.Delay.AsDuration(),
+			DelayPercent: r.DelayPercent,
+			AbortCode:    codes.Code(r.AbortCode),
+			AbortPercent: r.AbortPercent,
+			CutAfter:     int(r.CutAfter),
+		})
+	}
+	return rules
+}
+
+func faultConfig(rules []fault.Rule) *v1.FaultConfig {
+	cfg := &v1.FaultConfig{}
+	for _, r := range rules {
+		rule := &v1.FaultRule{
+			Method:       r.Method,
+			DelayPercent: r.DelayPercent,
+			AbortCode:    uint32(r.AbortCode),
+			AbortPercent: r.AbortPercent,
+			CutAfter:     uint32(r.CutAfter),
+		}
+		if r.Delay > 0 {
+			rule.Delay = durationpb.New(r.Delay)
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+	return cfg
+}
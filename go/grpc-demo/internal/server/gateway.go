@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/internal/gateway"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// serveGateway 在 lis 上同时提供 gRPC、gRPC-Web/Connect 和 HTTP/JSON 网关：gRPC 请求交给 grpc.Server.ServeHTTP，
+// 其余请求按协议交给网关。网关通过进程内连接调用同一个 grpc.Server，认证、限流、历史记录等照常生效
+func serveGateway(ctx context.Context, lis net.Listener, o *options) error {
+	// TLS 由 http.Server 终止，进程内连接不需要
+	grpcOpts := *o
+	grpcOpts.tlsConfig = nil
+	s, hs := newGrpcServer(&grpcOpts)
+
+	pipe := newPipeListener()
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(pipe.DialContext),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var rest, web http.Handler
+	if o.gateway {
+		rest = gateway.New(conn)
+	}
+	if o.web {
+		web = gateway.NewWeb(conn)
+	}
+	var gw http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case web != nil && gateway.IsWeb(r):
+			web.ServeHTTP(w, r)
+		case rest != nil:
+			rest.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	if len(o.corsOrigins) > 0 {
+		gw = gateway.CORS(o.corsOrigins, gw)
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if gateway.IsGRPC(r) {
+				s.ServeHTTP(w, r)
+				return
+			}
+			gw.ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		Protocols:         new(http.Protocols),
+	}
+	// 明文时 gRPC 客户端直接使用 HTTP/2（prior knowledge），浏览器和 curl 使用 HTTP/1.1
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(true)
+	srv.Protocols.SetUnencryptedHTTP2(true)
+	if o.tlsConfig != nil {
+		lis = tls.NewListener(lis, withHTTP1(o.tlsConfig))
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- s.Serve(pipe)
+	}()
+	go func() {
+		errCh <- srv.Serve(lis)
+	}()
+	select {
+	case err := <-errCh:
+		srv.Close()
+		s.Stop()
+		return err
+	case <-ctx.Done():
+	}
+
+	drain(o, hs, func() {
+		// 先等 HTTP 请求（包括经网关发出的调用）结束，再停止 gRPC 服务
+		srv.Shutdown(context.Background())
+		s.GracefulStop()
+	}, func() {
+		srv.Close()
+		s.Stop()
+	})
+	return nil
+}
+
+// withHTTP1 在 ALPN 中同时提供 h2 和 http/1.1，只支持 HTTP/1.1 的客户端也能访问网关
+func withHTTP1(cfg *tls.Config) *tls.Config {
+	protos := []string{"h2", "http/1.1"}
+	cfg = cfg.Clone()
+	cfg.NextProtos = protos
+	if get := cfg.GetConfigForClient; get != nil {
+		cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := get(hello)
+			if c != nil {
+				c = c.Clone()
+				c.NextProtos = protos
+			}
+			return c, err
+		}
+	}
+	return cfg
+}
+
+// inProcessAddr 进程内连接的地址，interceptor.CallerKey 据此信任 x-forwarded-for
+type inProcessAddr struct{}
+
+func (inProcessAddr) Network() string { return interceptor.InProcessNetwork }
+
+func (inProcessAddr) String() string { return "gateway" }
+
+type pipeConn struct {
+	net.Conn
+}
+
+func (pipeConn) LocalAddr() net.Addr { return inProcessAddr{} }
+
+func (pipeConn) RemoteAddr() net.Addr { return inProcessAddr{} }
+
+// pipeListener 进程内的监听器，DialContext 用 net.Pipe 建立连接
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return inProcessAddr{}
+}
+
+// DialContext 建立一条到监听器的连接，监听器关闭后返回 net.ErrClosed
+func (l *pipeListener) DialContext(ctx context.Context, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- pipeConn{server}:
+		return pipeConn{client}, nil
+	case <-l.done:
+		server.Close()
+		client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		server.Close()
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"github.com/MorseWayne/grpc-demo/internal/history"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultHistoryLimit    = 10000 // 未配置存储时内存中保留的记录数
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 1000
+	// maxHistoryPageBytes ListHistory 一页的大小上限，远低于客户端默认 4MB 的接收上限
+	maxHistoryPageBytes = 1 << 20
+	// maxRecordedBytes 记录中请求、响应各自保留的字节数，超过时截断并设置 Truncated
+	maxRecordedBytes = 1 << 10
+	// maxRecordedMessages 流式调用最多记录的请求消息数，其余只计数
+	maxRecordedMessages = 16
+	// historyStreamBuffer StreamHistory 允许积压的记录数
+	historyStreamBuffer = 256
+)
+
+// HistoryServer 计算历史查询服务
+type HistoryServer struct {
+	v1.UnimplementedHistoryServiceServer
+	journal *history.Journal
+}
+
+// ListHistory 按时间倒序分页查询
+func (server *HistoryServer) ListHistory(ctx context.Context, req *v1.ListHistoryRequest) (*v1.ListHistoryResponse, error) {
+	f, err := historyFilter(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	size := int(req.PageSize)
+	switch {
+	case size < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size[%d] < 0", size)
+	case size == 0:
+		size = defaultHistoryPageSize
+	case size > maxHistoryPageSize:
+		size = maxHistoryPageSize
+	}
+	if req.PageToken != "" {
+		if f.BeforeID, err = decodePageToken(req.PageToken); err != nil {
+			return nil, err
+		}
+	}
+	// 多取一条，用来判断是否还有下一页
+	entries, err := server.journal.List(f, size+1)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list history: %v", err)
+	}
+	resp := &v1.ListHistoryResponse{}
+	total := 0
+	for i := range entries {
+		pe := historyEntry(&entries[i])
+		total += proto.Size(pe)
+		// 条数或字节数到达上限时截止，至少返回一条
+		if i == size || (i > 0 && total > maxHistoryPageBytes) {
+			resp.NextPageToken = encodePageToken(entries[i-1].ID)
+			break
+		}
+		resp.Entries = append(resp.Entries, pe)
+	}
+	return resp, nil
+}
+
+// StreamHistory 先补发历史记录，再推送新记录
+func (server *HistoryServer) StreamHistory(req *v1.StreamHistoryRequest, stream v1.HistoryService_StreamHistoryServer) error {
+	f, err := historyFilter(req.GetFilter())
+	if err != nil {
+		return err
+	}
+	// 先订阅再补发，补发期间写入的记录留在订阅缓冲区中，按 ID 去重，不会漏掉
+	sub := server.journal.Subscribe(historyStreamBuffer)
+	defer sub.Close()
+	var last uint64
+	if req.SinceId != nil {
+		f.AfterID = *req.SinceId
+		var sendErr error
+		err := server.journal.Scan(f, func(e history.Entry) bool {
+			last = e.ID
+			sendErr = stream.Send(historyEntry(&e))
+			return sendErr == nil
+		})
+		if err == nil {
+			err = sendErr
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "replay history: %v", err)
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case e, ok := <-sub.C:
+			if !ok {
+				return status.Errorf(codes.ResourceExhausted, "%v", sub.Err())
+			}
+			if e.ID <= last || !f.Match(&e) {
+				continue
+			}
+			if err := stream.Send(historyEntry(&e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func historyFilter(pf *v1.HistoryFilter) (history.Filter, error) {
+	f := history.Filter{
+		Caller: pf.GetCaller(),
+		Method: pf.GetMethod(),
+	}
+	for _, c := range pf.GetCodes() {
+		f.Codes = append(f.Codes, codes.Code(c))
+	}
+	if pf.GetSince() != nil {
+		f.Since = pf.GetSince().AsTime()
+	}
+	if pf.GetUntil() != nil {
+		f.Until = pf.GetUntil().AsTime()
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		return f, status.Error(codes.InvalidArgument, "filter.since must be before filter.until")
+	}
+	return f, nil
+}
+
+func historyEntry(e *history.Entry) *v1.HistoryEntry {
+	return &v1.HistoryEntry{
+		Id:               e.ID,
+		Time:             timestamppb.New(e.Time),
+		Caller:           e.Caller,
+		Method:           e.Method,
+		RequestId:        e.RequestID,
+		Request:          e.Request,
+		Response:         e.Response,
+		Code:             uint32(e.Code),
+		Error:            e.Error,
+		Latency:          durationpb.New(e.Latency),
+		MessagesReceived: uint32(e.Received),
+		MessagesSent:     uint32(e.Sent),
+		Truncated:        e.Truncated,
+	}
+}
+
+// encodePageToken page token 记录上一页最后一条的 ID，下一页从它之前开始
+func encodePageToken(id uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("h1:" + strconv.FormatUint(id, 10)))
+}
+
+func decodePageToken(token string) (uint64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		if rest, ok := strings.CutPrefix(string(raw), "h1:"); ok {
+			if id, err := strconv.ParseUint(rest, 10, 64); err == nil && id > 0 {
+				return id, nil
+			}
+		}
+	}
+	return 0, status.Error(codes.InvalidArgument, "malformed page_token")
+}
+
+// recorded 只记录计算调用，健康检查、反射和历史查询本身不记录
+func recorded(method string) bool {
+	for _, name := range []string{v1.CalculatorService_ServiceDesc.ServiceName, v2.CalculatorService_ServiceDesc.ServiceName} {
+		if strings.HasPrefix(method, "/"+name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// newHistoryEntry 记录中与调用结果无关的部分
+func newHistoryEntry(ctx context.Context, method string, start time.Time) history.Entry {
+	return history.Entry{
+		Time:      start,
+		Caller:    interceptor.CallerKey(ctx),
+		Method:    method,
+		RequestID: interceptor.RequestIDFromContext(ctx),
+	}
+}
+
+func finishHistoryEntry(j *history.Journal, e history.Entry, err error) {
+	e.Latency = time.Since(e.Time)
+	if err != nil {
+		st := status.Convert(err)
+		e.Code, e.Error, e.Response = st.Code(), st.Message(), ""
+	}
+	var reqCut, respCut bool
+	e.Request, reqCut = truncate(e.Request)
+	e.Response, respCut = truncate(e.Response)
+	e.Truncated = reqCut || respCut
+	// 审计写入失败不影响调用结果
+	if err := j.Record(e); err != nil {
+		log.Printf("record history of %s: %v", e.Method, err)
+	}
+}
+
+// truncate 超过 maxRecordedBytes 时在 UTF-8 字符边界上截断
+func truncate(s string) (string, bool) {
+	if len(s) <= maxRecordedBytes {
+		return s, false
+	}
+	n := maxRecordedBytes
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n], true
+}
+
+// unaryServerHistory 记录一元调用的请求、响应、状态和耗时
+func unaryServerHistory(j *history.Journal) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !recorded(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		e := newHistoryEntry(ctx, info.FullMethod, time.Now())
+		resp, err := handler(ctx, req)
+		e.Request, e.Received = marshalJSON(req), 1
+		if err == nil {
+			e.Response, e.Sent = marshalJSON(resp), 1
+		}
+		finishHistoryEntry(j, e, err)
+		return resp, err
+	}
+}
+
+// streamServerHistory 记录流式调用：请求为前 maxRecordedMessages 条消息组成的数组，响应为最后一条消息
+func streamServerHistory(j *history.Journal) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !recorded(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		e := newHistoryEntry(ss.Context(), info.FullMethod, time.Now())
+		rs := &recordingStream{ServerStream: ss, requests: []json.RawMessage{}}
+		err := handler(srv, rs)
+		e.Received, e.Sent = rs.received, rs.sent
+		if b, jerr := json.Marshal(rs.requests); jerr == nil {
+			e.Request = string(b)
+		}
+		if rs.last != nil {
+			e.Response = marshalJSON(rs.last)
+		}
+		finishHistoryEntry(j, e, err)
+		return err
+	}
+}
+
+// recordingStream 统计收发的消息；gRPC 允许 Recv 和 Send 在不同的 goroutine 中并发，
+// 两边只各自修改自己的字段
+type recordingStream struct {
+	grpc.ServerStream
+	received, sent int
+	requests       []json.RawMessage
+	size           int // requests 的总字节数，超过 maxRecordedBytes 后不再记录
+	last           interface{}
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.received++
+	if len(s.requests) < maxRecordedMessages && s.size <= maxRecordedBytes {
+		b := marshalJSON(m)
+		s.size += len(b)
+		s.requests = append(s.requests, json.RawMessage(b))
+	}
+	return nil
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.sent++
+	s.last = m
+	return nil
+}
+
+// marshalJSON 紧凑的 JSON，无法序列化时返回 null
+func marshalJSON(m interface{}) string {
+	if pm, ok := m.(proto.Message); ok {
+		if b, err := protojson.Marshal(pm); err == nil {
+			return string(b)
+		}
+	}
+	return "null"
+}
@@ -0,0 +1,1351 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"github.com/MorseWayne/grpc-demo/internal/gateway"
+	"github.com/MorseWayne/grpc-demo/internal/history"
+	"github.com/MorseWayne/grpc-demo/internal/operation"
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"github.com/MorseWayne/grpc-demo/pkg/concurrency"
+	"github.com/MorseWayne/grpc-demo/pkg/fault"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"github.com/MorseWayne/grpc-demo/pkg/ratelimit"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// harness 通过 bufconn 在进程内运行 NewGrpcServer，不占用端口；新增 RPC 时在这里拿客户端即可
+type harness struct {
+	conn    *grpc.ClientConn
+	v1      v1.CalculatorServiceClient
+	v2      v2.CalculatorServiceClient
+	history v1.HistoryServiceClient
+}
+
+func newHarness(t *testing.T, opts ...Option) *harness {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewGrpcServer(opts...)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &harness{
+		conn:    conn,
+		v1:      v1.NewCalculatorServiceClient(conn),
+		v2:      v2.NewCalculatorServiceClient(conn),
+		history: v1.NewHistoryServiceClient(conn),
+	}
+}
+
+// testContext 每个调用都带截止时间，出问题时测试不会一直挂住
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// wantCode 检查状态码；reason 非空时同时检查 ErrorInfo.Reason
+func wantCode(t *testing.T, err error, code codes.Code, reason string) *status.Status {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != code {
+		t.Fatalf("code = %s (%v), want %s", st.Code(), err, code)
+	}
+	if reason == "" {
+		return st
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			if info.Reason != reason {
+				t.Fatalf("reason = %s, want %s", info.Reason, reason)
+			}
+			return st
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return nil
+}
+
+// fieldViolations 返回 BadRequest 中的字段名
+func fieldViolations(st *status.Status) []string {
+	var fields []string
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				fields = append(fields, v.Field)
+			}
+		}
+	}
+	return fields
+}
+
+func TestUnary(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	if r, err := h.v1.Add(ctx, &v1.AddRequest{A: 3, B: 4}); err != nil || r.Result != 7 {
+		t.Fatalf("Add = %v, %v", r, err)
+	}
+	tests := []struct {
+		name string
+		call func(context.Context, *v1.OperandsRequest, ...grpc.CallOption) (*v1.ResultResponse, error)
+		a, b int64
+		want int64
+	}{
+		{"Subtract", h.v1.Subtract, 3, 4, -1},
+		{"Multiply", h.v1.Multiply, -6, 7, -42},
+		{"Modulo", h.v1.Modulo, -7, 3, 2},
+		{"Pow", h.v1.Pow, 2, 10, 1024},
+	}
+	for _, tt := range tests {
+		if r, err := tt.call(ctx, &v1.OperandsRequest{A: tt.a, B: tt.b}); err != nil || r.Result != tt.want {
+			t.Errorf("%s(%d, %d) = %v, %v, want %d", tt.name, tt.a, tt.b, r, err, tt.want)
+		}
+	}
+	if r, err := h.v1.Divide(ctx, &v1.OperandsRequest{A: -7, B: 2}); err != nil || r.Quotient != -3 || r.Remainder != -1 {
+		t.Fatalf("Divide = %v, %v", r, err)
+	}
+	if r, err := h.v1.MultiplyBatch(ctx, &v1.BatchRequest{Items: []*v1.OperandsRequest{{A: 2, B: 3}, {A: 4, B: 5}}}); err != nil ||
+		len(r.Results) != 2 || r.Results[0] != 6 || r.Results[1] != 20 {
+		t.Fatalf("MultiplyBatch = %v, %v", r, err)
+	}
+	r, err := h.v1.Evaluate(ctx, &v1.EvaluateRequest{
+		Expression: "(x + 2) * max(3, y) ^ 2",
+		Variables:  map[string]int64{"x": 1, "y": 4},
+	})
+	if err != nil || r.Result != 48 {
+		t.Fatalf("Evaluate = %v, %v", r, err)
+	}
+	if r, err := h.v2.Divide(ctx, &v2.BinaryRequest{A: "1", B: "3", Scale: 4}); err != nil ||
+		r.Result != "1/3" || r.Decimal != "0.3333" || r.Exact {
+		t.Fatalf("v2 Divide = %v, %v", r, err)
+	}
+}
+
+func TestUnaryErrors(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	_, err := h.v1.Add(ctx, &v1.AddRequest{A: 1 << 62, B: 1 << 62})
+	st := wantCode(t, err, codes.OutOfRange, "INT64_OVERFLOW")
+	if f := fieldViolations(st); len(f) != 2 || f[0] != "a" || f[1] != "b" {
+		t.Fatalf("field violations = %v", f)
+	}
+	_, err = h.v1.Divide(ctx, &v1.OperandsRequest{A: 1, B: 0})
+	wantCode(t, err, codes.InvalidArgument, "DIVISION_BY_ZERO")
+	_, err = h.v1.Pow(ctx, &v1.OperandsRequest{A: 2, B: -1})
+	wantCode(t, err, codes.InvalidArgument, "NEGATIVE_EXPONENT")
+
+	_, err = h.v1.DivideBatch(ctx, &v1.BatchRequest{Items: []*v1.OperandsRequest{{A: 4, B: 2}, {A: 1, B: 0}}})
+	st = wantCode(t, err, codes.InvalidArgument, "DIVISION_BY_ZERO")
+	if f := fieldViolations(st); len(f) != 1 || f[0] != "items[1].b" {
+		t.Fatalf("field violations = %v", f)
+	}
+
+	_, err = h.v1.Evaluate(ctx, &v1.EvaluateRequest{Expression: "1 + * 2"})
+	wantCode(t, err, codes.InvalidArgument, "SYNTAX_ERROR")
+	_, err = h.v1.Evaluate(ctx, &v1.EvaluateRequest{Expression: "x + 1"})
+	wantCode(t, err, codes.InvalidArgument, "UNDEFINED_VARIABLE")
+
+	_, err = h.v2.Add(ctx, &v2.BinaryRequest{A: "1.2.3", B: "1"})
+	wantCode(t, err, codes.InvalidArgument, "")
+}
+
+func TestClientStream(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	sum, err := h.v1.SumStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*v1.AddRequest{{A: 1, B: 2}, {A: 3, B: 4}} {
+		if err := sum.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r, err := sum.CloseAndRecv(); err != nil || r.Result != 10 {
+		t.Fatalf("SumStream = %v, %v", r, err)
+	}
+
+	// 空流返回 0
+	sum, _ = h.v1.SumStream(ctx)
+	if r, err := sum.CloseAndRecv(); err != nil || r.Result != 0 {
+		t.Fatalf("empty SumStream = %v, %v", r, err)
+	}
+
+	// 溢出后服务端立即结束流
+	sum, _ = h.v1.SumStream(ctx)
+	_ = sum.Send(&v1.AddRequest{A: 1 << 62, B: 1 << 62})
+	_, err = sum.CloseAndRecv()
+	wantCode(t, err, codes.OutOfRange, "INT64_OVERFLOW")
+
+	st, err := h.v1.StatsStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(1); i <= 100; i++ {
+		if err := st.Send(&v1.StatsRequest{Value: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := st.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count != 100 || r.Sum != 5050 || r.Min != 1 || r.Max != 100 || r.Mean != 50.5 {
+		t.Fatalf("StatsStream = %v", r)
+	}
+	if len(r.Percentiles) != 4 {
+		t.Fatalf("percentiles = %v", r.Percentiles)
+	}
+}
+
+// recvAll 读取服务端流直到结束
+func recvAll(s grpc.ServerStreamingClient[v1.AddResponse]) ([]int64, string, error) {
+	var results []int64
+	var token string
+	for {
+		m, err := s.Recv()
+		if err == io.EOF {
+			return results, token, nil
+		}
+		if err != nil {
+			return results, token, err
+		}
+		results = append(results, m.Result)
+		token = m.ContinuationToken
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	s, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 9, Step: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := recvAll(s)
+	if err != nil || len(got) != 5 || got[0] != 1 || got[4] != 9 {
+		t.Fatalf("RangeAdd = %v, %v", got, err)
+	}
+
+	// 读两个元素后断开，用最后一个 token 续传
+	req := &v1.RangeRequest{Start: 1, End: 9, Step: 2}
+	rctx, cancel := context.WithCancel(ctx)
+	s, _ = h.v1.RangeAdd(rctx, req)
+	var token string
+	for i := 0; i < 2; i++ {
+		m, err := s.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token = m.ContinuationToken
+	}
+	cancel()
+	req.ResumeToken = token
+	s, _ = h.v1.RangeAdd(ctx, req)
+	if got, _, err = recvAll(s); err != nil || len(got) != 3 || got[0] != 5 {
+		t.Fatalf("resumed RangeAdd = %v, %v", got, err)
+	}
+}
+
+func TestServerStreamInvalidRange(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	s, _ := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 3})
+	_, done, err := recvAll(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		req  *v1.RangeRequest
+	}{
+		{"start after end", &v1.RangeRequest{Start: 5, End: 1}},
+		{"negative step", &v1.RangeRequest{Start: 1, End: 5, Step: -1}},
+		{"negative rate", &v1.RangeRequest{Start: 1, End: 5, Rate: -1}},
+		{"NaN rate", &v1.RangeRequest{Start: 1, End: 5, Rate: math.NaN()}},
+		{"infinite rate", &v1.RangeRequest{Start: 1, End: 5, Rate: math.Inf(1)}},
+		{"rate too small", &v1.RangeRequest{Start: 1, End: 5, Rate: 1e-10}},
+		{"malformed token", &v1.RangeRequest{Start: 1, End: 3, ResumeToken: "!!"}},
+		{"token for another range", &v1.RangeRequest{Start: 1, End: 4, ResumeToken: done}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := h.v1.RangeAdd(ctx, tt.req)
+			if err == nil {
+				_, _, err = recvAll(s)
+			}
+			wantCode(t, err, codes.InvalidArgument, "")
+		})
+	}
+
+	// 已经发完的 token 续传时直接结束
+	s, _ = h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 3, ResumeToken: done})
+	if got, _, err := recvAll(s); err != nil || len(got) != 0 {
+		t.Fatalf("resume finished range = %v, %v", got, err)
+	}
+}
+
+func TestBidiStream(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	chat, err := h.v1.ChatAdd(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 一问一答交替进行
+	for i := int64(0); i < 3; i++ {
+		if err := chat.Send(&v1.AddRequest{A: 10, B: i}); err != nil {
+			t.Fatal(err)
+		}
+		if m, err := chat.Recv(); err != nil || m.Result != 10+i {
+			t.Fatalf("ChatAdd recv = %v, %v", m, err)
+		}
+	}
+	if err := chat.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.Recv(); err != io.EOF {
+		t.Fatalf("after CloseSend err = %v, want EOF", err)
+	}
+
+	div, err := h.v1.ChatDivide(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = div.Send(&v1.OperandsRequest{A: 7, B: 2})
+	if m, err := div.Recv(); err != nil || m.Quotient != 3 || m.Remainder != 1 {
+		t.Fatalf("ChatDivide recv = %v, %v", m, err)
+	}
+	_ = div.Send(&v1.OperandsRequest{A: 7, B: 0})
+	_, err = div.Recv()
+	wantCode(t, err, codes.InvalidArgument, "DIVISION_BY_ZERO")
+}
+
+func TestChatAddSession(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	open := func() v1.CalculatorService_ChatAddClient {
+		t.Helper()
+		chat, err := h.v1.ChatAdd(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chat
+	}
+	send := func(chat v1.CalculatorService_ChatAddClient, req *v1.AddRequest) (*v1.AddResponse, error) {
+		_ = chat.Send(req)
+		return chat.Recv()
+	}
+	cmd := func(op v1.SessionCommand_Op, value int64) *v1.AddRequest {
+		return &v1.AddRequest{Session: &v1.SessionCommand{Op: op, Value: value}}
+	}
+
+	// 第一条会话命令隐式打开新会话；普通消息不影响累加器
+	chat := open()
+	r, err := send(chat, cmd(v1.SessionCommand_ADD, 5))
+	if err != nil || r.Result != 5 || r.Session.GetSessionId() == "" {
+		t.Fatalf("ADD = %v, %v", r, err)
+	}
+	id := r.Session.SessionId
+	if r, err := send(chat, &v1.AddRequest{A: 1, B: 2}); err != nil || r.Result != 3 || r.Session != nil {
+		t.Fatalf("plain message = %v, %v", r, err)
+	}
+	for _, step := range []struct {
+		req           *v1.AddRequest
+		value         int64
+		version, undo int
+	}{
+		{cmd(v1.SessionCommand_SUBTRACT, 8), -3, 2, 2},
+		{cmd(v1.SessionCommand_RESET, 100), 100, 3, 3},
+		{cmd(v1.SessionCommand_UNDO, 0), -3, 4, 2},
+		{cmd(v1.SessionCommand_SNAPSHOT, 0), -3, 4, 2},
+	} {
+		r, err := send(chat, step.req)
+		if err != nil || r.Result != step.value || r.Session.Version != uint64(step.version) || int(r.Session.UndoDepth) != step.undo {
+			t.Fatalf("%v = %v, %v", step.req.Session.Op, r, err)
+		}
+	}
+	_, err = send(chat, &v1.AddRequest{Session: &v1.SessionCommand{Op: v1.SessionCommand_OPEN}})
+	wantCode(t, err, codes.FailedPrecondition, "")
+
+	// 流出错结束后在新的流上恢复，状态不变
+	chat = open()
+	r, err = send(chat, &v1.AddRequest{Session: &v1.SessionCommand{Op: v1.SessionCommand_OPEN, SessionId: id}})
+	if err != nil || r.Result != -3 || r.Session.Version != 4 {
+		t.Fatalf("resume = %v, %v", r, err)
+	}
+	_, err = send(chat, cmd(v1.SessionCommand_SUBTRACT, math.MaxInt64))
+	st := wantCode(t, err, codes.OutOfRange, "INT64_OVERFLOW")
+	if fields := fieldViolations(st); len(fields) != 1 || fields[0] != "session.value" {
+		t.Fatalf("field violations = %v", fields)
+	}
+
+	chat = open()
+	_, err = send(chat, &v1.AddRequest{Session: &v1.SessionCommand{Op: v1.SessionCommand_OPEN, SessionId: "missing"}})
+	wantCode(t, err, codes.NotFound, "SESSION_NOT_FOUND")
+
+	chat = open()
+	_, _ = send(chat, cmd(v1.SessionCommand_SNAPSHOT, 0))
+	_, err = send(chat, cmd(v1.SessionCommand_UNDO, 0))
+	wantCode(t, err, codes.FailedPrecondition, "NOTHING_TO_UNDO")
+
+	chat = open()
+	_, err = send(chat, cmd(v1.SessionCommand_OP_UNSPECIFIED, 0))
+	wantCode(t, err, codes.InvalidArgument, "")
+}
+
+func TestCancellation(t *testing.T) {
+	h := newHarness(t)
+	ctx, cancel := context.WithCancel(testContext(t))
+	s, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 1000, Rate: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	_, _, err = recvAll(s)
+	wantCode(t, err, codes.Canceled, "")
+
+	chat, err := h.v1.ChatAdd(ctx)
+	if err == nil {
+		_, err = chat.Recv()
+	}
+	wantCode(t, err, codes.Canceled, "")
+}
+
+func TestDeadline(t *testing.T) {
+	h := newHarness(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 100, Rate: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := recvAll(s)
+	wantCode(t, err, codes.DeadlineExceeded, "")
+	if len(got) == 0 || len(got) > 5 {
+		t.Fatalf("received %d results before the deadline", len(got))
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	_, err = h.v1.Add(expired, &v1.AddRequest{A: 1, B: 2})
+	wantCode(t, err, codes.DeadlineExceeded, "")
+}
+
+func TestRequestID(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	// 客户端带了请求 ID 时服务端原样返回，没带时生成一个
+	var header metadata.MD
+	out := metadata.AppendToOutgoingContext(ctx, interceptor.RequestIDKey, "req-42")
+	if _, err := h.v1.Add(out, &v1.AddRequest{A: 1, B: 2}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get(interceptor.RequestIDKey); len(got) != 1 || got[0] != "req-42" {
+		t.Fatalf("request id = %v", got)
+	}
+	s, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header, err = s.Header(); err != nil || len(header.Get(interceptor.RequestIDKey)) != 1 {
+		t.Fatalf("stream request id = %v, %v", header, err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	key := []byte("integration-key")
+	h := newHarness(t, WithAuth(auth.NewVerifier(key, "grpc-demo"), DefaultPolicy()))
+	ctx := testContext(t)
+	token := func(scopes ...string) grpc.CallOption {
+		tok, err := auth.Sign(key, "grpc-demo", auth.Identity{Subject: "alice", Scopes: scopes}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return grpc.PerRPCCredentials(auth.TokenCredentials{Token: tok, AllowInsecure: true})
+	}
+
+	_, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2})
+	wantCode(t, err, codes.Unauthenticated, "")
+	if _, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}, token()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 双向流需要 streaming scope
+	chat, err := h.v1.ChatAdd(ctx, token())
+	if err == nil {
+		_, err = chat.Recv()
+	}
+	wantCode(t, err, codes.PermissionDenied, "")
+	chat, err = h.v1.ChatAdd(ctx, token("streaming"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = chat.Send(&v1.AddRequest{A: 1, B: 2})
+	if m, err := chat.Recv(); err != nil || m.Result != 3 {
+		t.Fatalf("ChatAdd = %v, %v", m, err)
+	}
+
+	// 历史记录需要 audit scope
+	_, err = h.history.ListHistory(ctx, &v1.ListHistoryRequest{}, token("streaming"))
+	wantCode(t, err, codes.PermissionDenied, "")
+	if r, err := h.history.ListHistory(ctx, &v1.ListHistoryRequest{}, token("audit")); err != nil || r.Entries[0].Caller != "sub:alice" {
+		t.Fatalf("ListHistory = %v, %v", r, err)
+	}
+
+	// 健康检查无需认证
+	if _, err := healthpb.NewHealthClient(h.conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := newHarness(t, WithRateLimit(ratelimit.Limits{Default: ratelimit.Limit{Rate: 1, Burst: 2}}))
+	ctx := testContext(t)
+	for i := 0; i < 2; i++ {
+		if _, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2})
+	wantCode(t, err, codes.ResourceExhausted, "")
+	if d, ok := interceptor.RetryDelay(err); !ok || d <= 0 || d > time.Second {
+		t.Fatalf("retry delay = %s, %v", d, ok)
+	}
+	// 每个方法单独计数
+	if _, err := h.v1.Subtract(ctx, &v1.OperandsRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	h := newHarness(t)
+	ctx := interceptor.WithIdempotencyKey(testContext(t), "order-1")
+
+	var first, replay metadata.MD
+	r1, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}, grpc.Header(&first))
+	if err != nil || r1.Result != 3 || len(first.Get(interceptor.IdempotentReplayHeader)) != 0 {
+		t.Fatalf("first Add = %v, %v, header %v", r1, err, first)
+	}
+	r2, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}, grpc.Header(&replay))
+	if err != nil || r2.Result != 3 || replay.Get(interceptor.IdempotentReplayHeader)[0] != "true" {
+		t.Fatalf("replayed Add = %v, %v, header %v", r2, err, replay)
+	}
+	// 重放不会再次执行，历史中只有一条
+	if r, err := h.history.ListHistory(testContext(t), &v1.ListHistoryRequest{}); err != nil || len(r.Entries) != 1 {
+		t.Fatalf("history = %v, %v", r, err)
+	}
+
+	_, err = h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 5})
+	wantCode(t, err, codes.InvalidArgument, "IDEMPOTENCY_KEY_REUSED")
+	_, err = h.v1.Subtract(ctx, &v1.OperandsRequest{A: 1, B: 2})
+	wantCode(t, err, codes.InvalidArgument, "IDEMPOTENCY_KEY_REUSED")
+
+	// 确定的错误同样被保存
+	div := interceptor.WithIdempotencyKey(testContext(t), "div-1")
+	for i := 0; i < 2; i++ {
+		_, err = h.v1.Divide(div, &v1.OperandsRequest{A: 1, B: 0})
+		wantCode(t, err, codes.InvalidArgument, "DIVISION_BY_ZERO")
+	}
+	if r, _ := h.history.ListHistory(testContext(t), &v1.ListHistoryRequest{}); len(r.Entries) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(r.Entries))
+	}
+
+	// 关闭后忽略 key
+	off := newHarness(t, WithIdempotencyTTL(0))
+	for i := 0; i < 2; i++ {
+		if _, err := off.v1.Add(ctx, &v1.AddRequest{A: 1, B: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := newHarness(t, WithMetrics(reg))
+	ctx := testContext(t)
+
+	if _, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := h.v1.Divide(ctx, &v1.OperandsRequest{A: 1, B: 0})
+	wantCode(t, err, codes.InvalidArgument, "DIVISION_BY_ZERO")
+	s, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 3, Step: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := recvAll(s); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `
+# HELP grpc_server_handled_total Total number of RPCs completed on the server, regardless of success or failure.
+# TYPE grpc_server_handled_total counter
+grpc_server_handled_total{grpc_code="InvalidArgument",grpc_method="Divide",grpc_service="calculator.v1.CalculatorService",grpc_type="unary"} 1
+grpc_server_handled_total{grpc_code="OK",grpc_method="Add",grpc_service="calculator.v1.CalculatorService",grpc_type="unary"} 1
+grpc_server_handled_total{grpc_code="OK",grpc_method="RangeAdd",grpc_service="calculator.v1.CalculatorService",grpc_type="server_stream"} 1
+# HELP grpc_server_msg_sent_total Total number of stream messages sent on the server.
+# TYPE grpc_server_msg_sent_total counter
+grpc_server_msg_sent_total{grpc_method="Add",grpc_service="calculator.v1.CalculatorService",grpc_type="unary"} 1
+grpc_server_msg_sent_total{grpc_method="Divide",grpc_service="calculator.v1.CalculatorService",grpc_type="unary"} 0
+grpc_server_msg_sent_total{grpc_method="RangeAdd",grpc_service="calculator.v1.CalculatorService",grpc_type="server_stream"} 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "grpc_server_handled_total", "grpc_server_msg_sent_total"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := testutil.GatherAndCount(reg, "grpc_server_in_flight"); err != nil || n != 3 {
+		t.Fatalf("in flight series = %d, %v", n, err)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := newHarness(t, WithConcurrencyLimit(concurrency.Config{Initial: 1, Min: 1, Max: 1}), WithMetrics(reg))
+	ctx := testContext(t)
+
+	// 一个慢速的流占满唯一的名额
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := h.v1.RangeAdd(streamCtx, &v1.RangeRequest{Start: 1, End: 1000, Rate: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2})
+	wantCode(t, err, codes.Unavailable, interceptor.OverloadedReason)
+	// 健康检查不受并发上限约束
+	if _, err := healthpb.NewHealthClient(h.conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+# HELP grpc_server_concurrency_limit Current adaptive limit of concurrent RPCs on the server.
+# TYPE grpc_server_concurrency_limit gauge
+grpc_server_concurrency_limit 1
+# HELP grpc_server_concurrency_shed_total Total number of RPCs rejected because the concurrency limit was reached.
+# TYPE grpc_server_concurrency_shed_total counter
+grpc_server_concurrency_shed_total 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "grpc_server_concurrency_limit", "grpc_server_concurrency_shed_total"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 流结束后名额释放，服务端处理函数退出需要一点时间
+	cancel()
+	for i := 0; ; i++ {
+		_, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2})
+		if err == nil {
+			break
+		}
+		if status.Code(err) != codes.Unavailable || i == 100 {
+			t.Fatalf("Add after the stream ended = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exp := tracing.NewMemoryExporter()
+	h := newHarness(t, WithTracer(tracing.NewTracer("grpc-demo", exp)))
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := metadata.AppendToOutgoingContext(testContext(t), tracing.TraceparentHeader, parent)
+
+	if _, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 3, Step: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := recvAll(s); err != nil {
+		t.Fatal(err)
+	}
+	// 没有 traceparent 的调用开启新的调用链
+	if _, err := h.v1.Add(testContext(t), &v1.AddRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 流的 span 在服务端处理函数返回后才结束，可能晚于客户端读到 EOF
+	deadline := time.Now().Add(time.Second)
+	for len(exp.Spans()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	linked := map[string]int{}
+	for _, span := range exp.Spans() {
+		if span.TraceID.String() == "4bf92f3577b34da6a3ce929d0e0e4736" && span.ParentSpanID.String() == "00f067aa0ba902b7" && span.Kind == tracing.KindServer {
+			linked[span.Name]++
+		}
+	}
+	if linked["/calculator.v1.CalculatorService/Add"] != 1 || linked["/calculator.v1.CalculatorService/RangeAdd"] != 1 {
+		t.Errorf("spans continuing the caller's trace: %v", linked)
+	}
+	if spans := exp.Spans(); len(spans) != 3 {
+		t.Errorf("exported %d spans, want 3", len(spans))
+	}
+}
+
+func TestHealthAndReflection(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	for _, name := range []string{"", v1.CalculatorService_ServiceDesc.ServiceName, v2.CalculatorService_ServiceDesc.ServiceName} {
+		r, err := healthpb.NewHealthClient(h.conn).Check(ctx, &healthpb.HealthCheckRequest{Service: name})
+		if err != nil || r.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("health %q = %v, %v", name, r.GetStatus(), err)
+		}
+	}
+
+	rs, err := reflectionpb.NewServerReflectionClient(h.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rs.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := rs.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := map[string]bool{}
+	for _, s := range r.GetListServicesResponse().GetService() {
+		services[s.Name] = true
+	}
+	for _, want := range []string{v1.CalculatorService_ServiceDesc.ServiceName, v2.CalculatorService_ServiceDesc.ServiceName} {
+		if !services[want] {
+			t.Errorf("reflection does not list %s: %v", want, services)
+		}
+	}
+	if err := rs.CloseSend(); err != nil && !errors.Is(err, io.EOF) {
+		t.Fatal(err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	out := metadata.AppendToOutgoingContext(ctx, interceptor.RequestIDKey, "req-1")
+	if _, err := h.v1.Add(out, &v1.AddRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = h.v1.Divide(ctx, &v1.OperandsRequest{A: 1, B: 0})
+	sum, _ := h.v1.SumStream(ctx)
+	for i := int64(0); i < maxRecordedMessages+4; i++ {
+		_ = sum.Send(&v1.AddRequest{A: i, B: 1})
+	}
+	if _, err := sum.CloseAndRecv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 倒序分页，每页两条
+	r, err := h.history.ListHistory(ctx, &v1.ListHistoryRequest{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Entries) != 2 || r.NextPageToken == "" {
+		t.Fatalf("first page = %v", r)
+	}
+	stream, div := r.Entries[0], r.Entries[1]
+	if stream.Method != v1.CalculatorService_SumStream_FullMethodName || stream.MessagesReceived != maxRecordedMessages+4 ||
+		stream.MessagesSent != 1 || stream.Response == "" {
+		t.Fatalf("stream entry = %v", stream)
+	}
+	if div.Code != uint32(codes.InvalidArgument) || div.Error == "" || div.Response != "" {
+		t.Fatalf("divide entry = %v", div)
+	}
+	r, err = h.history.ListHistory(ctx, &v1.ListHistoryRequest{PageSize: 2, PageToken: r.NextPageToken})
+	if err != nil || len(r.Entries) != 1 || r.NextPageToken != "" {
+		t.Fatalf("second page = %v, %v", r, err)
+	}
+	add := r.Entries[0]
+	if add.Id != 1 || add.RequestId != "req-1" || add.Caller != "ip:bufconn" || add.Request == "" || add.Latency == nil {
+		t.Fatalf("add entry = %v", add)
+	}
+
+	r, err = h.history.ListHistory(ctx, &v1.ListHistoryRequest{Filter: &v1.HistoryFilter{Codes: []uint32{uint32(codes.InvalidArgument)}}})
+	if err != nil || len(r.Entries) != 1 || r.Entries[0].Method != v1.CalculatorService_Divide_FullMethodName {
+		t.Fatalf("filter by code = %v, %v", r, err)
+	}
+	_, err = h.history.ListHistory(ctx, &v1.ListHistoryRequest{PageToken: "bogus"})
+	wantCode(t, err, codes.InvalidArgument, "")
+
+	// 从头补发，再实时收到新的调用；查询历史本身不会被记录
+	since := uint64(0)
+	tail, err := h.history.StreamHistory(ctx, &v1.StreamHistoryRequest{
+		Filter:  &v1.HistoryFilter{Method: "Add"},
+		SinceId: &since,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := tail.Recv(); err != nil || e.Id != 1 {
+		t.Fatalf("replayed = %v, %v", e, err)
+	}
+	if _, err := h.v1.Subtract(ctx, &v1.OperandsRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.v1.Add(ctx, &v1.AddRequest{A: 5, B: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := tail.Recv(); err != nil || e.Id != 5 || e.Method != v1.CalculatorService_Add_FullMethodName {
+		t.Fatalf("live = %v, %v", e, err)
+	}
+
+	// 过长的请求被截断
+	if _, err := h.v2.Add(ctx, &v2.BinaryRequest{A: strings.Repeat("9", 2*maxRecordedBytes), B: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	r, err = h.history.ListHistory(ctx, &v1.ListHistoryRequest{PageSize: 1})
+	if err != nil || !r.Entries[0].Truncated || len(r.Entries[0].Request) > maxRecordedBytes {
+		t.Fatalf("long request = %v, %v", r, err)
+	}
+}
+
+func TestListHistoryPageBytes(t *testing.T) {
+	store := history.NewMemoryStore(0)
+	payload := strings.Repeat("x", maxRecordedBytes)
+	for range maxHistoryPageSize {
+		if err := store.Append(&history.Entry{Method: "/calculator.v1.CalculatorService/Add", Request: payload, Response: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := newHarness(t, WithHistory(store))
+	ctx := testContext(t)
+
+	r, err := h.history.ListHistory(ctx, &v1.ListHistoryRequest{PageSize: maxHistoryPageSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(r.Entries)
+	if n == 0 || n == maxHistoryPageSize || proto.Size(r) > maxHistoryPageBytes || r.NextPageToken == "" {
+		t.Fatalf("first page: %d entries, %d bytes, next = %q", n, proto.Size(r), r.NextPageToken)
+	}
+	r, err = h.history.ListHistory(ctx, &v1.ListHistoryRequest{PageSize: maxHistoryPageSize, PageToken: r.NextPageToken})
+	if err != nil || len(r.Entries) == 0 || r.Entries[0].Id != uint64(maxHistoryPageSize-n) {
+		t.Fatalf("second page = %d entries, %v", len(r.Entries), err)
+	}
+}
+
+func TestHistoryTailOutlivesTimeout(t *testing.T) {
+	h := newHarness(t, WithDefaultTimeout(50*time.Millisecond))
+	ctx := testContext(t)
+
+	tail, err := h.history.StreamHistory(ctx, &v1.StreamHistoryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := tail.Recv(); err != nil || e.Method != v1.CalculatorService_Add_FullMethodName {
+		t.Fatalf("entry after the default timeout = %v, %v", e, err)
+	}
+}
+
+// gatewayDo 发送 HTTP 请求，返回状态码、响应头和去掉空格的响应体（protojson 的输出会随机插入空格）
+func gatewayDo(t *testing.T, method, url string, header http.Header, body string) (int, http.Header, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(testContext(t), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, strings.ReplaceAll(string(b), " ", "")
+}
+
+func TestGateway(t *testing.T) {
+	h := newHarness(t, WithRateLimit(ratelimit.Limits{
+		PerMethod: map[string]ratelimit.Limit{v1.CalculatorService_Pow_FullMethodName: {Rate: 0.1, Burst: 1}},
+	}))
+	srv := httptest.NewServer(gateway.New(h.conn))
+	defer srv.Close()
+	get := func(path string, header http.Header) (int, http.Header, string) {
+		return gatewayDo(t, http.MethodGet, srv.URL+path, header, "")
+	}
+	post := func(path, body string) (int, string) {
+		code, _, b := gatewayDo(t, http.MethodPost, srv.URL+path, nil, body)
+		return code, b
+	}
+
+	code, header, body := gatewayDo(t, http.MethodPost, srv.URL+"/v1/add", nil, `{"a": 2, "b": 3}`)
+	if code != http.StatusOK || header.Get("Grpc-Metadata-X-Request-Id") == "" || !strings.Contains(body, `"result":"5"`) {
+		t.Fatalf("POST /v1/add = %d %s %v", code, body, header)
+	}
+	if code, _, body := get("/v1/multiply?a=4&b=5", nil); code != http.StatusOK || !strings.Contains(body, `"result":"20"`) {
+		t.Fatalf("GET /v1/multiply = %d %s", code, body)
+	}
+
+	// 错误返回 google.rpc.Status，细节原样保留
+	code, _, body = get("/v1/divide?a=1&b=0", nil)
+	if code != http.StatusBadRequest || !strings.Contains(body, `"code":3`) || !strings.Contains(body, "BadRequest") {
+		t.Fatalf("divide by zero = %d %s", code, body)
+	}
+	if code, _, body := get("/v1/add?c=1", nil); code != http.StatusBadRequest {
+		t.Fatalf("unknown parameter = %d %s", code, body)
+	}
+	if code, _, body := get("/v1/nope", nil); code != http.StatusNotFound {
+		t.Fatalf("unknown route = %d %s", code, body)
+	}
+	get("/v1/pow?a=2&b=3", nil)
+	if code, header, body := get("/v1/pow?a=2&b=3", nil); code != http.StatusTooManyRequests || header.Get("Retry-After") == "" {
+		t.Fatalf("rate limited = %d %s %v", code, body, header)
+	}
+
+	// 服务端流：默认 NDJSON，Accept: text/event-stream 时为 SSE
+	code, header, body = get("/v1/range?start=1&end=3", nil)
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if code != http.StatusOK || header.Get("Content-Type") != "application/x-ndjson" || len(lines) != 3 ||
+		!strings.HasPrefix(lines[2], `{"result":{"result":"3"`) {
+		t.Fatalf("range = %d %v %q", code, header, lines)
+	}
+	code, header, body = get("/v1/range?start=1&end=2", http.Header{"Accept": {"text/event-stream"}})
+	if code != http.StatusOK || header.Get("Content-Type") != "text/event-stream" || strings.Count(body, "data:") != 2 {
+		t.Fatalf("range SSE = %d %v %q", code, header, body)
+	}
+	if code, _, body := get("/v1/range?start=3&end=1", nil); code != http.StatusBadRequest {
+		t.Fatalf("invalid range = %d %s", code, body)
+	}
+
+	// 客户端流：NDJSON 或 JSON 数组
+	if code, body := post("/v1/sum", "{\"a\":1,\"b\":2}\n{\"a\":3,\"b\":4}\n"); code != http.StatusOK || !strings.Contains(body, `"result":"10"`) {
+		t.Fatalf("sum = %d %s", code, body)
+	}
+	if code, body := post("/v1/stats", `[{"value":1},{"value":"x"}]`); code != http.StatusBadRequest {
+		t.Fatalf("stats with a bad message = %d %s", code, body)
+	}
+	big := `{"value":1,"a":"` + strings.Repeat("x", 2<<20) + `"}`
+	if code, body := post("/v1/stats", `[{"value":1},`+big+`]`); code != http.StatusBadRequest || !strings.Contains(body, "exceedsthelimit") {
+		t.Fatalf("stats with an oversized message = %d %s", code, body)
+	}
+
+	// 经网关的调用和 gRPC 调用记在同一份历史里
+	code, _, body = get("/v1/history?filter.method=Add&filter.codes=0", nil)
+	if code != http.StatusOK || strings.Count(body, `"method":"/calculator.v1.CalculatorService/Add"`) != 1 {
+		t.Fatalf("history = %d %s", code, body)
+	}
+}
+
+// webFrame gRPC-Web 和 Connect 流式协议的一帧
+type webFrame struct {
+	flags byte
+	data  []byte
+}
+
+func encodeFrames(frames ...webFrame) []byte {
+	var b []byte
+	for _, f := range frames {
+		b = append(b, f.flags)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(f.data)))
+		b = append(b, f.data...)
+	}
+	return b
+}
+
+func decodeFrames(t *testing.T, b []byte) []webFrame {
+	t.Helper()
+	var frames []webFrame
+	for len(b) > 0 {
+		if len(b) < 5 {
+			t.Fatalf("truncated frame header %q", b)
+		}
+		n := int(binary.BigEndian.Uint32(b[1:5]))
+		if len(b) < 5+n {
+			t.Fatalf("truncated frame %q", b)
+		}
+		frames = append(frames, webFrame{flags: b[0], data: b[5 : 5+n]})
+		b = b[5+n:]
+	}
+	return frames
+}
+
+func marshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// webPost 用 client 发送 POST 请求，返回状态码、响应头和原始响应体
+func webPost(t *testing.T, client *http.Client, url, contentType string, header http.Header, body []byte) (int, http.Header, []byte) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(testContext(t), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, b
+}
+
+func TestWeb(t *testing.T) {
+	h := newHarness(t)
+	srv := httptest.NewServer(gateway.CORS([]string{"https://app.example"}, gateway.NewWeb(h.conn)))
+	defer srv.Close()
+	add := srv.URL + "/" + v1.CalculatorService_ServiceDesc.ServiceName + "/Add"
+	divide := srv.URL + "/" + v1.CalculatorService_ServiceDesc.ServiceName + "/Divide"
+	rangeAdd := srv.URL + "/" + v1.CalculatorService_ServiceDesc.ServiceName + "/RangeAdd"
+	client := srv.Client()
+
+	// gRPC-Web：消息和 trailer 都在响应体的帧里
+	code, header, body := webPost(t, client, add, "application/grpc-web+proto", http.Header{"X-Request-Id": {"web-1"}},
+		encodeFrames(webFrame{data: marshal(t, &v1.AddRequest{A: 2, B: 3})}))
+	frames := decodeFrames(t, body)
+	var resp v1.AddResponse
+	if code != http.StatusOK || header.Get("X-Request-Id") != "web-1" || len(frames) != 2 || frames[1].flags != 0x80 {
+		t.Fatalf("grpc-web add = %d %v %q", code, header, body)
+	}
+	if err := proto.Unmarshal(frames[0].data, &resp); err != nil || resp.Result != 5 || !strings.Contains(string(frames[1].data), "grpc-status: 0\r\n") {
+		t.Fatalf("grpc-web add = %v %q, %v", &resp, frames[1].data, err)
+	}
+	_, _, body = webPost(t, client, divide, "application/grpc-web+proto", nil,
+		encodeFrames(webFrame{data: marshal(t, &v1.OperandsRequest{A: 1, B: 0})}))
+	frames = decodeFrames(t, body)
+	if len(frames) != 1 || !strings.Contains(string(frames[0].data), "grpc-status: 3\r\n") || !strings.Contains(string(frames[0].data), "grpc-status-details-bin: ") {
+		t.Fatalf("grpc-web divide by zero = %q", body)
+	}
+	_, _, body = webPost(t, client, rangeAdd, "application/grpc-web+proto", nil,
+		encodeFrames(webFrame{data: marshal(t, &v1.RangeRequest{Start: 1, End: 3})}))
+	if frames = decodeFrames(t, body); len(frames) != 4 || !strings.Contains(string(frames[3].data), "grpc-status: 0\r\n") {
+		t.Fatalf("grpc-web range = %q", body)
+	}
+	code, _, body = webPost(t, client, add, "application/grpc-web-text", nil,
+		[]byte(base64.StdEncoding.EncodeToString(encodeFrames(webFrame{data: marshal(t, &v1.AddRequest{A: 4, B: 5})}))))
+	// 每帧单独编码，按 4 个字符一组解码
+	var raw []byte
+	for i := 0; i+4 <= len(body); i += 4 {
+		q, err := base64.StdEncoding.DecodeString(string(body[i : i+4]))
+		if err != nil {
+			t.Fatalf("grpc-web-text body %q: %v", body, err)
+		}
+		raw = append(raw, q...)
+	}
+	if frames = decodeFrames(t, raw); code != http.StatusOK || len(frames) != 2 || proto.Unmarshal(frames[0].data, &resp) != nil || resp.Result != 9 {
+		t.Fatalf("grpc-web-text add = %d %q", code, raw)
+	}
+	_, _, body = webPost(t, client, srv.URL+"/calculator.v1.CalculatorService/Nope", "application/grpc-web+proto", nil,
+		encodeFrames(webFrame{data: nil}))
+	if !strings.Contains(string(body), "grpc-status: 12\r\n") {
+		t.Fatalf("unknown method = %q", body)
+	}
+
+	// Connect 一元调用：响应体就是消息，错误对应 HTTP 状态码
+	code, header, body = webPost(t, client, add, "application/json", http.Header{"Connect-Protocol-Version": {"1"}}, []byte(`{"a":2,"b":3}`))
+	if code != http.StatusOK || header.Get("Content-Type") != "application/json" || !strings.Contains(strings.ReplaceAll(string(body), " ", ""), `"result":"5"`) {
+		t.Fatalf("connect add = %d %v %s", code, header, body)
+	}
+	code, _, body = webPost(t, client, add, "application/proto", nil, marshal(t, &v1.AddRequest{A: 2, B: 3}))
+	if err := proto.Unmarshal(body, &resp); code != http.StatusOK || err != nil || resp.Result != 5 {
+		t.Fatalf("connect proto add = %d %v, %v", code, &resp, err)
+	}
+	code, _, body = webPost(t, client, divide, "application/json", nil, []byte(`{"a":1,"b":0}`))
+	var cerr struct {
+		Code    string
+		Details []struct{ Type string }
+	}
+	if err := json.Unmarshal(body, &cerr); code != http.StatusBadRequest || err != nil || cerr.Code != "invalid_argument" ||
+		len(cerr.Details) == 0 || cerr.Details[0].Type != "google.rpc.BadRequest" {
+		t.Fatalf("connect divide by zero = %d %s", code, body)
+	}
+	if code, _, body := webPost(t, client, add, "application/json", nil, []byte(`{"c":1}`)); code != http.StatusBadRequest {
+		t.Fatalf("connect unknown field = %d %s", code, body)
+	}
+	if code, _, body := webPost(t, client, rangeAdd, "application/json", nil, []byte(`{"end":3}`)); code != http.StatusBadRequest {
+		t.Fatalf("connect unary call of a streaming method = %d %s", code, body)
+	}
+
+	// Connect 服务端流：最后一帧的标志位为 0x02，内容为状态和 trailer
+	code, _, body = webPost(t, client, rangeAdd, "application/connect+json", nil, encodeFrames(webFrame{data: []byte(`{"start":1,"end":3}`)}))
+	if frames = decodeFrames(t, body); code != http.StatusOK || len(frames) != 4 || frames[3].flags != 0x02 || string(frames[3].data) != "{}" {
+		t.Fatalf("connect range = %d %q", code, body)
+	}
+	_, _, body = webPost(t, client, rangeAdd, "application/connect+json", nil, encodeFrames(webFrame{data: []byte(`{"start":3,"end":1}`)}))
+	if frames = decodeFrames(t, body); len(frames) != 1 || !strings.Contains(string(frames[0].data), `"code":"invalid_argument"`) {
+		t.Fatalf("connect invalid range = %q", body)
+	}
+
+	// 客户端流在 HTTP/1.1 上也可以用，请求体发完后才收到响应
+	sum := srv.URL + "/" + v1.CalculatorService_ServiceDesc.ServiceName + "/SumStream"
+	_, _, body = webPost(t, client, sum, "application/connect+proto", nil,
+		encodeFrames(webFrame{data: marshal(t, &v1.AddRequest{A: 1, B: 2})}, webFrame{data: marshal(t, &v1.AddRequest{A: 3, B: 4})}))
+	if frames = decodeFrames(t, body); len(frames) != 2 || proto.Unmarshal(frames[0].data, &resp) != nil || resp.Result != 10 {
+		t.Fatalf("connect sum = %q", body)
+	}
+
+	// CORS
+	req, _ := http.NewRequest(http.MethodOptions, add, nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,authorization")
+	r, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusNoContent || r.Header.Get("Access-Control-Allow-Origin") != "https://app.example" ||
+		r.Header.Get("Access-Control-Allow-Headers") != "content-type,x-grpc-web,authorization" {
+		t.Fatalf("preflight = %d %v", r.StatusCode, r.Header)
+	}
+	req.Header.Set("Origin", "https://evil.example")
+	if r, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusForbidden || r.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("preflight from another origin = %d %v", r.StatusCode, r.Header)
+	}
+	_, header, _ = webPost(t, client, add, "application/json", http.Header{"Origin": {"https://app.example"}}, []byte(`{}`))
+	if header.Get("Access-Control-Allow-Origin") != "https://app.example" || !strings.Contains(header.Get("Access-Control-Expose-Headers"), "Grpc-Status") {
+		t.Fatalf("CORS headers = %v", header)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	inj, err := fault.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHarness(t, WithFaults(inj))
+	ctx := testContext(t)
+	faults := v1.NewFaultServiceClient(h.conn)
+
+	_, err = faults.SetFaults(ctx, &v1.FaultConfig{Rules: []*v1.FaultRule{{Method: "Add"}, {Method: "Add", AbortPercent: 200}}})
+	if st := wantCode(t, err, codes.InvalidArgument, ""); !reflect.DeepEqual(fieldViolations(st), []string{"rules[1]"}) {
+		t.Fatalf("violations = %v", fieldViolations(st))
+	}
+	cfg, err := faults.SetFaults(ctx, &v1.FaultConfig{Rules: []*v1.FaultRule{
+		{Method: "Add", AbortCode: uint32(codes.Unavailable)},
+		{Method: "RangeAdd", CutAfter: 2},
+		{Method: "*", Delay: durationpb.New(time.Second), DelayPercent: 100},
+	}})
+	if err != nil || len(cfg.Rules) != 3 {
+		t.Fatalf("SetFaults = %v, %v", cfg, err)
+	}
+
+	_, err = h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2})
+	wantCode(t, err, codes.Unavailable, interceptor.FaultInjectedReason)
+
+	// 流在收发两条消息后中断：收到一条请求、发出一条响应
+	stream, err := h.v1.RangeAdd(ctx, &v1.RangeRequest{Start: 1, End: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := recvAll(stream)
+	if len(got) != 1 {
+		t.Fatalf("received %v before the stream was cut", got)
+	}
+	wantCode(t, err, codes.Unavailable, interceptor.FaultInjectedReason)
+
+	// 注入的延迟计入调用方的 deadline；管理接口不受 * 规则影响
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = h.v1.Multiply(short, &v1.OperandsRequest{A: 2, B: 3})
+	wantCode(t, err, codes.DeadlineExceeded, "")
+	if cfg, err := faults.GetFaults(ctx, &v1.GetFaultsRequest{}); err != nil || cfg.Rules[2].Delay.AsDuration() != time.Second {
+		t.Fatalf("GetFaults = %v, %v", cfg, err)
+	}
+
+	if _, err := faults.SetFaults(ctx, &v1.FaultConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := h.v1.Add(ctx, &v1.AddRequest{A: 1, B: 2}); err != nil || r.Result != 3 {
+		t.Fatalf("Add after clearing = %v, %v", r, err)
+	}
+
+	// 没有开启故障注入时不注册管理接口
+	_, err = v1.NewFaultServiceClient(newHarness(t).conn).GetFaults(ctx, &v1.GetFaultsRequest{})
+	wantCode(t, err, codes.Unimplemented, "")
+}
+
+func TestOperations(t *testing.T) {
+	store := operation.NewStore(0, 0, 0, 0)
+	t.Cleanup(store.Close)
+	h := newHarness(t, WithOperations(store))
+	ops := v1.NewOperationServiceClient(h.conn)
+	ctx := testContext(t)
+
+	primes, err := ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_CountPrimes{CountPrimes: &v1.CountPrimesJob{Below: 1000}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pow, err := ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_Pow{Pow: &v1.PowJob{A: "-2/3", B: "-3"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_CountPrimes{CountPrimes: &v1.CountPrimesJob{Below: 200000}}})
+	if err != nil || long.State != v1.Operation_RUNNING {
+		t.Fatalf("StartOperation = %v, %v", long, err)
+	}
+
+	// 换一个连接（这里是共用 store 的另一个服务端）仍然可以取回结果
+	h2 := newHarness(t, WithOperations(store))
+	h.conn.Close()
+	ops = v1.NewOperationServiceClient(h2.conn)
+	op, err := ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: primes.Id})
+	if err != nil || op.State != v1.Operation_SUCCEEDED || op.GetResult().GetPrimeCount() != 168 || op.Progress != 100 {
+		t.Fatalf("count primes = %v, %v", op, err)
+	}
+	op, err = ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: pow.Id})
+	if err != nil || op.GetResult().GetPow() != "-27/8" {
+		t.Fatalf("pow = %v, %v", op, err)
+	}
+
+	// 等待超时时返回当前状态
+	op, err = ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: long.Id, Timeout: durationpb.New(50 * time.Millisecond)})
+	if err != nil || op.State != v1.Operation_RUNNING {
+		t.Fatalf("wait with timeout = %v, %v", op, err)
+	}
+	if _, err := ops.CancelOperation(ctx, &v1.CancelOperationRequest{Id: long.Id}); err != nil {
+		t.Fatal(err)
+	}
+	op, err = ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: long.Id})
+	if err != nil || op.State != v1.Operation_CANCELLED || op.GetError().GetCode() != uint32(codes.Canceled) {
+		t.Fatalf("cancelled = %v, %v", op, err)
+	}
+
+	list, err := ops.ListOperations(ctx, &v1.ListOperationsRequest{})
+	if err != nil || len(list.Operations) != 3 {
+		t.Fatalf("ListOperations = %v, %v", list, err)
+	}
+
+	// 结果过大的任务以 FAILED 结束，参数错误在启动时就返回
+	huge, err := ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_Pow{Pow: &v1.PowJob{A: "2", B: "99999999999"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err = ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: huge.Id})
+	if err != nil || op.State != v1.Operation_FAILED || op.GetError().GetCode() != uint32(codes.OutOfRange) {
+		t.Fatalf("huge pow = %v, %v", op, err)
+	}
+	// 同步的 Pow 放不下的结果可以在后台计算
+	large, err := ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_Pow{Pow: &v1.PowJob{A: "3", B: "1000000"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err = ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: large.Id})
+	if err != nil || op.State != v1.Operation_SUCCEEDED || len(op.GetResult().GetPow()) != 477122 {
+		t.Fatalf("large pow = %v, %d digits, %v", op.GetState(), len(op.GetResult().GetPow()), err)
+	}
+	_, err = ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_CountPrimes{CountPrimes: &v1.CountPrimesJob{Below: 200001}}})
+	if st := wantCode(t, err, codes.InvalidArgument, ""); !reflect.DeepEqual(fieldViolations(st), []string{"count_primes.below"}) {
+		t.Fatalf("violations = %v", fieldViolations(st))
+	}
+	_, err = ops.StartOperation(ctx, &v1.StartOperationRequest{})
+	wantCode(t, err, codes.InvalidArgument, "")
+	_, err = ops.GetOperation(ctx, &v1.GetOperationRequest{Id: "missing"})
+	wantCode(t, err, codes.NotFound, "OPERATION_NOT_FOUND")
+}
+
+func TestListOperationsLargeResults(t *testing.T) {
+	h := newHarness(t)
+	// 格式化几百万位的结果需要几秒
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ops := v1.NewOperationServiceClient(h.conn)
+
+	// 两个接近 MaxOperationResultBits 的结果加起来超过客户端默认 4MB 的接收上限
+	var ids []string
+	for range 2 {
+		op, err := ops.StartOperation(ctx, &v1.StartOperationRequest{Job: &v1.StartOperationRequest_Pow{Pow: &v1.PowJob{A: "255", B: "932067"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, op.Id)
+	}
+	for _, id := range ids {
+		op, err := ops.WaitOperation(ctx, &v1.WaitOperationRequest{Id: id})
+		if err != nil || op.State != v1.Operation_SUCCEEDED || len(op.GetResult().GetPow()) < 2<<20 {
+			t.Fatalf("pow = %v, %d digits, %v", op.GetState(), len(op.GetResult().GetPow()), err)
+		}
+	}
+	list, err := ops.ListOperations(ctx, &v1.ListOperationsRequest{})
+	if err != nil || len(list.Operations) != 2 {
+		t.Fatalf("ListOperations = %d operations, %v", len(list.GetOperations()), err)
+	}
+	for _, op := range list.Operations {
+		if op.State != v1.Operation_SUCCEEDED || op.GetResult() != nil {
+			t.Fatalf("listed operation = %v with result %t", op.State, op.GetResult() != nil)
+		}
+	}
+	if op, err := ops.GetOperation(ctx, &v1.GetOperationRequest{Id: ids[0]}); err != nil || op.GetResult().GetPow() == "" {
+		t.Fatalf("GetOperation = %v, %v", op.GetState(), err)
+	}
+}
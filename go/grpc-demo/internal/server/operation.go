@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/bigcalc"
+	"github.com/MorseWayne/grpc-demo/internal/operation"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"minitest/goroutine"
+)
+
+const (
+	// maxPrimesBelow 并发素数筛每个素数占用一个 goroutine，耗时随上限平方增长，200000 约需一分钟
+	maxPrimesBelow = 200000
+	// defaultWaitTimeout、maxWaitTimeout WaitOperation 的默认和最长等待时间
+	defaultWaitTimeout = 10 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+)
+
+// OperationServer 长时间运行的计算任务
+type OperationServer struct {
+	v1.UnimplementedOperationServiceServer
+	store *operation.Store
+}
+
+// StartOperation 校验参数后在后台启动任务
+func (server *OperationServer) StartOperation(ctx context.Context, req *v1.StartOperationRequest) (*v1.Operation, error) {
+	var kind string
+	var fn operation.Func
+	switch job := req.GetJob().(type) {
+	case *v1.StartOperationRequest_CountPrimes:
+		below := job.CountPrimes.GetBelow()
+		if below < 0 || below > maxPrimesBelow {
+			return nil, withDetails(status.Newf(codes.InvalidArgument, "below[%d] must be between 0 and %d", below, maxPrimesBelow),
+				&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+					{Field: "count_primes.below", Description: fmt.Sprintf("must be between 0 and %d", maxPrimesBelow)},
+				}},
+			)
+		}
+		kind, fn = "count_primes", countPrimes(int(below))
+	case *v1.StartOperationRequest_Pow:
+		a, err := bigcalc.Parse("a", job.Pow.GetA())
+		if err != nil {
+			return nil, bigcalcError(err)
+		}
+		b, err := bigcalc.Parse("b", job.Pow.GetB())
+		if err != nil {
+			return nil, bigcalcError(err)
+		}
+		kind = "pow"
+		// 结果可能有上百万位，在后台格式化一次，查询时不再重复转换
+		fn = func(ctx context.Context, progress func(float64)) (any, error) {
+			r, err := bigcalc.PowContext(ctx, a, b, progress)
+			if err != nil {
+				return nil, err
+			}
+			return bigcalc.Exact(r), nil
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "job is required")
+	}
+	op, err := server.store.Start(interceptor.CallerKey(ctx), kind, fn)
+	if err != nil {
+		return nil, operationError(err, "")
+	}
+	return operationProto(op), nil
+}
+
+// GetOperation 返回任务的当前状态
+func (server *OperationServer) GetOperation(ctx context.Context, req *v1.GetOperationRequest) (*v1.Operation, error) {
+	op, err := server.store.Get(interceptor.CallerKey(ctx), req.GetId())
+	if err != nil {
+		return nil, operationError(err, req.GetId())
+	}
+	return operationProto(op), nil
+}
+
+// ListOperations 返回调用方的所有任务，不带结果：一个结果就可能有几 MB，多个加起来会超出消息大小上限
+func (server *OperationServer) ListOperations(ctx context.Context, req *v1.ListOperationsRequest) (*v1.ListOperationsResponse, error) {
+	resp := &v1.ListOperationsResponse{}
+	for _, op := range server.store.List(interceptor.CallerKey(ctx)) {
+		op.Result = nil
+		resp.Operations = append(resp.Operations, operationProto(op))
+	}
+	return resp, nil
+}
+
+// CancelOperation 请求取消任务
+func (server *OperationServer) CancelOperation(ctx context.Context, req *v1.CancelOperationRequest) (*v1.Operation, error) {
+	op, err := server.store.Cancel(interceptor.CallerKey(ctx), req.GetId())
+	if err != nil {
+		return nil, operationError(err, req.GetId())
+	}
+	return operationProto(op), nil
+}
+
+// WaitOperation 等待任务结束，超过 timeout 时返回当前状态；调用本身被取消或超时时返回对应的错误
+func (server *OperationServer) WaitOperation(ctx context.Context, req *v1.WaitOperationRequest) (*v1.Operation, error) {
+	timeout := defaultWaitTimeout
+	if req.GetTimeout() != nil {
+		if err := req.GetTimeout().CheckValid(); err != nil || req.GetTimeout().AsDuration() < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid timeout %v", req.GetTimeout())
+		}
+		timeout = min(req.GetTimeout().AsDuration(), maxWaitTimeout)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	op, err := server.store.Wait(waitCtx, interceptor.CallerKey(ctx), req.GetId())
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, operationError(err, req.GetId())
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return operationProto(op), nil
+}
+
+// countPrimes 统计小于 below 的素数个数；筛的工作量大致随已找到的素数个数的平方增长，
+// 用 p/ln(p) 近似不超过 p 的素数个数来估算进度
+func countPrimes(below int) operation.Func {
+	estimate := func(n int) float64 { return float64(n) / math.Log(float64(n)) }
+	return func(ctx context.Context, progress func(float64)) (any, error) {
+		count, err := goroutine.NewPrimeSieve().Count(ctx, below, func(prime int) {
+			progress(100 * math.Pow(estimate(prime)/estimate(below), 2))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return int64(count), nil
+	}
+}
+
+func operationProto(op operation.Operation) *v1.Operation {
+	pb := &v1.Operation{
+		Id:         op.ID,
+		Kind:       op.Kind,
+		Progress:   op.Progress,
+		CreateTime: timestamppb.New(op.Created),
+		UpdateTime: timestamppb.New(op.Updated),
+	}
+	switch op.State {
+	case operation.Running:
+		pb.State = v1.Operation_RUNNING
+	case operation.Succeeded:
+		pb.State = v1.Operation_SUCCEEDED
+		switch r := op.Result.(type) {
+		case int64:
+			pb.Outcome = &v1.Operation_Result{Result: &v1.OperationResult{Value: &v1.OperationResult_PrimeCount{PrimeCount: r}}}
+		case string:
+			pb.Outcome = &v1.Operation_Result{Result: &v1.OperationResult{Value: &v1.OperationResult_Pow{Pow: r}}}
+		}
+	case operation.Failed:
+		pb.State = v1.Operation_FAILED
+		st := status.Convert(bigcalcError(op.Err))
+		pb.Outcome = &v1.Operation_Error{Error: &v1.OperationError{Code: uint32(st.Code()), Message: st.Message()}}
+	case operation.Cancelled:
+		pb.State = v1.Operation_CANCELLED
+		pb.Outcome = &v1.Operation_Error{Error: &v1.OperationError{Code: uint32(codes.Canceled), Message: "operation cancelled"}}
+	}
+	return pb
+}
+
+// operationError 将 operation 的错误转换为 gRPC 状态
+func operationError(err error, id string) error {
+	switch {
+	case errors.Is(err, operation.ErrNotFound):
+		return withDetails(status.New(codes.NotFound, err.Error()),
+			&errdetails.ErrorInfo{
+				Reason:   "OPERATION_NOT_FOUND",
+				Domain:   errorDomain,
+				Metadata: map[string]string{"operation_id": id},
+			},
+		)
+	case errors.Is(err, operation.ErrTooMany):
+		return withDetails(status.New(codes.ResourceExhausted, err.Error()),
+			&errdetails.ErrorInfo{Reason: "TOO_MANY_OPERATIONS", Domain: errorDomain},
+		)
+	case errors.Is(err, operation.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.FromContextError(err).Err()
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"crypto/tls"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/history"
+	"github.com/MorseWayne/grpc-demo/internal/operation"
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"github.com/MorseWayne/grpc-demo/pkg/concurrency"
+	"github.com/MorseWayne/grpc-demo/pkg/fault"
+	"github.com/MorseWayne/grpc-demo/pkg/idempotency"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"github.com/MorseWayne/grpc-demo/pkg/ratelimit"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// Option NewGrpcServer 和 Run 的可选配置
+type Option func(*options)
+
+type options struct {
+	tlsConfig *tls.Config
+	auth      *interceptor.Auth
+	rateLimit *ratelimit.Limiter
+	// concurrency 为 nil 时不限制并发
+	concurrency *concurrency.Limiter
+	history     history.Store
+	// operations 为 nil 时使用默认参数的内存存储
+	operations *operation.Store
+	// idempotencyTTL 带 idempotency-key 的调用结果保留的时长，0 表示不支持
+	idempotencyTTL time.Duration
+	// metrics 为 nil 时不记录指标
+	metrics prometheus.Registerer
+	// tracer 为 nil 时不创建 span
+	tracer *tracing.Tracer
+	// faults 为 nil 时不注入故障，也不注册 FaultService
+	faults *fault.Injector
+	// gateway 在同一个监听地址上提供 HTTP/JSON 网关
+	gateway bool
+	// web 在同一个监听地址上接受 gRPC-Web 和 Connect 协议
+	web bool
+	// corsOrigins 允许跨域访问网关和 gRPC-Web/Connect 的来源
+	corsOrigins []string
+	// sessionIdle ChatAdd 会话断开后保留的时长
+	sessionIdle time.Duration
+	// shutdownTimeout GracefulStop 的最长等待时间
+	shutdownTimeout time.Duration
+	// timeout 一元调用的默认处理超时
+	timeout time.Duration
+}
+
+// WithTLS 使用 TLS 监听；tls.Config 中配置了 ClientCAs 时即为 mTLS
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithAuth 要求调用方携带 verifier 可以校验的 Bearer token，并按 policy 检查 scope
+func WithAuth(verifier *auth.Verifier, policy auth.Policy) Option {
+	return func(o *options) {
+		o.auth = &interceptor.Auth{Verifier: verifier, Policy: policy}
+	}
+}
+
+// WithRateLimit 按调用方（身份或对端 IP）和方法限流
+func WithRateLimit(limits ratelimit.Limits) Option {
+	return func(o *options) {
+		o.rateLimit = ratelimit.New(limits)
+	}
+}
+
+// WithConcurrencyLimit 按观测到的延迟自适应地限制进行中的调用数，超出上限的调用以 Unavailable 拒绝；
+// 配置了 WithMetrics 时同时导出当前上限
+func WithConcurrencyLimit(cfg concurrency.Config) Option {
+	return func(o *options) {
+		o.concurrency = concurrency.New(cfg)
+	}
+}
+
+// WithHistory 把计算记录写入 store，默认只保存在内存中；store 由调用方负责关闭
+func WithHistory(store history.Store) Option {
+	return func(o *options) {
+		o.history = store
+	}
+}
+
+// WithOperations 在 store 中运行 OperationService 启动的任务，store 由调用方负责关闭；
+// 默认使用进程内的存储，服务停止后其中的任务继续运行到结束
+func WithOperations(store *operation.Store) Option {
+	return func(o *options) {
+		o.operations = store
+	}
+}
+
+// WithIdempotencyTTL 带 idempotency-key 的一元调用结果保留多久，默认 idempotency.DefaultTTL，0 表示忽略该 header
+func WithIdempotencyTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.idempotencyTTL = d
+	}
+}
+
+// WithMetrics 把 grpc_server_* 指标注册到 reg，由调用方负责对外提供（见 metrics.Serve）
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(o *options) {
+		o.metrics = reg
+	}
+}
+
+// WithTracer 为每次调用创建 server span，并从请求的 traceparent 继续调用链
+func WithTracer(t *tracing.Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithFaults 按 inj 的规则向调用注入延迟和错误，并注册 FaultService 以便在运行中修改规则；
+// 只应在测试环境中开启
+func WithFaults(inj *fault.Injector) Option {
+	return func(o *options) {
+		o.faults = inj
+	}
+}
+
+// WithGateway 在 Run/Serve 的监听地址上同时提供 HTTP/JSON 网关（见 gateway.New），
+// 按 Content-Type 区分 gRPC 和 HTTP 请求；对 NewGrpcServer 无效
+func WithGateway() Option {
+	return func(o *options) {
+		o.gateway = true
+	}
+}
+
+// WithWeb 在 Run/Serve 的监听地址上同时接受 gRPC-Web 和 Connect 协议（见 gateway.NewWeb），
+// 浏览器可以直接调用；对 NewGrpcServer 无效
+func WithWeb() Option {
+	return func(o *options) {
+		o.web = true
+	}
+}
+
+// WithCORS 允许 origins 中的页面跨域访问 HTTP/JSON 网关和 gRPC-Web/Connect，"*" 表示任意来源
+func WithCORS(origins ...string) Option {
+	return func(o *options) {
+		o.corsOrigins = origins
+	}
+}
+
+// WithSessionIdleTimeout ChatAdd 会话在最后一个流断开后保留的时长，默认 session.DefaultIdleTimeout
+func WithSessionIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.sessionIdle = d
+	}
+}
+
+// WithShutdownTimeout 优雅退出的最长等待时间，超时后强制关闭所有连接
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = d
+	}
+}
+
+// WithDefaultTimeout 一元调用的默认处理超时，默认 10s，0 表示不限制；流不受影响
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// DefaultPolicy 计算器服务的默认授权策略：健康检查和反射无需认证，双向流方法需要 streaming scope，
+// 历史记录包含所有调用方的数据，需要 audit scope，修改故障注入规则需要 admin scope
+func DefaultPolicy() auth.Policy {
+	streaming := []string{"streaming"}
+	audit := []string{"audit"}
+	admin := []string{"admin"}
+	return auth.Policy{
+		Public: map[string]bool{
+			healthpb.Health_Check_FullMethodName:                                   true,
+			healthpb.Health_List_FullMethodName:                                    true,
+			healthpb.Health_Watch_FullMethodName:                                   true,
+			reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName:      true,
+			reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: true,
+		},
+		Scopes: map[string][]string{
+			v1.CalculatorService_ChatAdd_FullMethodName:      streaming,
+			v1.CalculatorService_ChatSubtract_FullMethodName: streaming,
+			v1.CalculatorService_ChatMultiply_FullMethodName: streaming,
+			v1.CalculatorService_ChatDivide_FullMethodName:   streaming,
+			v1.CalculatorService_ChatModulo_FullMethodName:   streaming,
+			v1.CalculatorService_ChatPow_FullMethodName:      streaming,
+			v1.HistoryService_ListHistory_FullMethodName:     audit,
+			v1.HistoryService_StreamHistory_FullMethodName:   audit,
+			v1.FaultService_SetFaults_FullMethodName:         admin,
+			v1.FaultService_GetFaults_FullMethodName:         admin,
+		},
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{shutdownTimeout: 10 * time.Second, timeout: 10 * time.Second, idempotencyTTL: idempotency.DefaultTTL}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// serveHTTP 是否需要由 http.Server 提供服务，gRPC 请求再交给 grpc.Server.ServeHTTP
+func (o *options) serveHTTP() bool {
+	return o.gateway || o.web
+}
+
+func (o *options) credentials() credentials.TransportCredentials {
+	if o.tlsConfig == nil {
+		return nil
+	}
+	return credentials.NewTLS(o.tlsConfig)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rangeCursor RangeAdd 的续传位置，next 为下一个待发送的值，done 表示已经发送完毕
+type rangeCursor struct {
+	start, end, step int64
+	next             int64
+	done             bool
+}
+
+// encodeRangeToken 生成 continuation token，带上 start/end/step 以便校验续传请求
+func encodeRangeToken(c rangeCursor) string {
+	if c.done {
+		return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "v1:%d:%d:%d:done", c.start, c.end, c.step))
+	}
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "v1:%d:%d:%d:%d", c.start, c.end, c.step, c.next))
+}
+
+func decodeRangeToken(token string, req *v1.RangeRequest, step int64) (rangeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return rangeCursor{}, status.Error(codes.InvalidArgument, "malformed resume_token")
+	}
+	var c rangeCursor
+	var next string
+	if _, err := fmt.Sscanf(string(raw), "v1:%d:%d:%d:%s", &c.start, &c.end, &c.step, &next); err != nil {
+		return rangeCursor{}, status.Error(codes.InvalidArgument, "malformed resume_token")
+	}
+	if c.start != req.Start || c.end != req.End || c.step != step {
+		return rangeCursor{}, status.Error(codes.InvalidArgument, "resume_token does not match start/end/step")
+	}
+	if next == "done" {
+		c.done = true
+		return c, nil
+	}
+	if _, err := fmt.Sscanf(next, "%d", &c.next); err != nil ||
+		c.next < c.start || c.next > c.end || uint64(c.next-c.start)%uint64(c.step) != 0 {
+		return rangeCursor{}, status.Error(codes.InvalidArgument, "resume_token out of range")
+	}
+	return c, nil
+}
+
+// pacer 按 rate 控制发送间隔；rate 为 0 时不等待，只依靠 Send 的流控阻塞
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// minRate 最小的非零 rate，再小发送间隔就超出 time.Duration 的范围
+const minRate = float64(time.Second) / math.MaxInt64
+
+// validRate rate 为 0（不限速）或不小于 minRate 的有限值
+func validRate(rate float64) bool {
+	return rate == 0 || rate >= minRate && !math.IsInf(rate, 0)
+}
+
+func newPacer(rate float64) *pacer {
+	if rate <= 0 || math.IsNaN(rate) {
+		return &pacer{}
+	}
+	// 间隔超出 time.Duration 时按最大值处理，避免溢出成负数后不再限速
+	interval := time.Duration(math.MaxInt64)
+	if rate > minRate {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+	return &pacer{interval: interval, next: time.Now()}
+}
+
+// wait 阻塞到下一个发送时刻，返回 nil 表示可以发送
+func (p *pacer) wait(done <-chan struct{}) error {
+	if p.interval == 0 {
+		return nil
+	}
+	if d := time.Until(p.next); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-done:
+			return status.Error(codes.Canceled, "client canceled")
+		case <-t.C:
+		}
+	}
+	// 以计划时间而不是实际时间累加，避免误差积累
+	p.next = p.next.Add(p.interval)
+	if now := time.Now(); p.next.Before(now) {
+		p.next = now
+	}
+	return nil
+}
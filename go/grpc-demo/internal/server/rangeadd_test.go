@@ -0,0 +1,61 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRangeToken(t *testing.T) {
+	req := &v1.RangeRequest{Start: 1, End: math.MaxInt64, Step: 3}
+	c := rangeCursor{start: 1, end: math.MaxInt64, step: 3, next: 7}
+	got, err := decodeRangeToken(encodeRangeToken(c), req, 3)
+	if err != nil || got != c {
+		t.Fatalf("round trip = %+v, %v", got, err)
+	}
+	c.done = true
+	if got, err := decodeRangeToken(encodeRangeToken(c), req, 3); err != nil || !got.done {
+		t.Fatalf("done round trip = %+v, %v", got, err)
+	}
+
+	bad := []string{
+		"not base64!",
+		encodeRangeToken(rangeCursor{start: 2, end: math.MaxInt64, step: 3, next: 8}), // start 不匹配
+		encodeRangeToken(rangeCursor{start: 1, end: math.MaxInt64, step: 3, next: 8}), // 未对齐步长
+		encodeRangeToken(rangeCursor{start: 1, end: math.MaxInt64, step: 3, next: -2}),
+	}
+	for _, token := range bad {
+		if _, err := decodeRangeToken(token, req, 3); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("decodeRangeToken(%q) err = %v, expected InvalidArgument", token, err)
+		}
+	}
+}
+
+func TestPacer(t *testing.T) {
+	p := newPacer(100)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := p.wait(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第一条立即发送，之后每条间隔 10ms
+	if d := time.Since(start); d < 45*time.Millisecond {
+		t.Errorf("6 messages at 100/s took %s", d)
+	}
+	// 间隔超出 time.Duration 的 rate 不会溢出成负数
+	if p := newPacer(minRate / 2); p.interval <= 0 {
+		t.Errorf("interval at rate %g = %s", minRate/2, p.interval)
+	}
+	done := make(chan struct{})
+	close(done)
+	p = newPacer(0.001)
+	_ = p.wait(done)
+	if err := p.wait(done); status.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+}
@@ -9,13 +9,27 @@ import (
 	"time"
 
 	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+	"github.com/MorseWayne/grpc-demo/internal/history"
+	"github.com/MorseWayne/grpc-demo/internal/operation"
+	"github.com/MorseWayne/grpc-demo/internal/session"
+	"github.com/MorseWayne/grpc-demo/internal/stats"
+	"github.com/MorseWayne/grpc-demo/pkg/idempotency"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
 type CalculatorSerer struct {
 	v1.UnimplementedCalculatorServiceServer
+	// sessions ChatAdd 会话，为 nil 时不支持会话命令
+	sessions *session.Store
 }
 
 // Unary: 单词请求-响应
@@ -30,7 +44,11 @@ func (server *CalculatorSerer) Add(ctx context.Context, req *v1.AddRequest) (*v1
 		}
 	default:
 	}
-	return &v1.AddResponse{Result: req.A + req.B}, nil
+	result, err := calc.Add(req.A, req.B)
+	if err != nil {
+		return nil, calcError(err)
+	}
+	return &v1.AddResponse{Result: result}, nil
 }
 
 // Client Streaming: 客户端流，客户端批量上传数据
@@ -44,31 +62,99 @@ func (server *CalculatorSerer) SumStream(stream v1.CalculatorService_SumStreamSe
 		if err != nil {
 			return status.Errorf(codes.Internal, "recv error: %v", err)
 		}
-		sum = req.A + req.B
+		if sum, err = calc.Add(sum, req.A); err != nil {
+			return calcError(err)
+		}
+		if sum, err = calc.Add(sum, req.B); err != nil {
+			return calcError(err)
+		}
+	}
+}
+
+// StatsStream 客户端流：统计上传的所有数值，内存占用为常数
+func (server *CalculatorSerer) StatsStream(stream v1.CalculatorService_StatsStreamServer) error {
+	summary := stats.NewSummary(stats.DefaultQuantiles...)
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(statsResponse(summary))
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "recv error: %v", err)
+		}
+		if err := summary.Add(req.Value); err != nil {
+			return calcError(err)
+		}
 	}
 }
 
+func statsResponse(s *stats.Summary) *v1.StatsResponse {
+	resp := &v1.StatsResponse{
+		Count:    s.Count(),
+		Sum:      s.Sum(),
+		Min:      s.Min(),
+		Max:      s.Max(),
+		Mean:     s.Mean(),
+		Variance: s.Variance(),
+	}
+	for _, q := range s.Quantiles() {
+		resp.Percentiles = append(resp.Percentiles, &v1.Percentile{Quantile: q.P(), Value: q.Value()})
+	}
+	return resp
+}
+
 // Server Streaming: 服务器流， 服务器批量推送数据
 func (server *CalculatorSerer) RangeAdd(req *v1.RangeRequest, stream v1.CalculatorService_RangeAddServer) error {
 	if req.Start > req.End {
 		return status.Errorf(codes.InvalidArgument, "start[%d] > end[%d]", req.Start, req.End)
 	}
-	for i := req.Start; i <= req.End; i++ {
+	step := req.Step
+	if step == 0 {
+		step = 1
+	}
+	if step < 0 {
+		return status.Errorf(codes.InvalidArgument, "step[%d] < 0", req.Step)
+	}
+	if !validRate(req.Rate) {
+		return status.Errorf(codes.InvalidArgument, "rate[%g] must be 0 or a finite number >= %g", req.Rate, minRate)
+	}
+	cursor := rangeCursor{start: req.Start, end: req.End, step: step, next: req.Start}
+	if req.ResumeToken != "" {
+		var err error
+		if cursor, err = decodeRangeToken(req.ResumeToken, req, step); err != nil {
+			return err
+		}
+	}
+	pace := newPacer(req.Rate)
+	for !cursor.done {
+		if err := pace.wait(stream.Context().Done()); err != nil {
+			return err
+		}
 		select {
 		case <-stream.Context().Done():
 			return status.Error(codes.Canceled, "client canceled")
 		default:
 		}
-		if err := stream.Send(&v1.AddResponse{Result: i}); err != nil {
+		i := cursor.next
+		// 用无符号差值判断是否到达终点，避免 start/end 接近 int64 边界时回绕
+		if uint64(cursor.end-i) < uint64(step) {
+			cursor.done = true
+		} else {
+			cursor.next = i + step
+		}
+		// Send 在流控窗口耗尽时会阻塞，慢客户端自然会拖慢发送速度
+		if err := stream.Send(&v1.AddResponse{Result: i, ContinuationToken: encodeRangeToken(cursor)}); err != nil {
 			return status.Errorf(codes.Internal, "send err : %v", err)
 		}
-		time.Sleep(50 * time.Millisecond)
 	}
 	return nil
 }
 
-// Bidirectional: 双向流，客户端服务端交替发送数据，适用于实时聊天
+// Bidirectional: 双向流，客户端服务端交替发送数据，适用于实时聊天；
+// 带 session 字段的消息操作服务端保存的累加器
 func (server *CalculatorSerer) ChatAdd(stream v1.CalculatorService_ChatAddServer) error {
+	sess := &chatSession{store: server.sessions}
+	defer sess.close()
 	for {
 		req, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
@@ -77,48 +163,192 @@ func (server *CalculatorSerer) ChatAdd(stream v1.CalculatorService_ChatAddServer
 		if err != nil {
 			return status.Errorf(codes.Internal, "recv err : %v", err)
 		}
-		if err := stream.Send(&v1.AddResponse{Result: req.A + req.B}); err != nil {
+		var resp *v1.AddResponse
+		if req.Session != nil {
+			resp, err = sess.apply(stream.Context(), req.Session)
+		} else {
+			var result int64
+			result, err = calc.Add(req.A, req.B)
+			if err != nil {
+				err = calcError(err)
+			}
+			resp = &v1.AddResponse{Result: result}
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
 			return status.Errorf(codes.Internal, "send err : %v", err)
 		}
 	}
 }
 
-func UnrayLoggingInterceptor(
-	ctx context.Context,
-	req interface{},
-	info *grpc.UnaryServerInfo,
-	handler grpc.UnaryHandler) (resp interface{}, err error) {
-	start := time.Now()
-	resp, err = handler(ctx, req)
-	log.Printf("[UNARY] method = %s, cost = %s, err = %v", info.FullMethod, time.Since(start), err)
-	return
+// NewGrpcServer 创建注册了计算器、健康检查和反射服务的 grpc.Server
+func NewGrpcServer(opts ...Option) *grpc.Server {
+	s, _ := newGrpcServer(newOptions(opts))
+	return s
 }
 
-func StreamLoggingInterceptor(
-	srv interface{},
-	ss grpc.ServerStream,
-	info *grpc.StreamServerInfo,
-	handler grpc.StreamHandler) error {
-	start := time.Now()
-	err := handler(srv, ss)
-	log.Printf("[STREAM] method = %s, cost = %s, err = %v", info.FullMethod, time.Since(start), err)
-	return err
-}
+func newGrpcServer(o *options) (*grpc.Server, *health.Server) {
+	cfg := interceptor.Config{
+		Timeouts: interceptor.Timeouts{
+			Default: o.timeout,
+			PerMethod: map[string]time.Duration{
+				// WaitOperation 自己限制等待时间，见 maxWaitTimeout
+				v1.OperationService_WaitOperation_FullMethodName: 0,
+				// 客户端的健康检查一直挂在 Watch 上，超时会让连接反复被判为不健康
+				healthpb.Health_Watch_FullMethodName: 0,
+				// 实时跟踪历史记录，直到客户端断开
+				v1.HistoryService_StreamHistory_FullMethodName: 0,
+			},
+		},
+		Auth:      o.auth,
+		RateLimit: o.rateLimit,
+	}
+	if o.idempotencyTTL > 0 {
+		cfg.Idempotency = idempotency.New(o.idempotencyTTL, 0)
+	}
+	cfg.Tracer = o.tracer
+	if o.faults != nil {
+		// 管理接口和健康检查不受 * 规则影响，注入的故障总是可以撤销
+		o.faults.Exempt(
+			v1.FaultService_SetFaults_FullMethodName,
+			v1.FaultService_GetFaults_FullMethodName,
+			healthpb.Health_Check_FullMethodName,
+			healthpb.Health_List_FullMethodName,
+			healthpb.Health_Watch_FullMethodName,
+		)
+		cfg.Fault = o.faults
+	}
+	if o.concurrency != nil {
+		// 健康检查和管理接口在过载时也要能响应；WaitOperation 的耗时是等待任务的时间，不反映负载
+		o.concurrency.Exempt(
+			v1.OperationService_WaitOperation_FullMethodName,
+			v1.FaultService_SetFaults_FullMethodName,
+			v1.FaultService_GetFaults_FullMethodName,
+			healthpb.Health_Check_FullMethodName,
+			healthpb.Health_List_FullMethodName,
+			healthpb.Health_Watch_FullMethodName,
+		)
+		cfg.Concurrency = o.concurrency
+	}
+	if o.metrics != nil {
+		// 只有指标名冲突时才会失败，不影响提供服务
+		if m, err := metrics.NewServer(o.metrics); err != nil {
+			log.Printf("register metrics: %v", err)
+		} else {
+			cfg.Metrics = m
+		}
+		if o.concurrency != nil {
+			if err := metrics.RegisterConcurrency(o.metrics, o.concurrency); err != nil {
+				log.Printf("register concurrency metrics: %v", err)
+			}
+		}
+	}
+	store := o.history
+	if store == nil {
+		store = history.NewMemoryStore(defaultHistoryLimit)
+	}
+	journal := history.NewJournal(store)
+	// 记录放在链的最后，只记录通过了认证和限流、真正执行了的计算
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append(interceptor.UnaryServerChain(cfg), unaryServerHistory(journal))...),
+		grpc.ChainStreamInterceptor(append(interceptor.StreamServerChain(cfg), streamServerHistory(journal))...),
+	}
+	if creds := o.credentials(); creds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(serverOpts...)
+	v1.RegisterCalculatorServiceServer(s, &CalculatorSerer{sessions: session.NewStore(o.sessionIdle, 0)})
+	v2.RegisterCalculatorServiceServer(s, &CalculatorServerV2{})
+	v1.RegisterHistoryServiceServer(s, &HistoryServer{journal: journal})
+	operations := o.operations
+	if operations == nil {
+		operations = operation.NewStore(0, 0, 0, 0)
+	}
+	v1.RegisterOperationServiceServer(s, &OperationServer{store: operations})
+	if o.faults != nil {
+		v1.RegisterFaultServiceServer(s, &FaultServer{injector: o.faults})
+	}
 
-func NewGrpcServer() *grpc.Server {
-	s := grpc.NewServer(
-		grpc.UnaryInterceptor(UnrayLoggingInterceptor),
-		grpc.StreamInterceptor(StreamLoggingInterceptor),
-	)
-	v1.RegisterCalculatorServiceServer(s, &CalculatorSerer{})
-	return s
+	hs := health.NewServer()
+	for _, name := range []string{
+		v1.CalculatorService_ServiceDesc.ServiceName,
+		v2.CalculatorService_ServiceDesc.ServiceName,
+		v1.HistoryService_ServiceDesc.ServiceName,
+		v1.OperationService_ServiceDesc.ServiceName,
+	} {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(s, hs)
+	reflection.Register(s)
+	return s, hs
 }
 
-func Run(addr string) error {
+// Run 监听 addr 并提供服务，ctx 结束后优雅退出
+func Run(ctx context.Context, addr string, opts ...Option) error {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
-	log.Printf("grpc server listening at: %s", addr)
-	return NewGrpcServer().Serve(lis)
+	o := newOptions(opts)
+	name := "grpc server"
+	switch {
+	case o.gateway && o.web:
+		name = "grpc server, grpc-web/connect and http gateway"
+	case o.gateway:
+		name = "grpc server and http gateway"
+	case o.web:
+		name = "grpc server and grpc-web/connect"
+	}
+	if o.tlsConfig != nil {
+		log.Printf("%s listening at: %s (tls)", name, addr)
+	} else {
+		log.Printf("%s listening at: %s", name, addr)
+	}
+	return Serve(ctx, lis, opts...)
+}
+
+// Serve 在 lis 上提供服务直到 ctx 结束：先把健康状态置为 NOT_SERVING，
+// 再 GracefulStop 等待进行中的调用完成，超过 shutdown timeout 后强制关闭
+func Serve(ctx context.Context, lis net.Listener, opts ...Option) error {
+	o := newOptions(opts)
+	if o.serveHTTP() {
+		return serveGateway(ctx, lis, o)
+	}
+	s, hs := newGrpcServer(o)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(lis)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	drain(o, hs, s.GracefulStop, s.Stop)
+	return nil
+}
+
+// drain 把健康状态置为 NOT_SERVING 后调用 graceful 等待进行中的调用完成，超过 shutdown timeout 后调用 force
+func drain(o *options, hs *health.Server, graceful, force func()) {
+	log.Printf("grpc server draining, timeout = %s", o.shutdownTimeout)
+	hs.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		graceful()
+		close(stopped)
+	}()
+	timer := time.NewTimer(o.shutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+		log.Printf("grpc server stopped gracefully")
+	case <-timer.C:
+		log.Printf("grpc server graceful stop timed out, closing remaining connections")
+		force()
+		<-stopped
+	}
 }
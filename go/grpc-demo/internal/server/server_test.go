@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestServeGracefulShutdown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, lis, WithShutdownTimeout(300*time.Millisecond))
+	}()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	hc := healthpb.NewHealthClient(conn)
+	resp, err := hc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: v1.CalculatorService_ServiceDesc.ServiceName})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health = %v, err = %v", resp.GetStatus(), err)
+	}
+
+	// 一个一直不结束的双向流会阻塞 GracefulStop，直到超时被强制关闭
+	watch, err := hc.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := watch.Recv(); err != nil || r.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("watch = %v, err = %v", r.GetStatus(), err)
+	}
+	chat, err := v1.NewCalculatorServiceClient(conn).ChatAdd(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chat.Send(&v1.AddRequest{A: 1, B: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chat.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	cancel()
+	if r, err := watch.Recv(); err != nil || r.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("watch while draining = %v, err = %v", r.GetStatus(), err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve returned %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("server did not stop after shutdown timeout")
+	}
+	if d := time.Since(start); d < 250*time.Millisecond {
+		t.Errorf("server stopped after %s, expected to wait for the open stream", d)
+	}
+}
+
+func TestServeGateway(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, lis, WithGateway(), WithShutdownTimeout(time.Second))
+	}()
+
+	// 同一个端口上既是 gRPC 服务，也是 HTTP/JSON 网关
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if r, err := v1.NewCalculatorServiceClient(conn).Add(context.Background(), &v1.AddRequest{A: 1, B: 2}); err != nil || r.Result != 3 {
+		t.Fatalf("grpc add = %v, %v", r, err)
+	}
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + lis.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.ReplaceAll(string(b), " ", "")
+	}
+	if code, body := get("/v1/add?a=2&b=3"); code != http.StatusOK || !strings.Contains(body, `"result":"5"`) {
+		t.Fatalf("http add = %d %s", code, body)
+	}
+	// 网关把 HTTP 客户端的地址带给服务端，两次调用的调用方相同，而不是进程内连接
+	if code, body := get("/v1/history?filter.method=Add"); code != http.StatusOK || strings.Count(body, `"caller":"ip:127.0.0.1"`) != 2 {
+		t.Fatalf("history = %d %s", code, body)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve returned %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestServeWeb(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, lis, WithWeb(), WithShutdownTimeout(time.Second))
+	}()
+	sum := "http://" + lis.Addr().String() + "/" + v1.CalculatorService_ServiceDesc.ServiceName + "/SumStream"
+
+	// 明文 HTTP/2 上的 Connect 客户端流
+	h2 := &http.Client{Transport: &http.Transport{Protocols: new(http.Protocols)}}
+	h2.Transport.(*http.Transport).Protocols.SetUnencryptedHTTP2(true)
+	defer h2.CloseIdleConnections()
+	body := encodeFrames(webFrame{data: []byte(`{"a":1,"b":2}`)}, webFrame{data: []byte(`{"a":3,"b":4}`)})
+	resp, err := h2.Post(sum, "application/connect+json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	frames := decodeFrames(t, b)
+	if resp.ProtoMajor != 2 || len(frames) != 2 || !strings.Contains(strings.ReplaceAll(string(frames[0].data), " ", ""), `"result":"10"`) {
+		t.Fatalf("connect sum over HTTP/2 = %s %q", resp.Proto, b)
+	}
+
+	// 只开启 gRPC-Web/Connect 时没有 REST 路由，gRPC 照常可用
+	if resp, err := http.Get("http://" + lis.Addr().String() + "/v1/add?a=1&b=2"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("rest route = %v, %v", resp, err)
+	} else {
+		resp.Body.Close()
+	}
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if r, err := v1.NewCalculatorServiceClient(conn).Add(context.Background(), &v1.AddRequest{A: 1, B: 2}); err != nil || r.Result != 3 {
+		t.Fatalf("grpc add = %v, %v", r, err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve returned %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	v1 "github.com/MorseWayne/grpc-demo/api/gen"
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+	"github.com/MorseWayne/grpc-demo/internal/session"
+	"github.com/MorseWayne/grpc-demo/pkg/interceptor"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var sessionOps = map[v1.SessionCommand_Op]session.Op{
+	v1.SessionCommand_ADD:      session.OpAdd,
+	v1.SessionCommand_SUBTRACT: session.OpSubtract,
+	v1.SessionCommand_RESET:    session.OpReset,
+	v1.SessionCommand_UNDO:     session.OpUndo,
+	v1.SessionCommand_SNAPSHOT: session.OpSnapshot,
+}
+
+// chatSession ChatAdd 流上的会话，第一条会话命令时才打开
+type chatSession struct {
+	store *session.Store
+	s     *session.Session
+}
+
+// close 流结束时释放会话，会话本身继续保留到空闲超时
+func (c *chatSession) close() {
+	if c.s != nil {
+		c.store.Release(c.s)
+	}
+}
+
+// apply 执行一条会话命令
+func (c *chatSession) apply(ctx context.Context, cmd *v1.SessionCommand) (*v1.AddResponse, error) {
+	if c.store == nil {
+		return nil, status.Error(codes.Unimplemented, "sessions are not enabled on this server")
+	}
+	if cmd.Op == v1.SessionCommand_OPEN {
+		if c.s != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "stream already has session %s", c.s.ID())
+		}
+		s, err := c.store.Open(interceptor.CallerKey(ctx), cmd.SessionId)
+		if err != nil {
+			return nil, sessionError(err, cmd)
+		}
+		c.s = s
+		return sessionResponse(s.Apply(session.OpSnapshot, 0))
+	}
+	op, ok := sessionOps[cmd.Op]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown session op %v", cmd.Op)
+	}
+	if c.s == nil {
+		s, err := c.store.Open(interceptor.CallerKey(ctx), "")
+		if err != nil {
+			return nil, sessionError(err, cmd)
+		}
+		c.s = s
+	}
+	st, err := c.s.Apply(op, cmd.Value)
+	if err != nil {
+		return nil, sessionError(err, cmd)
+	}
+	return sessionResponse(st, nil)
+}
+
+func sessionResponse(st session.State, err error) (*v1.AddResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &v1.AddResponse{
+		Result: st.Value,
+		Session: &v1.SessionState{
+			SessionId: st.ID,
+			Version:   st.Version,
+			UndoDepth: int32(st.UndoDepth),
+		},
+	}, nil
+}
+
+// sessionError 将 session 包返回的错误转换为带 error details 的 gRPC 状态
+func sessionError(err error, cmd *v1.SessionCommand) error {
+	var oe *calc.OverflowError
+	switch {
+	case errors.Is(err, session.ErrNotFound):
+		return withDetails(status.New(codes.NotFound, err.Error()),
+			&errdetails.ErrorInfo{
+				Reason:   "SESSION_NOT_FOUND",
+				Domain:   errorDomain,
+				Metadata: map[string]string{"session_id": cmd.SessionId},
+			},
+		)
+	case errors.Is(err, session.ErrTooMany):
+		return withDetails(status.New(codes.ResourceExhausted, err.Error()),
+			&errdetails.ErrorInfo{Reason: "TOO_MANY_SESSIONS", Domain: errorDomain},
+		)
+	case errors.Is(err, session.ErrNothingToUndo):
+		return withDetails(status.New(codes.FailedPrecondition, err.Error()),
+			&errdetails.ErrorInfo{Reason: "NOTHING_TO_UNDO", Domain: errorDomain},
+		)
+	case errors.As(err, &oe):
+		value := strconv.FormatInt(cmd.Value, 10)
+		return withDetails(status.New(codes.OutOfRange, err.Error()),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "session.value", Description: "value = " + value + " causes int64 overflow of the accumulator"},
+			}},
+			&errdetails.ErrorInfo{
+				Reason:   "INT64_OVERFLOW",
+				Domain:   errorDomain,
+				Metadata: map[string]string{"op": oe.Op, "a": strconv.FormatInt(oe.A, 10), "b": value},
+			},
+		)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
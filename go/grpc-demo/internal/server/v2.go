@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+
+	v2 "github.com/MorseWayne/grpc-demo/api/gen/v2"
+	"github.com/MorseWayne/grpc-demo/internal/bigcalc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CalculatorServerV2 任意精度计算器，与 v1 注册在同一个 grpc.Server 上
+type CalculatorServerV2 struct {
+	v2.UnimplementedCalculatorServiceServer
+}
+
+type bigOp func(a, b *big.Rat) (*big.Rat, error)
+
+func (server *CalculatorServerV2) Add(ctx context.Context, req *v2.BinaryRequest) (*v2.NumberResponse, error) {
+	return bigBinary(ctx, req, bigcalc.Add)
+}
+
+func (server *CalculatorServerV2) Subtract(ctx context.Context, req *v2.BinaryRequest) (*v2.NumberResponse, error) {
+	return bigBinary(ctx, req, bigcalc.Subtract)
+}
+
+func (server *CalculatorServerV2) Multiply(ctx context.Context, req *v2.BinaryRequest) (*v2.NumberResponse, error) {
+	return bigBinary(ctx, req, bigcalc.Multiply)
+}
+
+func (server *CalculatorServerV2) Divide(ctx context.Context, req *v2.BinaryRequest) (*v2.NumberResponse, error) {
+	return bigBinary(ctx, req, bigcalc.Divide)
+}
+
+func (server *CalculatorServerV2) Pow(ctx context.Context, req *v2.BinaryRequest) (*v2.NumberResponse, error) {
+	return bigBinary(ctx, req, bigcalc.Pow)
+}
+
+// SumStream 累加客户端上传的所有数，scale 取最后一条消息中的非零值
+func (server *CalculatorServerV2) SumStream(stream v2.CalculatorService_SumStreamServer) error {
+	sum := new(big.Rat)
+	scale := 0
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(numberResponse(sum, scale))
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "recv error: %v", err)
+		}
+		v, err := bigcalc.Parse("value", req.Value)
+		if err != nil {
+			return bigcalcError(err)
+		}
+		if sum, err = bigcalc.Add(sum, v); err != nil {
+			return bigcalcError(err)
+		}
+		if req.Scale != 0 {
+			scale = int(req.Scale)
+		}
+	}
+}
+
+func bigBinary(ctx context.Context, req *v2.BinaryRequest, op bigOp) (*v2.NumberResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "request is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	a, err := bigcalc.Parse("a", req.A)
+	if err != nil {
+		return nil, bigcalcError(err)
+	}
+	b, err := bigcalc.Parse("b", req.B)
+	if err != nil {
+		return nil, bigcalcError(err)
+	}
+	r, err := op(a, b)
+	if err != nil {
+		return nil, bigcalcError(err)
+	}
+	return numberResponse(r, int(req.Scale)), nil
+}
+
+func numberResponse(r *big.Rat, scale int) *v2.NumberResponse {
+	exact, decimal, isExact := bigcalc.Format(r, scale)
+	return &v2.NumberResponse{Result: exact, Decimal: decimal, Exact: isExact}
+}
+
+// bigcalcError 将 bigcalc 的错误转换为 gRPC 状态
+func bigcalcError(err error) error {
+	var ie *bigcalc.InvalidNumberError
+	if errors.As(err, &ie) {
+		return withDetails(status.New(codes.InvalidArgument, err.Error()),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: ie.Field, Description: ie.Msg},
+			}},
+		)
+	}
+	var te *bigcalc.TooLargeError
+	if errors.As(err, &te) {
+		return withDetails(status.New(codes.OutOfRange, err.Error()),
+			&errdetails.ErrorInfo{
+				Reason:   "RESULT_TOO_LARGE",
+				Domain:   "calculator.v2",
+				Metadata: map[string]string{"op": te.Op},
+			},
+		)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
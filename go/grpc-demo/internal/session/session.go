@@ -0,0 +1,204 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+)
+
+const (
+	// DefaultIdleTimeout 没有流连接的会话保留多久
+	DefaultIdleTimeout = 10 * time.Minute
+	// DefaultLimit 同时保留的会话数上限
+	DefaultLimit = 10000
+	// MaxUndo 每个会话最多可以撤销的步数，更早的修改不再保留
+	MaxUndo = 64
+	// sweepInterval 清理过期会话的间隔
+	sweepInterval = time.Minute
+)
+
+var (
+	// ErrNotFound 会话不存在、已经过期或属于其他调用方
+	ErrNotFound = errors.New("session not found")
+	// ErrTooMany 会话数达到上限
+	ErrTooMany = errors.New("too many sessions")
+	// ErrNothingToUndo 没有可以撤销的修改
+	ErrNothingToUndo = errors.New("nothing to undo")
+)
+
+// Op 会话命令
+type Op int
+
+const (
+	OpAdd Op = iota + 1
+	OpSubtract
+	OpReset
+	OpUndo
+	OpSnapshot
+)
+
+// State 执行命令后的会话状态
+type State struct {
+	ID        string
+	Value     int64
+	Version   uint64 // 每次修改加一
+	UndoDepth int
+}
+
+// Session 一个累加器；可以同时被多个流使用（例如旧连接还没断开时客户端已经重连），命令串行执行
+type Session struct {
+	id    string
+	owner string
+
+	mu      sync.Mutex
+	value   int64
+	version uint64
+	undo    []int64 // 每次修改前的值，最新的在最后
+
+	// 以下字段由 Store.mu 保护
+	attached int       // 正在使用会话的流数，大于 0 时不会过期
+	idleFrom time.Time // 最后一个流断开的时刻
+}
+
+// ID 恢复会话时使用的标识
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Apply 执行一条命令；出错时会话状态不变
+func (s *Session) Apply(op Op, v int64) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.value
+	var err error
+	switch op {
+	case OpSnapshot:
+		return s.state(), nil
+	case OpAdd:
+		next, err = calc.Add(s.value, v)
+	case OpSubtract:
+		next, err = calc.Subtract(s.value, v)
+	case OpReset:
+		next = v
+	case OpUndo:
+		if len(s.undo) == 0 {
+			return s.state(), ErrNothingToUndo
+		}
+		s.value = s.undo[len(s.undo)-1]
+		s.undo = s.undo[:len(s.undo)-1]
+		s.version++
+		return s.state(), nil
+	default:
+		return s.state(), errors.New("unknown session op")
+	}
+	if err != nil {
+		return s.state(), err
+	}
+	if len(s.undo) == MaxUndo {
+		s.undo = append(s.undo[:0], s.undo[1:]...)
+	}
+	s.undo = append(s.undo, s.value)
+	s.value = next
+	s.version++
+	return s.state(), nil
+}
+
+// state 调用方持有 s.mu
+func (s *Session) state() State {
+	return State{ID: s.id, Value: s.value, Version: s.version, UndoDepth: len(s.undo)}
+}
+
+// Store 服务端保存的会话；流断开后会话保留 idle 时长，期间可以用 ID 恢复
+type Store struct {
+	idle  time.Duration
+	limit int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// NewStore 创建会话存储；idle 或 limit 不大于 0 时使用默认值
+func NewStore(idle time.Duration, limit int) *Store {
+	if idle <= 0 {
+		idle = DefaultIdleTimeout
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Store{
+		idle:     idle,
+		limit:    limit,
+		now:      time.Now,
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Open 为 owner 新建会话（id 为空）或恢复已有会话；使用完毕后必须调用 Release
+func (st *Store) Open(owner, id string) (*Session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	st.sweep(now)
+	if id != "" {
+		s, ok := st.sessions[id]
+		// 其他调用方的会话同样报告不存在，不暴露它是否存在
+		if !ok || s.owner != owner || st.expired(s, now) {
+			return nil, ErrNotFound
+		}
+		s.attached++
+		return s, nil
+	}
+	if len(st.sessions) >= st.limit {
+		return nil, ErrTooMany
+	}
+	s := &Session{id: newID(), owner: owner, attached: 1}
+	st.sessions[s.id] = s
+	return s, nil
+}
+
+// Release 流不再使用会话，最后一个流断开后开始计算空闲时间
+func (st *Store) Release(s *Session) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s.attached--
+	if s.attached == 0 {
+		s.idleFrom = st.now()
+	}
+}
+
+// Len 当前保留的会话数，包括已过期但还没清理的
+func (st *Store) Len() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.sessions)
+}
+
+func (st *Store) expired(s *Session, now time.Time) bool {
+	return s.attached == 0 && now.Sub(s.idleFrom) >= st.idle
+}
+
+// sweep 删除过期会话，调用方持有 st.mu
+func (st *Store) sweep(now time.Time) {
+	if now.Sub(st.lastSweep) < min(sweepInterval, st.idle) {
+		return
+	}
+	st.lastSweep = now
+	for id, s := range st.sessions {
+		if st.expired(s, now) {
+			delete(st.sessions, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read 不会返回错误
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+)
+
+func TestApply(t *testing.T) {
+	st := NewStore(0, 0)
+	s, err := st.Open("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		op    Op
+		v     int64
+		value int64
+		undo  int
+	}{
+		{OpAdd, 5, 5, 1},
+		{OpSubtract, 2, 3, 2},
+		{OpReset, 10, 10, 3},
+		{OpSnapshot, 0, 10, 3},
+		{OpUndo, 0, 3, 2},
+		{OpUndo, 0, 5, 1},
+	}
+	for i, step := range steps {
+		got, err := s.Apply(step.op, step.v)
+		if err != nil || got.Value != step.value || got.UndoDepth != step.undo || got.ID != s.ID() {
+			t.Fatalf("step %d: state = %+v, %v", i, got, err)
+		}
+	}
+	if got, _ := s.Apply(OpSnapshot, 0); got.Version != 5 {
+		t.Fatalf("version = %d, want 5 (snapshot does not count)", got.Version)
+	}
+
+	// 出错时状态不变
+	var oe *calc.OverflowError
+	if got, err := s.Apply(OpAdd, math.MaxInt64); !errors.As(err, &oe) || got.Value != 5 || got.Version != 5 {
+		t.Fatalf("overflow: state = %+v, %v", got, err)
+	}
+	s.Apply(OpUndo, 0)
+	if _, err := s.Apply(OpUndo, 0); err != ErrNothingToUndo {
+		t.Fatalf("undo of nothing = %v", err)
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	s, _ := NewStore(0, 0).Open("alice", "")
+	for i := 0; i < MaxUndo+10; i++ {
+		s.Apply(OpAdd, 1)
+	}
+	for i := 0; i < MaxUndo; i++ {
+		if _, err := s.Apply(OpUndo, 0); err != nil {
+			t.Fatalf("undo %d: %v", i, err)
+		}
+	}
+	got, err := s.Apply(OpUndo, 0)
+	if err != ErrNothingToUndo || got.Value != 10 {
+		t.Fatalf("after %d undos: state = %+v, %v", MaxUndo, got, err)
+	}
+}
+
+func TestResumeAndExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	st := NewStore(time.Minute, 0)
+	st.now = func() time.Time { return now }
+
+	s, _ := st.Open("alice", "")
+	s.Apply(OpAdd, 7)
+	if _, err := st.Open("bob", s.ID()); err != ErrNotFound {
+		t.Fatalf("other caller resumed the session: %v", err)
+	}
+	// 仍有流连接时不会过期
+	now = now.Add(time.Hour)
+	st.Release(s)
+	now = now.Add(30 * time.Second)
+	r, err := st.Open("alice", s.ID())
+	if err != nil || r != s {
+		t.Fatalf("resume = %v", err)
+	}
+	if got, _ := r.Apply(OpSnapshot, 0); got.Value != 7 {
+		t.Fatalf("resumed value = %d", got.Value)
+	}
+	st.Release(r)
+
+	now = now.Add(time.Minute)
+	if _, err := st.Open("alice", s.ID()); err != ErrNotFound {
+		t.Fatalf("expired session resumed: %v", err)
+	}
+	if st.Len() != 0 {
+		t.Fatalf("expired session not swept, %d left", st.Len())
+	}
+}
+
+func TestLimit(t *testing.T) {
+	st := NewStore(0, 2)
+	a, _ := st.Open("alice", "")
+	st.Open("alice", "")
+	if _, err := st.Open("bob", ""); err != ErrTooMany {
+		t.Fatalf("third session = %v", err)
+	}
+	// 已有的会话仍然可以恢复
+	if _, err := st.Open("alice", a.ID()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Quantile 使用 P² 算法（Jain & Chlamtac, 1985）在线估计分位数，
+// 只维护 5 个标记点，内存占用与样本数量无关
+type Quantile struct {
+	p     float64
+	count int
+	q     [5]float64 // 标记点高度
+	n     [5]float64 // 标记点实际位置
+	np    [5]float64 // 标记点期望位置
+	dn    [5]float64 // 期望位置的增量
+}
+
+// NewQuantile 创建 p 分位数估计器，p 取值 (0, 1)
+func NewQuantile(p float64) *Quantile {
+	return &Quantile{
+		p:  p,
+		dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// P 返回估计的分位点
+func (e *Quantile) P() float64 {
+	return e.p
+}
+
+// Add 加入一个样本
+func (e *Quantile) Add(x float64) {
+	if e.count < 5 {
+		e.q[e.count] = x
+		e.count++
+		if e.count == 5 {
+			sort.Float64s(e.q[:])
+			for i := range e.n {
+				e.n[i] = float64(i + 1)
+			}
+			e.np = [5]float64{1, 1 + 2*e.p, 1 + 4*e.p, 3 + 2*e.p, 5}
+		}
+		return
+	}
+	e.count++
+
+	// 找到 x 所在的区间并更新端点
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.n[i]++
+	}
+	for i := range e.np {
+		e.np[i] += e.dn[i]
+	}
+
+	// 调整中间三个标记点
+	for i := 1; i <= 3; i++ {
+		d := e.np[i] - e.n[i]
+		if (d >= 1 && e.n[i+1]-e.n[i] > 1) || (d <= -1 && e.n[i-1]-e.n[i] < -1) {
+			s := math.Copysign(1, d)
+			q := e.parabolic(i, s)
+			if e.q[i-1] < q && q < e.q[i+1] {
+				e.q[i] = q
+			} else {
+				e.q[i] = e.linear(i, s)
+			}
+			e.n[i] += s
+		}
+	}
+}
+
+// Value 返回当前估计值，样本不足 5 个时返回精确值
+func (e *Quantile) Value() float64 {
+	if e.count == 0 {
+		return 0
+	}
+	if e.count < 5 {
+		xs := make([]float64, e.count)
+		copy(xs, e.q[:e.count])
+		sort.Float64s(xs)
+		idx := int(math.Ceil(e.p*float64(e.count))) - 1
+		return xs[max(idx, 0)]
+	}
+	return e.q[2]
+}
+
+func (e *Quantile) parabolic(i int, s float64) float64 {
+	return e.q[i] + s/(e.n[i+1]-e.n[i-1])*
+		((e.n[i]-e.n[i-1]+s)*(e.q[i+1]-e.q[i])/(e.n[i+1]-e.n[i])+
+			(e.n[i+1]-e.n[i]-s)*(e.q[i]-e.q[i-1])/(e.n[i]-e.n[i-1]))
+}
+
+func (e *Quantile) linear(i int, s float64) float64 {
+	j := i + int(s)
+	return e.q[i] + s*(e.q[j]-e.q[i])/(e.n[j]-e.n[i])
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	s := NewSummary(DefaultQuantiles...)
+	for _, x := range []int64{2, 4, 4, 4, 5, 5, 7, 9} {
+		if err := s.Add(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Count() != 8 || s.Sum() != 40 || s.Min() != 2 || s.Max() != 9 {
+		t.Errorf("count/sum/min/max = %d/%d/%d/%d", s.Count(), s.Sum(), s.Min(), s.Max())
+	}
+	if s.Mean() != 5 || s.Variance() != 4 {
+		t.Errorf("mean = %v, variance = %v, expected 5 and 4", s.Mean(), s.Variance())
+	}
+	if err := s.Add(math.MaxInt64); err == nil {
+		t.Error("expected overflow")
+	}
+	if s.Count() != 8 {
+		t.Errorf("overflowed sample should not be counted")
+	}
+}
+
+func TestQuantileAccuracy(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	qs := []*Quantile{NewQuantile(0.5), NewQuantile(0.9), NewQuantile(0.99)}
+	xs := make([]float64, 100000)
+	for i := range xs {
+		xs[i] = r.NormFloat64()*100 + 1000
+		for _, q := range qs {
+			q.Add(xs[i])
+		}
+	}
+	sort.Float64s(xs)
+	for _, q := range qs {
+		exact := xs[int(q.P()*float64(len(xs)))]
+		if math.Abs(q.Value()-exact) > 5 {
+			t.Errorf("p%v = %v, exact %v", q.P()*100, q.Value(), exact)
+		}
+	}
+}
+
+func TestQuantileSmallSample(t *testing.T) {
+	q := NewQuantile(0.5)
+	for _, x := range []float64{3, 1, 2} {
+		q.Add(x)
+	}
+	if q.Value() != 2 {
+		t.Errorf("median of 1,2,3 = %v", q.Value())
+	}
+}
@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"math"
+
+	"github.com/MorseWayne/grpc-demo/internal/calc"
+)
+
+// DefaultQuantiles StatsStream 默认返回的分位点
+var DefaultQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// Summary 流式统计：计数、求和、最值、均值、方差和近似分位数，内存占用为常数
+type Summary struct {
+	count     int64
+	sum       int64
+	min, max  int64
+	mean, m2  float64 // Welford 在线算法
+	quantiles []*Quantile
+}
+
+// NewSummary 创建统计器，qs 为需要估计的分位点
+func NewSummary(qs ...float64) *Summary {
+	s := &Summary{}
+	for _, q := range qs {
+		s.quantiles = append(s.quantiles, NewQuantile(q))
+	}
+	return s
+}
+
+// Add 加入一个样本，求和溢出时返回 calc.OverflowError 且不修改状态
+func (s *Summary) Add(x int64) error {
+	sum, err := calc.Add(s.sum, x)
+	if err != nil {
+		return err
+	}
+	s.sum = sum
+	s.count++
+	if s.count == 1 || x < s.min {
+		s.min = x
+	}
+	if s.count == 1 || x > s.max {
+		s.max = x
+	}
+	delta := float64(x) - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (float64(x) - s.mean)
+	for _, q := range s.quantiles {
+		q.Add(float64(x))
+	}
+	return nil
+}
+
+func (s *Summary) Count() int64  { return s.count }
+func (s *Summary) Sum() int64    { return s.sum }
+func (s *Summary) Min() int64    { return s.min }
+func (s *Summary) Max() int64    { return s.max }
+func (s *Summary) Mean() float64 { return s.mean }
+
+// Variance 总体方差，样本数为 0 时返回 0
+func (s *Summary) Variance() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.m2 / float64(s.count)
+}
+
+// StdDev 总体标准差
+func (s *Summary) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Quantiles 返回各分位点的估计器
+func (s *Summary) Quantiles() []*Quantile {
+	return s.quantiles
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key := []byte("secret")
+	token, err := Sign(key, "demo", Identity{Subject: "alice", Scopes: []string{"streaming", "admin"}}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := NewVerifier(key, "demo").Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "alice" || !id.HasScope("streaming") || id.HasScope("billing") {
+		t.Errorf("unexpected identity %+v", id)
+	}
+
+	if _, err := NewVerifier([]byte("other"), "").Verify(token); err == nil {
+		t.Error("expected signature error")
+	}
+	if _, err := NewVerifier(key, "someone-else").Verify(token); err == nil {
+		t.Error("expected issuer error")
+	}
+	expired, _ := Sign(key, "", Identity{Subject: "bob"}, -time.Minute)
+	if _, err := NewVerifier(key, "").Verify(expired); err == nil {
+		t.Error("expected expiry error")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{
+		Public: map[string]bool{"/health": true},
+		Scopes: map[string][]string{"/chat": {"streaming"}},
+	}
+	if !p.IsPublic("/health") || p.IsPublic("/chat") {
+		t.Error("unexpected public methods")
+	}
+	if m := p.Missing("/chat", &Identity{Subject: "a"}); len(m) != 1 || m[0] != "streaming" {
+		t.Errorf("missing = %v", m)
+	}
+	if m := p.Missing("/chat", &Identity{Subject: "a", Scopes: []string{"streaming"}}); len(m) != 0 {
+		t.Errorf("missing = %v", m)
+	}
+	if m := p.Missing("/add", &Identity{Subject: "a"}); len(m) != 0 {
+		t.Errorf("missing = %v", m)
+	}
+}
@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type identityCtxKey struct{}
+
+// NewContext 将调用方身份放入上下文
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityCtxKey{}, id)
+}
+
+// FromContext 获取调用方身份，未认证时返回 false
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityCtxKey{}).(*Identity)
+	return id, ok
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "context"
+
+// TokenCredentials 实现 credentials.PerRPCCredentials，为每次调用附带 Bearer token
+type TokenCredentials struct {
+	Token string
+	// AllowInsecure 允许在明文连接上发送 token，仅用于本地调试
+	AllowInsecure bool
+}
+
+func (c TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.Token}, nil
+}
+
+func (c TokenCredentials) RequireTransportSecurity() bool {
+	return !c.AllowInsecure
+}
@@ -0,0 +1,25 @@
+package auth
+
+// Policy 按方法配置的授权策略，key 为完整方法名
+type Policy struct {
+	// Public 无需认证的方法，例如健康检查
+	Public map[string]bool
+	// Scopes 调用方法需要同时具备的 scope；未列出的方法只要求通过认证
+	Scopes map[string][]string
+}
+
+// IsPublic 方法是否无需认证
+func (p Policy) IsPublic(method string) bool {
+	return p.Public[method]
+}
+
+// Missing 返回调用方缺少的 scope，为空表示允许调用
+func (p Policy) Missing(method string, id *Identity) []string {
+	var missing []string
+	for _, s := range p.Scopes[method] {
+		if !id.HasScope(s) {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
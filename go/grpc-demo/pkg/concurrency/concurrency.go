@@ -0,0 +1,214 @@
+package concurrency
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Config 自适应并发上限的参数，零值字段使用默认值
+type Config struct {
+	// Initial 初始上限，默认 100
+	Initial int
+	// Min、Max 上限的调整范围，默认 10 和 1000
+	Min int
+	Max int
+	// Tolerance 短期延迟超过长期延迟多少倍时才开始收缩上限，默认 1.5
+	Tolerance float64
+	// Smoothing 每次调整向新估计值靠近的比例，默认 0.2
+	Smoothing float64
+	// Backoff 请求超时（被丢弃）时上限乘以的系数，默认 0.9
+	Backoff float64
+	// Window 统计延迟的窗口，每个窗口结束时调整一次上限，默认 1s
+	Window time.Duration
+}
+
+// withDefaults 填充零值字段并保证 Min <= Initial <= Max
+func (c Config) withDefaults() Config {
+	if c.Min <= 0 {
+		c.Min = 10
+	}
+	if c.Max <= 0 {
+		c.Max = max(1000, c.Min)
+	}
+	if c.Initial <= 0 {
+		c.Initial = 100
+	}
+	c.Min = min(c.Min, c.Max)
+	c.Initial = min(max(c.Initial, c.Min), c.Max)
+	if c.Tolerance < 1 {
+		c.Tolerance = 1.5
+	}
+	if c.Smoothing <= 0 || c.Smoothing > 1 {
+		c.Smoothing = 0.2
+	}
+	if c.Backoff <= 0 || c.Backoff >= 1 {
+		c.Backoff = 0.9
+	}
+	if c.Window <= 0 {
+		c.Window = time.Second
+	}
+	return c
+}
+
+const (
+	// minWindowSamples 窗口至少包含的样本数，样本太少时延迟的平均值没有意义
+	minWindowSamples = 5
+	// longWindows 长期延迟按最近多少个窗口做指数平均
+	longWindows = 600
+)
+
+// Limiter 按观测到的延迟调整并发上限（梯度算法）：每个窗口的平均延迟接近长期延迟时上限增加约 sqrt(limit)，
+// 窗口延迟升高说明请求在排队，上限按长期与窗口延迟之比收缩，每次最多减半；请求超时按 Backoff 乘性减小
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	shed     uint64
+	exempt   map[string]bool
+
+	// 当前窗口的开始时间、延迟之和（纳秒）、样本数和最大并发
+	windowStart    time.Time
+	windowSum      float64
+	windowSamples  int
+	windowInFlight int
+	// longRTT 各窗口平均延迟的长期指数平均，0 表示还没有完整的窗口
+	longRTT float64
+	windows int
+}
+
+// New 创建限制器
+func New(cfg Config) *Limiter {
+	cfg = cfg.withDefaults()
+	return &Limiter{cfg: cfg, now: time.Now, limit: float64(cfg.Initial), exempt: map[string]bool{}}
+}
+
+// Exempt 这些完整方法名不受并发上限约束，也不计入进行中的请求；用于健康检查等必须及时响应的接口
+func (l *Limiter) Exempt(methods ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range methods {
+		l.exempt[m] = true
+	}
+}
+
+// Limit 当前的并发上限
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight 当前进行中的请求数
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Shed 因超过上限被拒绝的请求总数
+func (l *Limiter) Shed() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.shed
+}
+
+// Acquire 为一次 method 调用占用一个名额；进行中的请求已达到上限时返回 false，
+// 否则调用结束时必须调用返回值的 Done、Dropped 或 Ignore 之一
+func (l *Limiter) Acquire(method string) (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.exempt[method] {
+		return &Token{}, true
+	}
+	if l.inFlight >= int(l.limit) {
+		l.shed++
+		return nil, false
+	}
+	l.inFlight++
+	return &Token{l: l, start: l.now(), inFlight: l.inFlight}, true
+}
+
+// Token 一次被放行的调用
+type Token struct {
+	l        *Limiter
+	start    time.Time
+	inFlight int
+	once     sync.Once
+}
+
+// Done 调用正常结束，耗时作为延迟样本
+func (t *Token) Done() {
+	t.release(func(l *Limiter) { l.sample(l.now().Sub(t.start), t.inFlight) })
+}
+
+// Dropped 调用超时或因过载失败，上限按 Backoff 减小
+func (t *Token) Dropped() {
+	t.release(func(l *Limiter) { l.setLimit(l.limit * l.cfg.Backoff) })
+}
+
+// Ignore 只释放名额，不调整上限；用于耗时不反映服务端负载的调用，如长时间的流
+func (t *Token) Ignore() {
+	t.release(func(*Limiter) {})
+}
+
+func (t *Token) release(update func(*Limiter)) {
+	if t.l == nil {
+		return
+	}
+	t.once.Do(func() {
+		t.l.mu.Lock()
+		defer t.l.mu.Unlock()
+		t.l.inFlight--
+		update(t.l)
+	})
+}
+
+// sample 记录一个延迟样本，窗口结束时调整上限；inFlight 为该请求开始时进行中的请求数。调用方持有 mu
+func (l *Limiter) sample(rtt time.Duration, inFlight int) {
+	now := l.now()
+	if l.windowSamples == 0 {
+		l.windowStart = now
+	}
+	l.windowSum += float64(max(rtt, time.Microsecond))
+	l.windowSamples++
+	l.windowInFlight = max(l.windowInFlight, inFlight)
+	if l.windowSamples < minWindowSamples || now.Sub(l.windowStart) < l.cfg.Window {
+		return
+	}
+	short := l.windowSum / float64(l.windowSamples)
+	peak := l.windowInFlight
+	l.windowSum, l.windowSamples, l.windowInFlight = 0, 0, 0
+
+	l.windows++
+	if l.windows == 1 {
+		l.longRTT = short
+		return
+	}
+	l.longRTT = ewma(l.longRTT, short, longWindows)
+	// 延迟长期偏高时让长期平均更快跟上，避免上限一直被压在下限
+	if l.longRTT*2 < short {
+		l.longRTT *= 1.05
+	}
+	// 并发远低于上限时延迟不反映上限是否合适，不调整，防止空闲时上限无限增长
+	if float64(peak) < l.limit/2 {
+		return
+	}
+	gradient := max(0.5, min(1, l.cfg.Tolerance*l.longRTT/short))
+	next := l.limit*gradient + math.Sqrt(l.limit)
+	l.setLimit(l.limit*(1-l.cfg.Smoothing) + next*l.cfg.Smoothing)
+}
+
+// setLimit 把上限限制在 [Min, Max] 内；调用方持有 mu
+func (l *Limiter) setLimit(v float64) {
+	l.limit = min(max(v, float64(l.cfg.Min)), float64(l.cfg.Max))
+}
+
+// ewma 最近 n 个值的指数加权平均
+func ewma(avg, v float64, n int) float64 {
+	alpha := 2 / float64(n+1)
+	return avg*(1-alpha) + v*alpha
+}
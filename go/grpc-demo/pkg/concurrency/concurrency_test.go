@@ -0,0 +1,115 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+const add = "/calculator.v1.CalculatorService/Add"
+
+// fakeClock 手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newLimiter(cfg Config) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := New(cfg)
+	l.now = clock.now
+	return l, clock
+}
+
+// run 放行 n 个并发请求，每个耗时 rtt；返回被拒绝的个数
+func run(l *Limiter, clock *fakeClock, n int, rtt time.Duration) int {
+	var tokens []*Token
+	rejected := 0
+	for range n {
+		if tk, ok := l.Acquire(add); ok {
+			tokens = append(tokens, tk)
+		} else {
+			rejected++
+		}
+	}
+	clock.t = clock.t.Add(rtt)
+	for _, tk := range tokens {
+		tk.Done()
+	}
+	return rejected
+}
+
+func TestShed(t *testing.T) {
+	l, _ := newLimiter(Config{Initial: 2, Min: 1})
+	a, ok := l.Acquire(add)
+	if !ok {
+		t.Fatal("first request rejected")
+	}
+	if _, ok := l.Acquire(add); !ok {
+		t.Fatal("second request rejected")
+	}
+	if _, ok := l.Acquire(add); ok {
+		t.Fatal("request over the limit admitted")
+	}
+	// 豁免的方法不占名额
+	l.Exempt("/grpc.health.v1.Health/Check")
+	if _, ok := l.Acquire("/grpc.health.v1.Health/Check"); !ok {
+		t.Fatal("exempt method rejected")
+	}
+	a.Ignore()
+	a.Ignore()
+	if l.InFlight() != 1 || l.Shed() != 1 || l.Limit() != 2 {
+		t.Fatalf("in flight = %d, shed = %d, limit = %d", l.InFlight(), l.Shed(), l.Limit())
+	}
+	if _, ok := l.Acquire(add); !ok {
+		t.Fatal("request rejected after a release")
+	}
+}
+
+func TestGradient(t *testing.T) {
+	l, clock := newLimiter(Config{Initial: 20, Min: 5, Max: 100, Window: 10 * time.Millisecond})
+	// 并发打满、延迟稳定时上限增长，直到 Max
+	for range 100 {
+		run(l, clock, l.Limit(), 10*time.Millisecond)
+	}
+	if got := l.Limit(); got != 100 {
+		t.Fatalf("limit under steady latency = %d, want 100", got)
+	}
+	// 延迟升高到 5 倍后上限收缩，多余的请求被拒绝
+	for range 10 {
+		run(l, clock, 100, 50*time.Millisecond)
+	}
+	shrunk := l.Limit()
+	if shrunk >= 50 {
+		t.Fatalf("limit after latency rose = %d", shrunk)
+	}
+	if rejected := run(l, clock, 100, 50*time.Millisecond); rejected != 100-shrunk {
+		t.Fatalf("rejected %d of 100 with limit %d", rejected, shrunk)
+	}
+	// 延迟恢复后上限重新增长
+	for range 50 {
+		run(l, clock, l.Limit(), 10*time.Millisecond)
+	}
+	if got := l.Limit(); got <= shrunk {
+		t.Fatalf("limit after latency recovered = %d, was %d", got, shrunk)
+	}
+}
+
+func TestIdleDoesNotGrow(t *testing.T) {
+	l, clock := newLimiter(Config{Initial: 20, Window: time.Millisecond})
+	for range 100 {
+		run(l, clock, 1, time.Millisecond)
+	}
+	if got := l.Limit(); got != 20 {
+		t.Fatalf("limit = %d while mostly idle, want 20", got)
+	}
+}
+
+func TestDropped(t *testing.T) {
+	l, _ := newLimiter(Config{Initial: 20, Min: 15})
+	for range 5 {
+		tk, _ := l.Acquire(add)
+		tk.Dropped()
+	}
+	if got := l.Limit(); got != 15 {
+		t.Fatalf("limit after drops = %d, want the minimum 15", got)
+	}
+}
@@ -0,0 +1,152 @@
+package fault
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Rule 一个方法的故障规则。每次调用分别按比例决定是否延迟、是否中止，比例为 0~100，0 表示 100
+type Rule struct {
+	// Method 完整方法名、只写方法名如 Add，或 * 匹配所有方法
+	Method       string
+	Delay        time.Duration
+	DelayPercent float64
+	// AbortCode 为 OK 且 CutAfter 为 0 时不中止；设置了 CutAfter 时默认为 Unavailable
+	AbortCode    codes.Code
+	AbortPercent float64
+	// CutAfter 收发这么多条消息后才中止，0 表示调用开始时就中止
+	CutAfter int
+}
+
+// Validate 检查规则是否合法
+func (r Rule) Validate() error {
+	switch {
+	case r.Method == "":
+		return fmt.Errorf("fault rule: method is required")
+	case r.Delay < 0:
+		return fmt.Errorf("fault rule for %s: negative delay %s", r.Method, r.Delay)
+	case r.DelayPercent < 0 || r.DelayPercent > 100, r.AbortPercent < 0 || r.AbortPercent > 100:
+		return fmt.Errorf("fault rule for %s: percent must be between 0 and 100", r.Method)
+	case r.AbortCode > codes.Unauthenticated:
+		return fmt.Errorf("fault rule for %s: unknown status code %d", r.Method, r.AbortCode)
+	case r.CutAfter < 0:
+		return fmt.Errorf("fault rule for %s: negative cut_after %d", r.Method, r.CutAfter)
+	}
+	return nil
+}
+
+// code 中止时使用的状态码，不中止时为 OK
+func (r Rule) code() codes.Code {
+	if r.AbortCode == codes.OK && r.CutAfter > 0 {
+		return codes.Unavailable
+	}
+	return r.AbortCode
+}
+
+// specificity 规则匹配 method 的具体程度，0 表示不匹配
+func (r Rule) specificity(method string) int {
+	switch {
+	case r.Method == method:
+		return 3
+	case !strings.HasPrefix(r.Method, "/") && strings.HasSuffix(method, "/"+r.Method):
+		return 2
+	case r.Method == "*":
+		return 1
+	}
+	return 0
+}
+
+// Fault 一次调用抽中的故障
+type Fault struct {
+	// Delay 调用开始前的延迟，0 表示不延迟
+	Delay time.Duration
+	// Code 中止的状态码，OK 表示不中止
+	Code codes.Code
+	// CutAfter 收发这么多条消息后中止
+	CutAfter int
+}
+
+// Injector 按方法匹配故障规则，规则可以在运行中整体替换
+type Injector struct {
+	mu     sync.RWMutex
+	rules  []Rule
+	exempt map[string]bool
+	// percent 返回 [0, 100) 的随机数，测试中可以替换
+	percent func() float64
+}
+
+// New 创建注入器，rules 不合法时返回错误
+func New(rules ...Rule) (*Injector, error) {
+	i := &Injector{exempt: map[string]bool{}, percent: func() float64 { return rand.Float64() * 100 }}
+	if err := i.Set(rules); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Set 整体替换规则，rules 为空即清除所有规则；有任何一条不合法时保留原规则
+func (i *Injector) Set(rules []Rule) error {
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append([]Rule(nil), rules...)
+	return nil
+}
+
+// Exempt 不对这些完整方法名注入故障，即使有规则匹配；用于保护管理接口，避免 * 规则让故障无法撤销
+func (i *Injector) Exempt(methods ...string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, m := range methods {
+		i.exempt[m] = true
+	}
+}
+
+// Rules 返回当前规则的副本
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]Rule(nil), i.rules...)
+}
+
+// Pick 为一次 method 调用抽取故障；没有匹配的规则或都没有抽中时返回 false
+func (i *Injector) Pick(method string) (Fault, bool) {
+	i.mu.RLock()
+	if i.exempt[method] {
+		i.mu.RUnlock()
+		return Fault{}, false
+	}
+	var rule Rule
+	best := 0
+	for _, r := range i.rules {
+		if s := r.specificity(method); s > best {
+			rule, best = r, s
+		}
+	}
+	i.mu.RUnlock()
+	if best == 0 {
+		return Fault{}, false
+	}
+
+	var f Fault
+	if rule.Delay > 0 && i.hit(rule.DelayPercent) {
+		f.Delay = rule.Delay
+	}
+	if code := rule.code(); code != codes.OK && i.hit(rule.AbortPercent) {
+		f.Code, f.CutAfter = code, rule.CutAfter
+	}
+	return f, f.Delay > 0 || f.Code != codes.OK
+}
+
+func (i *Injector) hit(percent float64) bool {
+	return percent == 0 || percent >= 100 || i.percent() < percent
+}
@@ -0,0 +1,88 @@
+package fault
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const add = "/calculator.v1.CalculatorService/Add"
+
+func TestPickMostSpecific(t *testing.T) {
+	i, err := New(
+		Rule{Method: "*", Delay: time.Second},
+		Rule{Method: "Add", AbortCode: codes.Unavailable},
+		Rule{Method: "/calculator.v1.CalculatorService/RangeAdd", CutAfter: 3},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := i.Pick(add); !ok || f.Code != codes.Unavailable || f.Delay != 0 {
+		t.Fatalf("Add = %+v, %v", f, ok)
+	}
+	// 只写方法名时按完整的方法名匹配，不会匹配到 RangeAdd
+	if f, ok := i.Pick("/calculator.v1.CalculatorService/RangeAdd"); !ok || f.Code != codes.Unavailable || f.CutAfter != 3 {
+		t.Fatalf("RangeAdd = %+v, %v", f, ok)
+	}
+	if f, ok := i.Pick("/calculator.v1.CalculatorService/Subtract"); !ok || f.Delay != time.Second || f.Code != codes.OK {
+		t.Fatalf("Subtract = %+v, %v", f, ok)
+	}
+
+	i.Exempt(add)
+	if f, ok := i.Pick(add); ok {
+		t.Fatalf("picked %+v for an exempt method", f)
+	}
+
+	if err := i.Set(nil); err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := i.Pick("/calculator.v1.CalculatorService/Subtract"); ok {
+		t.Fatalf("picked %+v after clearing the rules", f)
+	}
+}
+
+func TestPickPercent(t *testing.T) {
+	i, err := New(Rule{Method: "Add", Delay: time.Millisecond, DelayPercent: 30, AbortCode: codes.Internal, AbortPercent: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		roll     float64
+		delay    bool
+		aborted  bool
+		injected bool
+	}{
+		{roll: 10, delay: true, aborted: true, injected: true},
+		{roll: 50, delay: false, aborted: true, injected: true},
+		{roll: 90, delay: false, aborted: false, injected: false},
+	} {
+		i.percent = func() float64 { return tc.roll }
+		f, ok := i.Pick(add)
+		if ok != tc.injected || (f.Delay > 0) != tc.delay || (f.Code != codes.OK) != tc.aborted {
+			t.Errorf("roll %v: %+v, %v", tc.roll, f, ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	i, err := New(Rule{Method: "Add", AbortCode: codes.Internal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []Rule{
+		{},
+		{Method: "Add", Delay: -time.Second},
+		{Method: "Add", AbortPercent: 101},
+		{Method: "Add", AbortCode: 17},
+		{Method: "Add", CutAfter: -1},
+	} {
+		if err := i.Set([]Rule{{Method: "*"}, r}); err == nil {
+			t.Errorf("rule %+v accepted", r)
+		}
+	}
+	// 不合法的规则不会替换原有规则
+	if rules := i.Rules(); len(rules) != 1 || rules[0].AbortCode != codes.Internal {
+		t.Fatalf("rules = %+v", rules)
+	}
+}
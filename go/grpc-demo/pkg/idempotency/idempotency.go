@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL 结果默认保留时长
+	DefaultTTL = 10 * time.Minute
+	// DefaultLimit 默认最多保留的 key 数
+	DefaultLimit = 100000
+	// sweepInterval 清理过期结果的间隔
+	sweepInterval = time.Minute
+)
+
+var (
+	// ErrMismatch 同一个 key 已经用于内容不同的请求
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+	// ErrFull 保留的 key 数达到上限
+	ErrFull = errors.New("too many idempotency keys in flight")
+)
+
+// Result 保存的调用结果
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{} // 第一次调用完成后关闭
+	result      *Result       // 为 nil 表示结果没有保留，等待者需要重新执行
+	expires     time.Time
+}
+
+// Cache 按 (调用方, key) 保存调用结果；同一个 key 的并发请求只执行一次，其余的等待并复用结果
+type Cache struct {
+	ttl   time.Duration
+	limit int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// New 创建缓存；ttl 或 limit 不大于 0 时使用默认值
+func New(ttl time.Duration, limit int) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Cache{
+		ttl:     ttl,
+		limit:   limit,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Call 第一次使用某个 key 的调用，执行完毕后必须调用 Finish
+type Call struct {
+	c  *Cache
+	id string
+	e  *entry
+}
+
+// Begin 登记一次带 key 的调用。key 已有保存的结果时返回该结果；
+// 同一个 key 正在执行时等待它完成；否则返回 Call，由调用方执行请求。
+// fingerprint 标识请求内容，与之前不一致时返回 ErrMismatch
+func (c *Cache) Begin(ctx context.Context, caller, key, fingerprint string) (*Result, *Call, error) {
+	id := caller + "\x00" + key
+	for {
+		c.mu.Lock()
+		now := c.now()
+		c.sweep(now)
+		e, ok := c.entries[id]
+		if ok && e.result != nil && !now.Before(e.expires) {
+			delete(c.entries, id)
+			ok = false
+		}
+		if !ok {
+			if len(c.entries) >= c.limit {
+				c.mu.Unlock()
+				return nil, nil, ErrFull
+			}
+			e = &entry{fingerprint: fingerprint, done: make(chan struct{})}
+			c.entries[id] = e
+			c.mu.Unlock()
+			return nil, &Call{c: c, id: id, e: e}, nil
+		}
+		c.mu.Unlock()
+		if e.fingerprint != fingerprint {
+			return nil, nil, ErrMismatch
+		}
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if e.result != nil {
+			return e.result, nil, nil
+		}
+		// 第一次调用的结果没有保留（例如可以重试的错误），重新争取执行
+	}
+}
+
+// Finish 记录调用结果；keep 为 false 时不保留，下一个使用该 key 的请求会重新执行
+func (call *Call) Finish(r Result, keep bool) {
+	c := call.c
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if keep {
+		call.e.result = &r
+		call.e.expires = c.now().Add(c.ttl)
+	} else {
+		delete(c.entries, call.id)
+	}
+	close(call.e.done)
+}
+
+// sweep 删除过期的结果，正在执行的调用不受影响；调用方持有 c.mu
+func (c *Cache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < min(sweepInterval, c.ttl) {
+		return
+	}
+	c.lastSweep = now
+	for id, e := range c.entries {
+		if e.result != nil && !now.Before(e.expires) {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := New(time.Minute, 0)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	saved, call, err := c.Begin(ctx, "alice", "k1", "add 1 2")
+	if err != nil || saved != nil || call == nil {
+		t.Fatalf("first Begin = %v, %v, %v", saved, call, err)
+	}
+	call.Finish(Result{Value: 3}, true)
+
+	saved, _, err = c.Begin(ctx, "alice", "k1", "add 1 2")
+	if err != nil || saved == nil || saved.Value != 3 {
+		t.Fatalf("replay = %v, %v", saved, err)
+	}
+	if _, _, err := c.Begin(ctx, "alice", "k1", "add 1 5"); err != ErrMismatch {
+		t.Fatalf("different payload = %v", err)
+	}
+	// key 按调用方隔离
+	if saved, call, err := c.Begin(ctx, "bob", "k1", "add 1 5"); err != nil || saved != nil || call == nil {
+		t.Fatalf("other caller = %v, %v, %v", saved, call, err)
+	}
+
+	now = now.Add(time.Minute)
+	if saved, call, err := c.Begin(ctx, "alice", "k1", "add 1 5"); err != nil || saved != nil || call == nil {
+		t.Fatalf("after ttl = %v, %v, %v", saved, call, err)
+	}
+}
+
+func TestNotKept(t *testing.T) {
+	c := New(0, 0)
+	ctx := context.Background()
+	_, call, _ := c.Begin(ctx, "alice", "k1", "f")
+	call.Finish(Result{}, false)
+	if saved, call, err := c.Begin(ctx, "alice", "k1", "f"); err != nil || saved != nil || call == nil {
+		t.Fatalf("retry after unkept result = %v, %v, %v", saved, call, err)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	c := New(0, 0)
+	var runs atomic.Int32
+	var wg sync.WaitGroup
+	results := make([]interface{}, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saved, call, err := c.Begin(context.Background(), "alice", "k1", "f")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if saved != nil {
+				results[i] = saved.Value
+				return
+			}
+			runs.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			call.Finish(Result{Value: "done"}, true)
+			results[i] = "done"
+		}()
+	}
+	wg.Wait()
+	if runs.Load() != 1 {
+		t.Fatalf("executed %d times", runs.Load())
+	}
+	for i, r := range results {
+		if r != "done" {
+			t.Fatalf("result %d = %v", i, r)
+		}
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	c := New(0, 0)
+	_, call, _ := c.Begin(context.Background(), "alice", "k1", "f")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.Begin(ctx, "alice", "k1", "f"); err != context.DeadlineExceeded {
+		t.Fatalf("waiting Begin = %v", err)
+	}
+	call.Finish(Result{}, true)
+}
+
+func TestLimit(t *testing.T) {
+	c := New(0, 1)
+	c.Begin(context.Background(), "alice", "k1", "f")
+	if _, _, err := c.Begin(context.Background(), "alice", "k2", "f"); err != ErrFull {
+		t.Fatalf("second key = %v", err)
+	}
+}
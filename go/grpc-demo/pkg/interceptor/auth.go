@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Auth 认证授权配置
+type Auth struct {
+	Verifier *auth.Verifier
+	Policy   auth.Policy
+}
+
+// UnaryServerAuth 校验 Bearer token 并按策略检查 scope，身份放入上下文
+func UnaryServerAuth(a *Auth) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerAuth 流式版本的认证授权拦截器
+func StreamServerAuth(a *Auth) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, WrapServerStream(ss, ctx))
+	}
+}
+
+func (a *Auth) authorize(ctx context.Context, method string) (context.Context, error) {
+	if a.Policy.IsPublic(method) {
+		return ctx, nil
+	}
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := a.Verifier.Verify(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	if missing := a.Policy.Missing(method, id); len(missing) > 0 {
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires scope %s", method, strings.Join(missing, ", "))
+	}
+	return auth.NewContext(ctx, id), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization header")
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization header must be \"Bearer <token>\"")
+	}
+	return token, nil
+}
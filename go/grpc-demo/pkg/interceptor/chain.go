@@ -0,0 +1,126 @@
+package interceptor
+
+import (
+	"github.com/MorseWayne/grpc-demo/pkg/concurrency"
+	"github.com/MorseWayne/grpc-demo/pkg/fault"
+	"github.com/MorseWayne/grpc-demo/pkg/idempotency"
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
+	"github.com/MorseWayne/grpc-demo/pkg/ratelimit"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"google.golang.org/grpc"
+)
+
+// Config 拦截器链配置
+type Config struct {
+	Timeouts Timeouts
+	// Concurrency 为 nil 时不限制并发；放在认证之前，过载时尽早拒绝，不再做验签等工作
+	Concurrency *concurrency.Limiter
+	// Auth 为 nil 时不做认证
+	Auth *Auth
+	// RateLimit 为 nil 时不限流；放在认证之后，以便按身份限流
+	RateLimit *ratelimit.Limiter
+	// Idempotency 为 nil 时忽略 idempotency-key；只作用于一元调用，放在限流之后，被限流的请求不会占用 key
+	Idempotency *idempotency.Cache
+	// Metrics 为 nil 时不记录指标；放在最外层，panic 被 recovery 转换后的状态码也能统计到
+	Metrics *metrics.Metrics
+	// Tracer 为 nil 时不创建 span，也不传递 traceparent；紧接在指标之后，同样位于 recovery 之外
+	Tracer *tracing.Tracer
+	// Fault 为 nil 时不注入故障；放在最内层，注入的延迟和错误与处理函数变慢、出错没有区别，
+	// 延迟同样受服务端超时约束
+	Fault *fault.Injector
+}
+
+// UnaryServerChain 服务端一元拦截器链：除指标和追踪外 recovery 在最外层，保证日志和超时中的 panic 也能被捕获
+func UnaryServerChain(cfg Config) []grpc.UnaryServerInterceptor {
+	var chain []grpc.UnaryServerInterceptor
+	if cfg.Metrics != nil {
+		chain = append(chain, UnaryServerMetrics(cfg.Metrics))
+	}
+	if cfg.Tracer != nil {
+		chain = append(chain, UnaryServerTracing(cfg.Tracer))
+	}
+	chain = append(chain,
+		UnaryServerRecovery(),
+		UnaryServerRequestID(),
+		UnaryServerLogging(),
+	)
+	if cfg.Concurrency != nil {
+		chain = append(chain, UnaryServerConcurrency(cfg.Concurrency))
+	}
+	if cfg.Auth != nil {
+		chain = append(chain, UnaryServerAuth(cfg.Auth))
+	}
+	if cfg.RateLimit != nil {
+		chain = append(chain, UnaryServerRateLimit(cfg.RateLimit))
+	}
+	if cfg.Idempotency != nil {
+		chain = append(chain, UnaryServerIdempotency(cfg.Idempotency))
+	}
+	chain = append(chain, UnaryServerTimeout(cfg.Timeouts))
+	if cfg.Fault != nil {
+		chain = append(chain, UnaryServerFault(cfg.Fault))
+	}
+	return chain
+}
+
+// StreamServerChain 服务端流式拦截器链
+func StreamServerChain(cfg Config) []grpc.StreamServerInterceptor {
+	var chain []grpc.StreamServerInterceptor
+	if cfg.Metrics != nil {
+		chain = append(chain, StreamServerMetrics(cfg.Metrics))
+	}
+	if cfg.Tracer != nil {
+		chain = append(chain, StreamServerTracing(cfg.Tracer))
+	}
+	chain = append(chain,
+		StreamServerRecovery(),
+		StreamServerRequestID(),
+		StreamServerLogging(),
+	)
+	if cfg.Concurrency != nil {
+		chain = append(chain, StreamServerConcurrency(cfg.Concurrency))
+	}
+	if cfg.Auth != nil {
+		chain = append(chain, StreamServerAuth(cfg.Auth))
+	}
+	if cfg.RateLimit != nil {
+		chain = append(chain, StreamServerRateLimit(cfg.RateLimit))
+	}
+	chain = append(chain, StreamServerTimeout(cfg.Timeouts))
+	if cfg.Fault != nil {
+		chain = append(chain, StreamServerFault(cfg.Fault))
+	}
+	return chain
+}
+
+// UnaryClientChain 客户端一元拦截器链
+func UnaryClientChain(cfg Config) []grpc.UnaryClientInterceptor {
+	var chain []grpc.UnaryClientInterceptor
+	if cfg.Metrics != nil {
+		chain = append(chain, UnaryClientMetrics(cfg.Metrics))
+	}
+	if cfg.Tracer != nil {
+		chain = append(chain, UnaryClientTracing(cfg.Tracer))
+	}
+	return append(chain,
+		UnaryClientRequestID(),
+		UnaryClientLogging(),
+		UnaryClientTimeout(cfg.Timeouts),
+	)
+}
+
+// StreamClientChain 客户端流式拦截器链
+func StreamClientChain(cfg Config) []grpc.StreamClientInterceptor {
+	var chain []grpc.StreamClientInterceptor
+	if cfg.Metrics != nil {
+		chain = append(chain, StreamClientMetrics(cfg.Metrics))
+	}
+	if cfg.Tracer != nil {
+		chain = append(chain, StreamClientTracing(cfg.Tracer))
+	}
+	return append(chain,
+		StreamClientRequestID(),
+		StreamClientLogging(),
+		StreamClientTimeout(cfg.Timeouts),
+	)
+}
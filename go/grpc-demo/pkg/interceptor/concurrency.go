@@ -0,0 +1,60 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/MorseWayne/grpc-demo/pkg/concurrency"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OverloadedReason 因并发上限被拒绝时 ErrorInfo 中的 Reason
+const OverloadedReason = "OVERLOADED"
+
+// UnaryServerConcurrency 进行中的请求达到 l 的上限时直接以 Unavailable 拒绝；放行的调用以处理耗时调整上限，
+// 以 DeadlineExceeded 结束或 panic 的调用视为过载
+func UnaryServerConcurrency(l *concurrency.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		tk, ok := l.Acquire(info.FullMethod)
+		if !ok {
+			return nil, overloaded(l, info.FullMethod)
+		}
+		finished := false
+		// Recovery 在外层，handler panic 时也要归还名额
+		defer func() {
+			if !finished {
+				tk.Dropped()
+			}
+		}()
+		resp, err := handler(ctx, req)
+		finished = true
+		if status.Code(err) == codes.DeadlineExceeded {
+			tk.Dropped()
+		} else {
+			tk.Done()
+		}
+		return resp, err
+	}
+}
+
+// StreamServerConcurrency 流式版本：流在整个生命周期内占用一个名额，但流的时长取决于客户端，不用于调整上限
+func StreamServerConcurrency(l *concurrency.Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		tk, ok := l.Acquire(info.FullMethod)
+		if !ok {
+			return overloaded(l, info.FullMethod)
+		}
+		defer tk.Ignore()
+		return handler(srv, ss)
+	}
+}
+
+func overloaded(l *concurrency.Limiter, method string) error {
+	st := status.Newf(codes.Unavailable, "server overloaded, %s rejected at concurrency limit %d", method, l.Limit())
+	if ds, err := st.WithDetails(&errdetails.ErrorInfo{Reason: OverloadedReason, Domain: "grpc-demo"}); err == nil {
+		st = ds
+	}
+	return st.Err()
+}
@@ -0,0 +1,132 @@
+package interceptor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/pkg/fault"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FaultInjectedReason 注入的错误在 ErrorInfo 中的 Reason，便于和真实错误区分
+const FaultInjectedReason = "FAULT_INJECTED"
+
+// UnaryServerFault 按 inj 的规则注入延迟和错误；设置了 cut_after 时处理函数照常执行，响应被替换为错误，
+// 相当于响应在路上丢失
+func UnaryServerFault(inj *fault.Injector) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		f, ok := inj.Pick(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+		if err := faultDelay(ctx, f.Delay); err != nil {
+			return nil, err
+		}
+		if f.Code == codes.OK {
+			return handler(ctx, req)
+		}
+		if f.CutAfter == 0 {
+			return nil, faultError(f, info.FullMethod)
+		}
+		if _, err := handler(ctx, req); err != nil {
+			return nil, err
+		}
+		return nil, faultError(f, info.FullMethod)
+	}
+}
+
+// StreamServerFault 流式版本；设置了 cut_after 时，收发的消息数达到 cut_after 后，
+// 之后的 SendMsg/RecvMsg 都返回注入的错误，调用也以该错误结束
+func StreamServerFault(inj *fault.Injector) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		f, ok := inj.Pick(info.FullMethod)
+		if !ok {
+			return handler(srv, ss)
+		}
+		if err := faultDelay(ss.Context(), f.Delay); err != nil {
+			return err
+		}
+		if f.Code == codes.OK {
+			return handler(srv, ss)
+		}
+		errFault := faultError(f, info.FullMethod)
+		if f.CutAfter == 0 {
+			return errFault
+		}
+		cs := &cutServerStream{ServerStream: ss, left: f.CutAfter, err: errFault}
+		err := handler(srv, cs)
+		if cs.isCut() {
+			return errFault
+		}
+		return err
+	}
+}
+
+// faultDelay 等待 d，调用方取消或超时时提前返回
+func faultDelay(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func faultError(f fault.Fault, method string) error {
+	st := status.Newf(f.Code, "fault injected into %s", method)
+	if ds, err := st.WithDetails(&errdetails.ErrorInfo{Reason: FaultInjectedReason, Domain: "grpc-demo"}); err == nil {
+		st = ds
+	}
+	return st.Err()
+}
+
+// cutServerStream 收发 left 条消息后中断的 ServerStream
+type cutServerStream struct {
+	grpc.ServerStream
+	err error
+
+	mu   sync.Mutex
+	left int
+	cut  bool
+}
+
+// take 消耗一条消息的额度，额度用完时返回 false
+func (s *cutServerStream) take() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.left == 0 {
+		s.cut = true
+		return false
+	}
+	s.left--
+	return true
+}
+
+func (s *cutServerStream) isCut() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cut
+}
+
+func (s *cutServerStream) SendMsg(m interface{}) error {
+	if !s.take() {
+		return s.err
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *cutServerStream) RecvMsg(m interface{}) error {
+	if !s.take() {
+		return s.err
+	}
+	return s.ServerStream.RecvMsg(m)
+}
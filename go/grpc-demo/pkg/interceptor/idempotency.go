@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/MorseWayne/grpc-demo/pkg/idempotency"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端为一次逻辑调用生成的唯一 key，重试时保持不变
+	IdempotencyKeyHeader = "idempotency-key"
+	// IdempotentReplayHeader 响应来自保存的结果时，服务端在 header 中设置为 "true"
+	IdempotentReplayHeader = "idempotent-replayed"
+	// maxIdempotencyKeyLen key 的最大长度
+	maxIdempotencyKeyLen = 255
+)
+
+// finalCodes 重试也不会改变结果的状态码，这些错误和成功结果一样被保存；
+// 其余错误（如 Unavailable、ResourceExhausted）不保存，带同一个 key 重试会重新执行
+var finalCodes = map[codes.Code]bool{
+	codes.OK:                 true,
+	codes.InvalidArgument:    true,
+	codes.OutOfRange:         true,
+	codes.FailedPrecondition: true,
+	codes.NotFound:           true,
+	codes.AlreadyExists:      true,
+	codes.Unimplemented:      true,
+}
+
+// UnaryServerIdempotency 带 idempotency-key 的一元调用在 key 有效期内只执行一次：
+// 同一调用方用同一个 key 重放时直接返回保存的结果，key 用于不同的方法或请求内容时返回 InvalidArgument。
+// 需要放在认证之后，以便按身份区分调用方
+func UnaryServerIdempotency(c *idempotency.Cache) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := incomingIdempotencyKey(ctx)
+		if key == "" {
+			return handler(ctx, req)
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			return nil, status.Errorf(codes.InvalidArgument, "%s longer than %d bytes", IdempotencyKeyHeader, maxIdempotencyKeyLen)
+		}
+		fingerprint, err := requestFingerprint(info.FullMethod, req)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "fingerprint request: %v", err)
+		}
+		saved, call, err := c.Begin(ctx, CallerKey(ctx), key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			st := status.New(codes.InvalidArgument, err.Error())
+			if ds, derr := st.WithDetails(&errdetails.ErrorInfo{
+				Reason:   "IDEMPOTENCY_KEY_REUSED",
+				Domain:   "grpc-demo",
+				Metadata: map[string]string{"key": key, "method": info.FullMethod},
+			}); derr == nil {
+				st = ds
+			}
+			return nil, st.Err()
+		case errors.Is(err, idempotency.ErrFull):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		case err != nil:
+			return nil, status.FromContextError(err).Err()
+		case saved != nil:
+			_ = grpc.SetHeader(ctx, metadata.Pairs(IdempotentReplayHeader, "true"))
+			return cloneMessage(saved.Value), saved.Err
+		}
+
+		finished := false
+		// handler panic 时不保留结果，让等待同一个 key 的请求重新执行
+		defer func() {
+			if !finished {
+				call.Finish(idempotency.Result{}, false)
+			}
+		}()
+		resp, err := handler(ctx, req)
+		call.Finish(idempotency.Result{Value: cloneMessage(resp), Err: err}, finalCodes[status.Code(err)])
+		finished = true
+		return resp, err
+	}
+}
+
+// WithIdempotencyKey 为这次调用附带 idempotency key，重试时使用同一个 ctx 即可复用
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, IdempotencyKeyHeader, key)
+}
+
+func incomingIdempotencyKey(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(IdempotencyKeyHeader); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// requestFingerprint 方法名加上请求的确定性序列化结果的摘要
+func requestFingerprint(method string, req interface{}) (string, error) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return method, nil
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return method + "\x00" + hex.EncodeToString(sum[:]), nil
+}
+
+// cloneMessage 保存和返回的都是副本，避免与正在序列化的响应共享
+func cloneMessage(v interface{}) interface{} {
+	if m, ok := v.(proto.Message); ok && m != nil {
+		return proto.Clone(m)
+	}
+	return v
+}
@@ -0,0 +1,448 @@
+package interceptor
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"github.com/MorseWayne/grpc-demo/pkg/concurrency"
+	"github.com/MorseWayne/grpc-demo/pkg/fault"
+	"github.com/MorseWayne/grpc-demo/pkg/idempotency"
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
+	"github.com/MorseWayne/grpc-demo/pkg/ratelimit"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var unaryInfo = &grpc.UnaryServerInfo{FullMethod: "/calculator.v1.CalculatorService/Add"}
+
+func TestUnaryServerRecovery(t *testing.T) {
+	_, err := UnaryServerRecovery()(context.Background(), nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+}
+
+func TestUnaryServerRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, "abc"))
+	var got string
+	_, _ = UnaryServerRequestID()(ctx, nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = RequestIDFromContext(ctx)
+			return nil, nil
+		})
+	if got != "abc" {
+		t.Errorf("expected request id abc, got %q", got)
+	}
+
+	_, _ = UnaryServerRequestID()(context.Background(), nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = RequestIDFromContext(ctx)
+			return nil, nil
+		})
+	if got == "" {
+		t.Error("expected generated request id")
+	}
+}
+
+func TestUnaryServerTimeout(t *testing.T) {
+	timeouts := Timeouts{
+		Default:   time.Second,
+		PerMethod: map[string]time.Duration{unaryInfo.FullMethod: 10 * time.Millisecond},
+	}
+	_, err := UnaryServerTimeout(timeouts)(context.Background(), nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// fakeServerStream 只提供 Context 的服务端流
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerTimeout(t *testing.T) {
+	const method = "/calculator.v1.CalculatorService/RangeAdd"
+	deadline := func(timeouts Timeouts) bool {
+		var ok bool
+		_ = StreamServerTimeout(timeouts)(nil, &fakeServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: method},
+			func(srv interface{}, ss grpc.ServerStream) error {
+				_, ok = ss.Context().Deadline()
+				return nil
+			})
+		return ok
+	}
+	// 一元调用的默认超时不作用于流
+	if deadline(Timeouts{Default: time.Second}) {
+		t.Error("Default applied to a stream")
+	}
+	if !deadline(Timeouts{StreamDefault: time.Second}) {
+		t.Error("StreamDefault not applied")
+	}
+	if !deadline(Timeouts{PerMethod: map[string]time.Duration{method: time.Second}}) {
+		t.Error("PerMethod not applied to a stream")
+	}
+	if deadline(Timeouts{StreamDefault: time.Second, PerMethod: map[string]time.Duration{method: 0}}) {
+		t.Error("exempt stream got a deadline")
+	}
+}
+
+func TestUnaryClientRequestID(t *testing.T) {
+	var md metadata.MD
+	err := UnaryClientRequestID()(context.Background(), "/m", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Get(RequestIDKey)) != 1 {
+		t.Errorf("expected one request id, got %v", md.Get(RequestIDKey))
+	}
+}
+
+func TestUnaryServerAuth(t *testing.T) {
+	key := []byte("secret")
+	a := &Auth{
+		Verifier: auth.NewVerifier(key, ""),
+		Policy:   auth.Policy{Scopes: map[string][]string{unaryInfo.FullMethod: {"streaming"}}},
+	}
+	call := func(token string) (string, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		var subject string
+		_, err := UnaryServerAuth(a)(ctx, nil, unaryInfo,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				id, _ := auth.FromContext(ctx)
+				subject = id.Subject
+				return nil, nil
+			})
+		return subject, err
+	}
+
+	if _, err := call(""); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("no token: expected Unauthenticated, got %v", err)
+	}
+	if _, err := call("garbage"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("bad token: expected Unauthenticated, got %v", err)
+	}
+	noScope, _ := auth.Sign(key, "", auth.Identity{Subject: "bob"}, time.Minute)
+	if _, err := call(noScope); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("missing scope: expected PermissionDenied, got %v", err)
+	}
+	ok, _ := auth.Sign(key, "", auth.Identity{Subject: "alice", Scopes: []string{"streaming"}}, time.Minute)
+	if subject, err := call(ok); err != nil || subject != "alice" {
+		t.Errorf("valid token: subject = %q, err = %v", subject, err)
+	}
+}
+
+func TestUnaryServerRateLimit(t *testing.T) {
+	l := ratelimit.New(ratelimit.Limits{Default: ratelimit.Limit{Rate: 1, Burst: 1}})
+	ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "alice"})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	if _, err := UnaryServerRateLimit(l)(ctx, nil, unaryInfo, handler); err != nil {
+		t.Fatalf("first call rejected: %v", err)
+	}
+	_, err := UnaryServerRateLimit(l)(ctx, nil, unaryInfo, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if d, ok := RetryDelay(err); !ok || d <= 0 || d > time.Second {
+		t.Errorf("retry delay = %v, %v", d, ok)
+	}
+	other := auth.NewContext(context.Background(), &auth.Identity{Subject: "bob"})
+	if _, err := UnaryServerRateLimit(l)(other, nil, unaryInfo, handler); err != nil {
+		t.Errorf("other caller rejected: %v", err)
+	}
+}
+
+func TestUnaryServerFault(t *testing.T) {
+	inj, err := fault.New(
+		fault.Rule{Method: "Add", Delay: time.Second},
+		fault.Rule{Method: "Subtract", AbortCode: codes.Unavailable},
+		fault.Rule{Method: "Multiply", CutAfter: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return "ok", nil
+	}
+	info := func(method string) *grpc.UnaryServerInfo {
+		return &grpc.UnaryServerInfo{FullMethod: "/calculator.v1.CalculatorService/" + method}
+	}
+
+	// 延迟计入调用方的 deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := UnaryServerFault(inj)(ctx, nil, info("Add"), handler); status.Code(err) != codes.DeadlineExceeded || calls != 0 {
+		t.Fatalf("delayed call = %v, %d calls", err, calls)
+	}
+	_, err = UnaryServerFault(inj)(context.Background(), nil, info("Subtract"), handler)
+	if status.Code(err) != codes.Unavailable || calls != 0 {
+		t.Fatalf("aborted call = %v, %d calls", err, calls)
+	}
+	if info, ok := status.Convert(err).Details()[0].(*errdetails.ErrorInfo); !ok || info.Reason != FaultInjectedReason {
+		t.Fatalf("details = %v", status.Convert(err).Details())
+	}
+	// cut_after：处理函数执行了，但响应被丢弃
+	if _, err := UnaryServerFault(inj)(context.Background(), nil, info("Multiply"), handler); status.Code(err) != codes.Unavailable || calls != 1 {
+		t.Fatalf("cut call = %v, %d calls", err, calls)
+	}
+	if resp, err := UnaryServerFault(inj)(context.Background(), nil, info("Divide"), handler); err != nil || resp != "ok" {
+		t.Fatalf("call without faults = %v, %v", resp, err)
+	}
+}
+
+func TestUnaryServerConcurrency(t *testing.T) {
+	l := concurrency.New(concurrency.Config{Initial: 1, Min: 1})
+	unary := UnaryServerConcurrency(l)
+	info := &grpc.UnaryServerInfo{FullMethod: "/calculator.v1.CalculatorService/Add"}
+	entered, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := unary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			close(entered)
+			<-release
+			return "ok", nil
+		})
+		done <- err
+	}()
+	<-entered
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	_, err := unary(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("call over the limit = %v", err)
+	}
+	if info, ok := status.Convert(err).Details()[0].(*errdetails.ErrorInfo); !ok || info.Reason != OverloadedReason {
+		t.Fatalf("details = %v", status.Convert(err).Details())
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := unary(context.Background(), nil, info, handler); err != nil || resp != "ok" {
+		t.Fatalf("call after release = %v, %v", resp, err)
+	}
+	if l.InFlight() != 0 || l.Shed() != 1 {
+		t.Fatalf("in flight = %d, shed = %d", l.InFlight(), l.Shed())
+	}
+}
+
+func TestUnaryServerConcurrencyPanic(t *testing.T) {
+	l := concurrency.New(concurrency.Config{Initial: 20, Min: 10})
+	unary := UnaryServerConcurrency(l)
+	// 与服务端的拦截器链一样，Recovery 在外层
+	_, err := UnaryServerRecovery()(context.Background(), nil, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return unary(ctx, req, unaryInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+	if l.InFlight() != 0 || l.Limit() != 18 {
+		t.Fatalf("in flight = %d, limit = %d after a panic", l.InFlight(), l.Limit())
+	}
+}
+
+func TestUnaryServerIdempotency(t *testing.T) {
+	c := idempotency.New(time.Minute, 0)
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if req.(*wrapperspb.Int64Value).Value < 0 {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return wrapperspb.Int64(req.(*wrapperspb.Int64Value).Value * 2), nil
+	}
+	call := func(key string, v int64) (interface{}, error) {
+		ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "alice"})
+		if key != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(IdempotencyKeyHeader, key))
+		}
+		return UnaryServerIdempotency(c)(ctx, wrapperspb.Int64(v), unaryInfo, handler)
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp, err := call("k1", 21); err != nil || resp.(*wrapperspb.Int64Value).Value != 42 {
+			t.Fatalf("call %d = %v, %v", i, resp, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("handler ran %d times for a replayed key", calls)
+	}
+	_, err := call("k1", 22)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("reused key with another payload = %v", err)
+	}
+	// 可以重试的错误不保存
+	call("k2", -1)
+	call("k2", -1)
+	if calls != 3 {
+		t.Fatalf("handler ran %d times, want 3", calls)
+	}
+	// 不带 key 的调用不受影响
+	call("", 1)
+	call("", 1)
+	if calls != 5 {
+		t.Fatalf("handler ran %d times, want 5", calls)
+	}
+}
+
+// fakeClientStream RecvMsg 依次返回 recv 中的结果
+type fakeClientStream struct {
+	grpc.ClientStream
+	recv []error
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error { return nil }
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	err := s.recv[0]
+	s.recv = s.recv[1:]
+	return err
+}
+
+// metricSum 指标 name 所有序列的值之和
+func metricSum(t *testing.T, g prometheus.Gatherer, name string) float64 {
+	t.Helper()
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			sum += m.GetCounter().GetValue() + m.GetGauge().GetValue()
+		}
+	}
+	return sum
+}
+
+func TestStreamClientMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := metrics.NewClient(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(ctx context.Context, desc *grpc.StreamDesc, recv ...error) grpc.ClientStream {
+		cs, err := StreamClientMetrics(m)(ctx, desc, nil, "/calculator.v1.CalculatorService/ChatAdd",
+			func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return &fakeClientStream{recv: recv}, nil
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cs
+	}
+
+	// 服务端流读到 io.EOF 才算完成
+	cs := open(context.Background(), &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, nil, nil, io.EOF)
+	cs.SendMsg(nil)
+	for cs.RecvMsg(nil) == nil {
+		if v := metricSum(t, reg, "grpc_client_in_flight"); v != 1 {
+			t.Fatalf("in flight while streaming = %v", v)
+		}
+	}
+	if v := metricSum(t, reg, "grpc_client_in_flight"); v != 0 {
+		t.Fatalf("in flight after EOF = %v", v)
+	}
+	if sent, received := metricSum(t, reg, "grpc_client_msg_sent_total"), metricSum(t, reg, "grpc_client_msg_received_total"); sent != 1 || received != 2 {
+		t.Fatalf("sent %v, received %v", sent, received)
+	}
+
+	// 客户端流收到唯一的响应即完成
+	cs = open(context.Background(), &grpc.StreamDesc{ClientStreams: true}, nil)
+	cs.RecvMsg(nil)
+	if v := metricSum(t, reg, "grpc_client_in_flight"); v != 0 {
+		t.Fatalf("in flight after client stream response = %v", v)
+	}
+
+	// 没读完就取消的流在 ctx 结束时完成
+	ctx, cancel := context.WithCancel(context.Background())
+	open(ctx, &grpc.StreamDesc{ServerStreams: true})
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for metricSum(t, reg, "grpc_client_in_flight") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("canceled stream still in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v := metricSum(t, reg, "grpc_client_handled_total"); v != 3 {
+		t.Fatalf("handled = %v, want 3", v)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exp := tracing.NewMemoryExporter()
+	tr := tracing.NewTracer("test", exp)
+
+	// 客户端把自己的 span 作为 traceparent 发给服务端
+	var outgoing metadata.MD
+	err := UnaryClientTracing(tr)(context.Background(), unaryInfo.FullMethod, nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			outgoing, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handlerSpan *tracing.Span
+	ctx := metadata.NewIncomingContext(context.Background(), outgoing)
+	_, err = UnaryServerTracing(tr)(ctx, nil, unaryInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerSpan = tracing.SpanFromContext(ctx)
+			return nil, status.Error(codes.InvalidArgument, "bad")
+		})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal(err)
+	}
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans", len(spans))
+	}
+	client, server := spans[0], spans[1]
+	if client.Kind != tracing.KindClient || server.Kind != tracing.KindServer {
+		t.Fatalf("kinds = %s, %s", client.Kind, server.Kind)
+	}
+	if server.TraceID != client.TraceID || server.ParentSpanID != client.SpanID {
+		t.Errorf("server span %+v is not a child of client span %+v", server, client)
+	}
+	if handlerSpan == nil || handlerSpan.SpanContext().SpanID != server.SpanID {
+		t.Error("handler context does not carry the server span")
+	}
+	if server.Attributes["rpc.method"] != "Add" || server.Attributes["rpc.grpc.status_code"] != "InvalidArgument" || server.Error == "" {
+		t.Errorf("server span = %+v", server)
+	}
+}
@@ -1 +1,53 @@
-package pkg
+package interceptor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerLogging 记录一元调用的方法、耗时和错误
+func UnaryServerLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		log.Printf("[UNARY] method = %s, request_id = %s, cost = %s, err = %v",
+			info.FullMethod, RequestIDFromContext(ctx), time.Since(start), err)
+		return resp, err
+	}
+}
+
+// StreamServerLogging 记录流式调用的方法、耗时和错误
+func StreamServerLogging() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		log.Printf("[STREAM] method = %s, request_id = %s, cost = %s, err = %v",
+			info.FullMethod, RequestIDFromContext(ss.Context()), time.Since(start), err)
+		return err
+	}
+}
+
+// UnaryClientLogging 客户端一元调用日志
+func UnaryClientLogging() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		log.Printf("[CLIENT UNARY] method = %s, request_id = %s, cost = %s, err = %v",
+			method, outgoingRequestID(ctx), time.Since(start), err)
+		return err
+	}
+}
+
+// StreamClientLogging 客户端流式调用日志，只记录建流的结果
+func StreamClientLogging() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		log.Printf("[CLIENT STREAM] method = %s, request_id = %s, cost = %s, err = %v",
+			method, outgoingRequestID(ctx), time.Since(start), err)
+		return cs, err
+	}
+}
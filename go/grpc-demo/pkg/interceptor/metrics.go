@@ -0,0 +1,80 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/MorseWayne/grpc-demo/pkg/metrics"
+	"google.golang.org/grpc"
+)
+
+// UnaryServerMetrics 记录调用数、状态码、耗时和进行中的调用数；成功的一元调用计为收发各一条消息
+func UnaryServerMetrics(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		call := m.Begin(metrics.Unary, info.FullMethod)
+		call.Received()
+		resp, err := handler(ctx, req)
+		if err == nil {
+			call.Sent()
+		}
+		call.End(err)
+		return resp, err
+	}
+}
+
+// StreamServerMetrics 流式版本，另外统计流上收发的消息数
+func StreamServerMetrics(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		call := m.Begin(metrics.StreamType(info.IsClientStream, info.IsServerStream), info.FullMethod)
+		err := handler(srv, &metricsServerStream{ServerStream: ss, call: call})
+		call.End(err)
+		return err
+	}
+}
+
+type metricsServerStream struct {
+	grpc.ServerStream
+	call *metrics.Call
+}
+
+func (s *metricsServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.call.Received()
+	}
+	return err
+}
+
+func (s *metricsServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.call.Sent()
+	}
+	return err
+}
+
+// UnaryClientMetrics 客户端一元调用的指标，耗时包括重试和对冲
+func UnaryClientMetrics(m *metrics.Metrics) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		call := m.Begin(metrics.Unary, method)
+		call.Sent()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			call.Received()
+		}
+		call.End(err)
+		return err
+	}
+}
+
+// StreamClientMetrics 客户端流的指标，流结束的判断见 observeClientStream
+func StreamClientMetrics(m *metrics.Metrics) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		call := m.Begin(metrics.StreamType(desc.ClientStreams, desc.ServerStreams), method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			call.End(err)
+			return nil, err
+		}
+		return observeClientStream(ctx, cs, desc, call.Sent, call.Received, call.End), nil
+	}
+}
@@ -0,0 +1,90 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/MorseWayne/grpc-demo/pkg/auth"
+	"github.com/MorseWayne/grpc-demo/pkg/ratelimit"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// UnaryServerRateLimit 按调用方和方法限流，超限时返回 ResourceExhausted 和 RetryInfo
+func UnaryServerRateLimit(l *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := rateLimit(ctx, l, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRateLimit 流式版本，每建立一个流消耗一个令牌
+func StreamServerRateLimit(l *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rateLimit(ss.Context(), l, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func rateLimit(ctx context.Context, l *ratelimit.Limiter, method string) error {
+	ok, wait := l.Allow(CallerKey(ctx), method)
+	if ok {
+		return nil
+	}
+	st := status.Newf(codes.ResourceExhausted, "rate limit exceeded for %s, retry after %s", method, wait)
+	if ds, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = ds
+	}
+	return st.Err()
+}
+
+const (
+	// InProcessNetwork 进程内连接（如 HTTP 网关）的对端地址所用的网络名
+	InProcessNetwork = "inprocess"
+	// ForwardedForHeader 进程内的调用方代为传递的原始客户端地址；来自网络连接的同名 metadata 不被信任
+	ForwardedForHeader = "x-forwarded-for"
+)
+
+// CallerKey 调用方标识：已认证时使用身份，否则使用对端 IP；进程内连接使用 x-forwarded-for 中的地址
+func CallerKey(ctx context.Context) string {
+	if id, ok := auth.FromContext(ctx); ok {
+		return "sub:" + id.Subject
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if p.Addr.Network() == InProcessNetwork {
+			if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(ForwardedForHeader)) > 0 {
+				return "ip:" + md.Get(ForwardedForHeader)[0]
+			}
+		}
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		return "ip:" + addr
+	}
+	return "unknown"
+}
+
+// RetryDelay 从 ResourceExhausted 等错误的 RetryInfo 中取出建议的重试间隔
+func RetryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,85 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDKey 请求 ID 所在的 metadata key
+const RequestIDKey = "x-request-id"
+
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext 获取服务端上下文中的请求 ID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// UnaryServerRequestID 从 metadata 读取请求 ID，没有则生成，并通过 header 回传给客户端
+func UnaryServerRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := incomingRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+		return handler(context.WithValue(ctx, requestIDCtxKey{}, id), req)
+	}
+}
+
+// StreamServerRequestID 流式版本的请求 ID 拦截器
+func StreamServerRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := incomingRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(RequestIDKey, id))
+		return handler(srv, WrapServerStream(ss, context.WithValue(ss.Context(), requestIDCtxKey{}, id)))
+	}
+}
+
+// UnaryClientRequestID 为每次调用附带请求 ID
+func UnaryClientRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withOutgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientRequestID 为每个流附带请求 ID
+func StreamClientRequestID() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withOutgoingRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// NewRequestID 生成一个随机的请求 ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	return NewRequestID()
+}
+
+func outgoingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(RequestIDKey); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func withOutgoingRequestID(ctx context.Context) context.Context {
+	if outgoingRequestID(ctx) != "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, NewRequestID())
+}
@@ -0,0 +1,78 @@
+package interceptor
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// wrappedServerStream 替换 ServerStream 的上下文，便于拦截器向下传递值
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedServerStream) Context() context.Context {
+	return w.ctx
+}
+
+// WrapServerStream 返回一个使用 ctx 作为上下文的 ServerStream
+func WrapServerStream(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &wrappedServerStream{ServerStream: ss, ctx: ctx}
+}
+
+// observedClientStream 观察客户端流收发的消息和结束。流在 RecvMsg 返回错误（包括 io.EOF）、
+// 非服务端流收到唯一的响应或 ctx 结束时计为结束，没有读完就丢弃的流也会结束
+type observedClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	sent          func()
+	received      func()
+	done          func(error)
+	stop          func() bool
+	once          sync.Once
+}
+
+// observeClientStream 包装 cs；sent、received 可以为 nil，done 只会被调用一次
+func observeClientStream(ctx context.Context, cs grpc.ClientStream, desc *grpc.StreamDesc, sent, received func(), done func(error)) grpc.ClientStream {
+	s := &observedClientStream{ClientStream: cs, serverStreams: desc.ServerStreams, sent: sent, received: received}
+	s.done = func(err error) { s.once.Do(func() { done(err) }) }
+	s.stop = context.AfterFunc(ctx, func() {
+		s.done(status.FromContextError(ctx.Err()).Err())
+	})
+	return s
+}
+
+func (s *observedClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil && s.sent != nil {
+		s.sent()
+	}
+	return err
+}
+
+func (s *observedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		if s.received != nil {
+			s.received()
+		}
+		if !s.serverStreams {
+			s.end(nil)
+		}
+	case err == io.EOF:
+		s.end(nil)
+	default:
+		s.end(err)
+	}
+	return err
+}
+
+func (s *observedClientStream) end(err error) {
+	s.stop()
+	s.done(err)
+}
@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Timeouts 按方法配置的超时时间，key 为完整方法名，如 /calculator.v1.CalculatorService/Add
+type Timeouts struct {
+	// Default 一元调用的默认超时
+	Default time.Duration
+	// StreamDefault 流的默认超时，默认 0 不限制：订阅、实时推送这类流本来就会持续很久，需要限制的流在 PerMethod 中单独配置
+	StreamDefault time.Duration
+	PerMethod     map[string]time.Duration
+}
+
+// For 返回一元方法的超时时间，0 表示不限制
+func (t Timeouts) For(method string) time.Duration {
+	if d, ok := t.PerMethod[method]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// ForStream 返回流式方法的超时时间，0 表示不限制
+func (t Timeouts) ForStream(method string) time.Duration {
+	if d, ok := t.PerMethod[method]; ok {
+		return d
+	}
+	return t.StreamDefault
+}
+
+// withTimeout 只会缩短上下文已有的截止时间，不会延长
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// UnaryServerTimeout 为一元调用设置服务端处理超时
+func UnaryServerTimeout(t Timeouts) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := withTimeout(ctx, t.For(info.FullMethod))
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerTimeout 为流式调用设置服务端处理超时，只使用 StreamDefault 和 PerMethod，不受一元调用的 Default 影响
+func StreamServerTimeout(t Timeouts) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := withTimeout(ss.Context(), t.ForStream(info.FullMethod))
+		defer cancel()
+		return handler(srv, WrapServerStream(ss, ctx))
+	}
+}
+
+// UnaryClientTimeout 为没有设置截止时间的一元调用补上默认超时
+func UnaryClientTimeout(t Timeouts) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = withTimeout(ctx, t.For(method))
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientTimeout 为没有设置截止时间的流补上 StreamDefault 或 PerMethod 中的超时，流结束后才释放
+func StreamClientTimeout(t Timeouts) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if _, ok := ctx.Deadline(); ok || t.ForStream(method) <= 0 {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		ctx, cancel := withTimeout(ctx, t.ForStream(method))
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// 流结束时 grpc 会取消 cs.Context()，此时再释放 timer
+		go func() {
+			<-cs.Context().Done()
+			cancel()
+		}()
+		return cs, nil
+	}
+}
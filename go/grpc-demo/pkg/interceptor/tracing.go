@@ -0,0 +1,102 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataCarrier 让 tracing 读写 gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// UnaryServerTracing 从请求的 traceparent 继续调用链，为每次调用创建 server span
+func UnaryServerTracing(t *tracing.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startServerSpan(ctx, t, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endSpan(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerTracing 流式版本，span 覆盖整个流
+func StreamServerTracing(t *tracing.Tracer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startServerSpan(ss.Context(), t, info.FullMethod)
+		err := handler(srv, WrapServerStream(ss, ctx))
+		endSpan(span, err)
+		return err
+	}
+}
+
+// UnaryClientTracing 为每次调用创建 client span，并通过 traceparent 传给服务端；span 包括重试和对冲
+func UnaryClientTracing(t *tracing.Tracer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := startClientSpan(ctx, t, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// StreamClientTracing 客户端流的 span，流结束的判断见 observeClientStream
+func StreamClientTracing(t *tracing.Tracer) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startClientSpan(ctx, t, method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		return observeClientStream(ctx, cs, desc, nil, nil, func(err error) { endSpan(span, err) }), nil
+	}
+}
+
+func startServerSpan(ctx context.Context, t *tracing.Tracer, fullMethod string) (context.Context, *tracing.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = tracing.Extract(ctx, metadataCarrier(md))
+	}
+	ctx, span := t.Start(ctx, fullMethod, tracing.KindServer)
+	setRPCAttributes(span, fullMethod)
+	return ctx, span
+}
+
+func startClientSpan(ctx context.Context, t *tracing.Tracer, method string) (context.Context, *tracing.Span) {
+	ctx, span := t.Start(ctx, method, tracing.KindClient)
+	setRPCAttributes(span, method)
+	carrier := tracing.MapCarrier{}
+	tracing.Inject(ctx, carrier)
+	kv := make([]string, 0, 2*len(carrier))
+	for k, v := range carrier {
+		kv = append(kv, k, v)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...), span
+}
+
+// setRPCAttributes 按 OpenTelemetry 的 rpc.* 约定记录服务名和方法名
+func setRPCAttributes(span *tracing.Span, fullMethod string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.service", service)
+	span.SetAttribute("rpc.method", method)
+}
+
+func endSpan(span *tracing.Span, err error) {
+	span.SetAttribute("rpc.grpc.status_code", status.Code(err).String())
+	span.End(err)
+}
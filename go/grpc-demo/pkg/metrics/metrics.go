@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/status"
+)
+
+// CallType 调用类型，对应 grpc_type 标签
+type CallType string
+
+const (
+	Unary        CallType = "unary"
+	ClientStream CallType = "client_stream"
+	ServerStream CallType = "server_stream"
+	BidiStream   CallType = "bidi_stream"
+)
+
+// StreamType 按是否有客户端流、服务端流确定调用类型
+func StreamType(clientStreams, serverStreams bool) CallType {
+	switch {
+	case clientStreams && serverStreams:
+		return BidiStream
+	case clientStreams:
+		return ClientStream
+	case serverStreams:
+		return ServerStream
+	}
+	return Unary
+}
+
+// LatencyBuckets 处理耗时的直方图桶，单位秒；计算器的调用大多在毫秒以下，比 prometheus.DefBuckets 更细
+var LatencyBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics 按方法统计的调用指标；指标名和标签与 go-grpc-prometheus 一致，现成的仪表板可以直接使用
+type Metrics struct {
+	started  *prometheus.CounterVec
+	handled  *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	received *prometheus.CounterVec
+	sent     *prometheus.CounterVec
+}
+
+// NewServer 创建服务端指标（grpc_server_*）并注册到 reg；同一个 reg 重复创建时复用已注册的指标
+func NewServer(reg prometheus.Registerer) (*Metrics, error) {
+	return newMetrics(reg, "server")
+}
+
+// NewClient 创建客户端指标（grpc_client_*）并注册到 reg
+func NewClient(reg prometheus.Registerer) (*Metrics, error) {
+	return newMetrics(reg, "client")
+}
+
+func newMetrics(reg prometheus.Registerer, side string) (*Metrics, error) {
+	method := []string{"grpc_type", "grpc_service", "grpc_method"}
+	withCode := append(append([]string{}, method...), "grpc_code")
+	m := &Metrics{
+		started: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_" + side + "_started_total",
+			Help: "Total number of RPCs started on the " + side + ".",
+		}, method),
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_" + side + "_handled_total",
+			Help: "Total number of RPCs completed on the " + side + ", regardless of success or failure.",
+		}, withCode),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_" + side + "_handling_seconds",
+			Help:    "Histogram of RPC latency (seconds) until completed on the " + side + ".",
+			Buckets: LatencyBuckets,
+		}, method),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "grpc_" + side + "_in_flight",
+			Help: "Number of RPCs currently in progress on the " + side + ".",
+		}, method),
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_" + side + "_msg_received_total",
+			Help: "Total number of stream messages received on the " + side + ".",
+		}, method),
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_" + side + "_msg_sent_total",
+			Help: "Total number of stream messages sent on the " + side + ".",
+		}, method),
+	}
+	var errs []error
+	m.started = register(reg, m.started, &errs)
+	m.handled = register(reg, m.handled, &errs)
+	m.latency = register(reg, m.latency, &errs)
+	m.inFlight = register(reg, m.inFlight, &errs)
+	m.received = register(reg, m.received, &errs)
+	m.sent = register(reg, m.sent, &errs)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// register 注册 c；已经注册过同样的指标时返回已有的那个，其他错误追加到 errs
+func register[T prometheus.Collector](reg prometheus.Registerer, c T, errs *[]error) T {
+	err := reg.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	if err != nil {
+		*errs = append(*errs, err)
+	}
+	return c
+}
+
+// Concurrency 自适应并发限制器的状态，见 concurrency.Limiter
+type Concurrency interface {
+	Limit() int
+	InFlight() int
+	Shed() uint64
+}
+
+// RegisterConcurrency 把并发上限、受其约束的进行中请求数和被拒绝的请求数注册到 reg，采集时读取 c 的当前值；
+// 同一个 reg 重复注册时保留已有的指标
+func RegisterConcurrency(reg prometheus.Registerer, c Concurrency) error {
+	var errs []error
+	register(reg, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "grpc_server_concurrency_limit",
+		Help: "Current adaptive limit of concurrent RPCs on the server.",
+	}, func() float64 { return float64(c.Limit()) }), &errs)
+	register(reg, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "grpc_server_concurrency_in_flight",
+		Help: "Number of RPCs counted against the concurrency limit.",
+	}, func() float64 { return float64(c.InFlight()) }), &errs)
+	register(reg, prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "grpc_server_concurrency_shed_total",
+		Help: "Total number of RPCs rejected because the concurrency limit was reached.",
+	}, func() float64 { return float64(c.Shed()) }), &errs)
+	return errors.Join(errs...)
+}
+
+// Call 一次调用的记录
+type Call struct {
+	m        *Metrics
+	labels   []string
+	start    time.Time
+	received prometheus.Counter
+	sent     prometheus.Counter
+	once     sync.Once
+}
+
+// Begin 记录调用开始，调用结束时必须调用 End
+func (m *Metrics) Begin(typ CallType, fullMethod string) *Call {
+	service, method := splitMethod(fullMethod)
+	labels := []string{string(typ), service, method}
+	m.started.WithLabelValues(labels...).Inc()
+	m.inFlight.WithLabelValues(labels...).Inc()
+	return &Call{
+		m:        m,
+		labels:   labels,
+		start:    time.Now(),
+		received: m.received.WithLabelValues(labels...),
+		sent:     m.sent.WithLabelValues(labels...),
+	}
+}
+
+// Received 收到一条消息
+func (c *Call) Received() {
+	c.received.Inc()
+}
+
+// Sent 发出一条消息
+func (c *Call) Sent() {
+	c.sent.Inc()
+}
+
+// End 记录调用结果和耗时，只有第一次调用生效
+func (c *Call) End(err error) {
+	c.once.Do(func() {
+		c.m.inFlight.WithLabelValues(c.labels...).Dec()
+		c.m.latency.WithLabelValues(c.labels...).Observe(time.Since(c.start).Seconds())
+		c.m.handled.WithLabelValues(append(c.labels, status.Code(err).String())...).Inc()
+	})
+}
+
+// splitMethod 把 /package.Service/Method 拆成服务名和方法名
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+// NewRegistry 包含 Go 运行时和进程指标的注册表
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return reg
+}
+
+// Serve 在 addr 上以 Prometheus 格式提供 /metrics，直到 ctx 结束
+func Serve(ctx context.Context, addr string, g prometheus.Gatherer) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	log.Printf("metrics listening at: http://%s/metrics", lis.Addr())
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	})
+	defer stop()
+	if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
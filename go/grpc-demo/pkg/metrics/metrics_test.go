@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const addMethod = "/calculator.v1.CalculatorService/Add"
+
+func TestCall(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewServer(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := m.Begin(BidiStream, addMethod)
+	labels := []string{"bidi_stream", "calculator.v1.CalculatorService", "Add"}
+	if v := testutil.ToFloat64(m.inFlight.WithLabelValues(labels...)); v != 1 {
+		t.Fatalf("in flight = %v", v)
+	}
+	call.Received()
+	call.Received()
+	call.Sent()
+	call.End(status.Error(codes.InvalidArgument, "bad"))
+	call.End(nil)
+
+	if v := testutil.ToFloat64(m.inFlight.WithLabelValues(labels...)); v != 0 {
+		t.Errorf("in flight after End = %v", v)
+	}
+	if v := testutil.ToFloat64(m.received.WithLabelValues(labels...)); v != 2 {
+		t.Errorf("received = %v", v)
+	}
+	if v := testutil.ToFloat64(m.sent.WithLabelValues(labels...)); v != 1 {
+		t.Errorf("sent = %v", v)
+	}
+	// End 只生效一次
+	if v := testutil.ToFloat64(m.handled.WithLabelValues(append(labels, "InvalidArgument")...)); v != 1 {
+		t.Errorf("handled InvalidArgument = %v", v)
+	}
+	if n := testutil.CollectAndCount(m.handled); n != 1 {
+		t.Errorf("handled has %d series", n)
+	}
+	if n := testutil.CollectAndCount(m.latency, "grpc_server_handling_seconds"); n != 1 {
+		t.Errorf("latency has %d series", n)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	a, err := NewServer(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewServer(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.started != b.started {
+		t.Fatal("second NewServer did not reuse the registered collectors")
+	}
+	// 客户端指标名不同，可以注册到同一个 reg
+	if _, err := NewClient(reg); err != nil {
+		t.Fatal(err)
+	}
+	// 同名但标签不同的指标无法注册
+	other := prometheus.NewRegistry()
+	other.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "grpc_server_handled_total", Help: "other"}))
+	if _, err := NewServer(other); err == nil || !strings.Contains(err.Error(), "grpc_server_handled_total") {
+		t.Fatalf("conflicting registration = %v", err)
+	}
+}
+
+type fakeConcurrency struct{ limit, inFlight int }
+
+func (c *fakeConcurrency) Limit() int    { return c.limit }
+func (c *fakeConcurrency) InFlight() int { return c.inFlight }
+func (c *fakeConcurrency) Shed() uint64  { return 7 }
+
+func TestRegisterConcurrency(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := &fakeConcurrency{limit: 20, inFlight: 3}
+	if err := RegisterConcurrency(reg, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterConcurrency(reg, &fakeConcurrency{}); err != nil {
+		t.Fatalf("second registration = %v", err)
+	}
+	c.limit = 15
+	want := `
+# HELP grpc_server_concurrency_limit Current adaptive limit of concurrent RPCs on the server.
+# TYPE grpc_server_concurrency_limit gauge
+grpc_server_concurrency_limit 15
+# HELP grpc_server_concurrency_shed_total Total number of RPCs rejected because the concurrency limit was reached.
+# TYPE grpc_server_concurrency_shed_total counter
+grpc_server_concurrency_shed_total 7
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "grpc_server_concurrency_limit", "grpc_server_concurrency_shed_total"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	for _, tc := range []struct{ in, service, method string }{
+		{addMethod, "calculator.v1.CalculatorService", "Add"},
+		{"Add", "unknown", "Add"},
+	} {
+		if s, m := splitMethod(tc.in); s != tc.service || m != tc.method {
+			t.Errorf("splitMethod(%q) = %q, %q", tc.in, s, m)
+		}
+	}
+}
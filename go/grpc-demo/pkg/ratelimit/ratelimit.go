@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit 令牌桶参数：每秒补充 Rate 个令牌，最多积攒 Burst 个；Rate 为 0 表示不限流
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits 按方法配置的限流参数，key 为完整方法名
+type Limits struct {
+	Default   Limit
+	PerMethod map[string]Limit
+}
+
+// For 返回指定方法的限流参数
+func (l Limits) For(method string) Limit {
+	if limit, ok := l.PerMethod[method]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // 不再消耗令牌时桶被补满的时刻
+}
+
+// sweepInterval 清理空闲令牌桶的间隔
+const sweepInterval = time.Minute
+
+// Limiter 按 (调用方, 方法) 维护令牌桶
+type Limiter struct {
+	limits Limits
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New 创建限流器
+func New(limits Limits) *Limiter {
+	return &Limiter{
+		limits:  limits,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow 尝试为 key 调用 method 消耗一个令牌；被拒绝时返回下一个令牌可用前需要等待的时间
+func (l *Limiter) Allow(key, method string) (bool, time.Duration) {
+	limit := l.limits.For(method)
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	burst := float64(max(limit.Burst, 1))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	id := key + "\x00" + method
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[id] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((burst - b.tokens) / limit.Rate * float64(time.Second)))
+	if allowed {
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep 删除已经补满的令牌桶（删除后重建的桶同样是满的，不影响结果），防止调用方很多时内存增长
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for id, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, id)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Limits{
+		Default:   Limit{Rate: 2, Burst: 3},
+		PerMethod: map[string]Limit{"/free": {}},
+	})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("alice", "/add"); !ok {
+			t.Fatalf("request %d within burst rejected", i)
+		}
+	}
+	ok, wait := l.Allow("alice", "/add")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected rejection with 500ms wait, got %v %v", ok, wait)
+	}
+	// 其他调用方和不限流的方法不受影响
+	if ok, _ := l.Allow("bob", "/add"); !ok {
+		t.Error("bob should have his own bucket")
+	}
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Allow("alice", "/free"); !ok {
+			t.Error("unlimited method rejected")
+		}
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("alice", "/add"); !ok {
+		t.Error("token should be refilled after wait")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Limits{Default: Limit{Rate: 1, Burst: 1}})
+	l.now = func() time.Time { return now }
+	l.Allow("alice", "/add")
+	l.Allow("bob", "/add")
+	now = now.Add(2 * sweepInterval)
+	l.Allow("carol", "/add")
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be swept, have %d", len(l.buckets))
+	}
+}
@@ -0,0 +1,182 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config TLS 配置，全部为文件路径
+type Config struct {
+	CertFile string // 本端证书
+	KeyFile  string // 本端私钥
+	CAFile   string // 校验对端证书的 CA；服务端配置后即开启 mTLS
+	// ServerName 客户端校验服务端证书时使用的名字，为空时使用拨号地址中的主机名
+	ServerName string
+}
+
+// Enabled 是否配置了任何 TLS 文件
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+// Reloader 持有证书和 CA，每次握手前检查文件修改时间，文件变化后自动重新加载，无需重启进程
+type Reloader struct {
+	cfg Config
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time
+}
+
+// NewReloader 立即加载一次文件，加载失败直接返回错误
+func NewReloader(cfg Config) (*Reloader, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("tls: cert file and key file must be set together")
+	}
+	r := &Reloader{cfg: cfg}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 从磁盘重新加载证书和 CA
+func (r *Reloader) Reload() error {
+	modTime := make(map[string]time.Time)
+	for _, f := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if f == "" {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		modTime[f] = fi.ModTime()
+	}
+	var cert *tls.Certificate
+	if r.cfg.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("tls: load key pair: %w", err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if r.cfg.CAFile != "" {
+		pem, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("tls: read ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates found in %s", r.cfg.CAFile)
+		}
+	}
+	r.mu.Lock()
+	r.cert, r.pool, r.modTime = cert, pool, modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// maybeReload 文件修改时间变化时重新加载；加载失败（例如文件只写了一半）时保留旧证书，
+// 并记下新的修改时间，等文件再次变化后才重试，避免每次握手都打印错误
+func (r *Reloader) maybeReload() {
+	r.mu.RLock()
+	latest := make(map[string]time.Time, len(r.modTime))
+	changed := false
+	for f, t := range r.modTime {
+		latest[f] = t
+		if fi, err := os.Stat(f); err == nil && !fi.ModTime().Equal(t) {
+			latest[f] = fi.ModTime()
+			changed = true
+		}
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := r.Reload(); err != nil {
+		log.Printf("tls reload failed, keep using old certificates: %v", err)
+		r.mu.Lock()
+		r.modTime = latest
+		r.mu.Unlock()
+		return
+	}
+	log.Printf("tls certificates reloaded from %s", r.cfg.CertFile)
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// ServerTLSConfig 服务端配置：配置了 CA 时要求并校验客户端证书（mTLS）
+func (r *Reloader) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// 每次握手都生成新的配置，保证使用最新的证书和 CA
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			if cert == nil {
+				return nil, errors.New("tls: server certificate not configured")
+			}
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+			}
+			if pool != nil {
+				cfg.ClientCAs = pool
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientTLSConfig 客户端配置：配置了证书时提供给服务端（mTLS），配置了 CA 时用它校验服务端，
+// 否则使用系统根证书
+func (r *Reloader) ClientTLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.cfg.ServerName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+	}
+	if r.cfg.CAFile == "" {
+		return cfg
+	}
+	// RootCAs 在握手开始后无法替换，因此跳过内置校验，在 VerifyConnection 中用最新的 CA 自行校验
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		_, pool := r.current()
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tls: server presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			DNSName:       cs.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+	return cfg
+}
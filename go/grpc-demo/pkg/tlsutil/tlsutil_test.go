@@ -0,0 +1,234 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// testCA 测试用的临时 CA，证书全部在内存中生成
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发叶子证书，返回证书和私钥的 PEM
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile 写文件并推后修改时间，保证 Reloader 能发现变化
+func writeFile(t *testing.T, path string, data []byte, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type pki struct {
+	dir                               string
+	serverCert, serverKey, clientCert string
+	clientKey, caFile, clientCAFile   string
+	ca                                *testCA
+}
+
+func newPKI(t *testing.T) *pki {
+	dir := t.TempDir()
+	p := &pki{
+		dir:          dir,
+		serverCert:   filepath.Join(dir, "server.crt"),
+		serverKey:    filepath.Join(dir, "server.key"),
+		clientCert:   filepath.Join(dir, "client.crt"),
+		clientKey:    filepath.Join(dir, "client.key"),
+		caFile:       filepath.Join(dir, "ca.crt"),
+		clientCAFile: filepath.Join(dir, "client-ca.crt"),
+		ca:           newTestCA(t, "test-ca"),
+	}
+	now := time.Now()
+	sc, sk := p.ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+	cc, ck := p.ca.issue(t, "client", 3, x509.ExtKeyUsageClientAuth)
+	writeFile(t, p.serverCert, sc, now)
+	writeFile(t, p.serverKey, sk, now)
+	writeFile(t, p.clientCert, cc, now)
+	writeFile(t, p.clientKey, ck, now)
+	writeFile(t, p.caFile, p.ca.pem, now)
+	writeFile(t, p.clientCAFile, p.ca.pem, now)
+	return p
+}
+
+func startServer(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(cfg)))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// check 新建连接并调用一次 health check，返回服务端证书序列号
+func check(t *testing.T, addr string, cfg *tls.Config) (int64, error) {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	var p peer.Peer
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Peer(&p)); err != nil {
+		return 0, err
+	}
+	state := p.AuthInfo.(credentials.TLSInfo).State
+	return state.PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestTLS(t *testing.T) {
+	p := newPKI(t)
+	server, err := NewReloader(Config{CertFile: p.serverCert, KeyFile: p.serverKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startServer(t, server.ServerTLSConfig())
+
+	client, err := NewReloader(Config{CAFile: p.caFile, ServerName: "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := check(t, addr, client.ClientTLSConfig()); err != nil {
+		t.Fatalf("tls call failed: %v", err)
+	}
+
+	// 用另一个 CA 校验服务端证书应当失败
+	other := filepath.Join(p.dir, "other-ca.crt")
+	writeFile(t, other, newTestCA(t, "other-ca").pem, time.Now())
+	untrusted, _ := NewReloader(Config{CAFile: other, ServerName: "localhost"})
+	if _, err := check(t, addr, untrusted.ClientTLSConfig()); err == nil {
+		t.Fatal("expected failure with untrusted ca")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	p := newPKI(t)
+	server, err := NewReloader(Config{CertFile: p.serverCert, KeyFile: p.serverKey, CAFile: p.clientCAFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startServer(t, server.ServerTLSConfig())
+
+	client, err := NewReloader(Config{CertFile: p.clientCert, KeyFile: p.clientKey, CAFile: p.caFile, ServerName: "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := check(t, addr, client.ClientTLSConfig()); err != nil {
+		t.Fatalf("mtls call failed: %v", err)
+	}
+
+	// 不带客户端证书应当被拒绝
+	noCert, _ := NewReloader(Config{CAFile: p.caFile, ServerName: "localhost"})
+	if _, err := check(t, addr, noCert.ClientTLSConfig()); err == nil {
+		t.Fatal("expected failure without client certificate")
+	}
+}
+
+func TestReload(t *testing.T) {
+	p := newPKI(t)
+	server, err := NewReloader(Config{CertFile: p.serverCert, KeyFile: p.serverKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startServer(t, server.ServerTLSConfig())
+	client, _ := NewReloader(Config{CAFile: p.caFile, ServerName: "localhost"})
+
+	serial, err := check(t, addr, client.ClientTLSConfig())
+	if err != nil || serial != 2 {
+		t.Fatalf("serial = %d, err = %v", serial, err)
+	}
+
+	// 轮换服务端证书，新连接应当拿到新证书
+	later := time.Now().Add(time.Minute)
+	sc, sk := p.ca.issue(t, "server", 42, x509.ExtKeyUsageServerAuth)
+	writeFile(t, p.serverCert, sc, later)
+	writeFile(t, p.serverKey, sk, later)
+	if serial, err = check(t, addr, client.ClientTLSConfig()); err != nil || serial != 42 {
+		t.Fatalf("after rotation serial = %d, err = %v", serial, err)
+	}
+
+	// 写入损坏的证书时继续使用旧证书
+	writeFile(t, p.serverCert, []byte("garbage"), later.Add(time.Minute))
+	if serial, err = check(t, addr, client.ClientTLSConfig()); err != nil || serial != 42 {
+		t.Fatalf("after bad write serial = %d, err = %v", serial, err)
+	}
+}
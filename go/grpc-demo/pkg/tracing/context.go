@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// TraceparentHeader W3C trace-context 中携带调用链位置的头
+	TraceparentHeader = "traceparent"
+	// TracestateHeader 厂商自定义的状态，原样向下游传递
+	TracestateHeader = "tracestate"
+)
+
+// ErrInvalidTraceparent traceparent 格式不正确
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceID 调用链 ID，全零无效
+type TraceID [16]byte
+
+// SpanID span ID，全零无效
+type SpanID [8]byte
+
+// IsValid 是否不全为零
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid 是否不全为零
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// String 32 位小写十六进制
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// String 16 位小写十六进制
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// MarshalText 以十六进制写入 JSON
+func (id TraceID) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+
+// MarshalText 以十六进制写入 JSON
+func (id SpanID) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+
+// SpanContext 跨进程传递的 span 标识
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// TraceState tracestate 头的原始内容
+	TraceState string
+	// Remote 是否从上游的请求中解析得到
+	Remote bool
+}
+
+// IsValid trace ID 和 span ID 都有效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent 按 version 00 格式化，如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent 解析 traceparent 头。更高版本只读取 version 00 定义的字段，ff 版本和全零的 ID 无效
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	// version-traceid-spanid-flags，长度分别为 2、32、16、2
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+	version, rest := s[:2], s[55:]
+	if !isLowerHex(version) || version == "ff" || (version == "00" && rest != "") || (rest != "" && rest[0] != '-') {
+		return sc, ErrInvalidTraceparent
+	}
+	if !decodeHex(sc.TraceID[:], s[3:35]) || !decodeHex(sc.SpanID[:], s[36:52]) || !isLowerHex(s[53:55]) {
+		return sc, ErrInvalidTraceparent
+	}
+	if !sc.IsValid() {
+		return sc, ErrInvalidTraceparent
+	}
+	var flags [1]byte
+	decodeHex(flags[:], s[53:55])
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// decodeHex 只接受小写十六进制
+func decodeHex(dst []byte, s string) bool {
+	if !isLowerHex(s) {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+func isLowerHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// SpanKind span 在调用中的角色
+type SpanKind string
+
+const (
+	KindInternal SpanKind = "internal"
+	KindServer   SpanKind = "server"
+	KindClient   SpanKind = "client"
+	KindProducer SpanKind = "producer"
+	KindConsumer SpanKind = "consumer"
+)
+
+// SpanData 结束的 span，交给 Exporter 导出
+type SpanData struct {
+	TraceID      TraceID                `json:"trace_id"`
+	SpanID       SpanID                 `json:"span_id"`
+	ParentSpanID SpanID                 `json:"parent_span_id,omitzero"`
+	Service      string                 `json:"service"`
+	Name         string                 `json:"name"`
+	Kind         SpanKind               `json:"kind"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// Duration span 的耗时
+func (d *SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Exporter 导出结束的 span，需要支持并发调用
+type Exporter interface {
+	Export(span *SpanData) error
+}
+
+// Tracer 创建 span 并在结束时导出
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+// NewTracer 创建 Tracer；exporter 为 nil 时照常传递 trace-context，但不导出任何 span
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+// Start 创建 span 并放入返回的 ctx。ctx 中有 span 或从上游提取的 SpanContext 时作为父 span，
+// 否则开启新的调用链；结束时必须调用 End
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	s := &Span{
+		tracer: t,
+		data: SpanData{
+			Service: t.service,
+			Name:    name,
+			Kind:    kind,
+			Start:   time.Now(),
+		},
+	}
+	if parent.IsValid() {
+		s.sc = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+		s.data.ParentSpanID = parent.SpanID
+	} else {
+		s.sc = SpanContext{TraceID: newTraceID(), Sampled: true}
+	}
+	s.sc.SpanID = newSpanID()
+	s.data.TraceID = s.sc.TraceID
+	s.data.SpanID = s.sc.SpanID
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Span 一次操作的记录，可以在多个 goroutine 中使用
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext 向下游传递的标识
+func (s *Span) SpanContext() SpanContext {
+	return s.sc
+}
+
+// SetAttribute 记录一个属性，同名的属性会被覆盖
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+}
+
+// End 结束 span 并导出，err 不为 nil 时记录为失败；只有第一次调用生效
+func (s *Span) End(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if err != nil {
+		s.data.Error = err.Error()
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	if s.tracer.exporter != nil && s.sc.Sampled {
+		// 导出失败不影响业务，只是少了这条记录
+		_ = s.tracer.exporter.Export(&data)
+	}
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanFromContext 返回 ctx 中的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext 返回 ctx 中 span 的标识，没有 span 时返回从上游提取的标识
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// Carrier 读写传输层的头，如 gRPC metadata 或 Kafka record header
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// Inject 把 ctx 中的 SpanContext 写入 carrier，没有时什么也不做
+func Inject(ctx context.Context, c Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	c.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		c.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+// Extract 从 carrier 中读取上游的 SpanContext 并放入 ctx，之后 Start 的 span 以它为父；
+// 没有或格式不正确时原样返回 ctx
+func Extract(ctx context.Context, c Carrier) context.Context {
+	sc, err := ParseTraceparent(c.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = c.Get(TracestateHeader)
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// MapCarrier 基于 map 的 Carrier
+type MapCarrier map[string]string
+
+func (m MapCarrier) Get(key string) string { return m[key] }
+
+func (m MapCarrier) Set(key, value string) { m[key] = value }
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(valid)
+	if err != nil || !sc.Sampled || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("ParseTraceparent(%q) = %+v, %v", valid, sc, err)
+	}
+	if got := sc.Traceparent(); got != valid {
+		t.Errorf("Traceparent() = %q", got)
+	}
+	if sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"); err != nil || sc.Sampled {
+		t.Errorf("unsampled = %+v, %v", sc, err)
+	}
+	// 更高版本忽略多出的字段
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future version = %v", err)
+	}
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(s); !errors.Is(err, ErrInvalidTraceparent) {
+			t.Errorf("ParseTraceparent(%q) = %v", s, err)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	exp := NewMemoryExporter()
+	tr := NewTracer("test", exp)
+
+	ctx, root := tr.Start(context.Background(), "root", KindClient)
+	carrier := MapCarrier{}
+	Inject(ctx, carrier)
+	if carrier[TraceparentHeader] != root.SpanContext().Traceparent() {
+		t.Fatalf("injected %v", carrier)
+	}
+
+	// 下游从 carrier 继续同一条调用链
+	carrier[TracestateHeader] = "vendor=1"
+	remote := Extract(context.Background(), carrier)
+	if sc := SpanContextFromContext(remote); !sc.Remote || sc.SpanID != root.SpanContext().SpanID {
+		t.Fatalf("extracted %+v", sc)
+	}
+	_, child := tr.Start(remote, "child", KindServer)
+	child.SetAttribute("k", "v")
+	child.End(errors.New("boom"))
+	child.End(nil)
+	root.End(nil)
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || r.ParentSpanID.IsValid() {
+		t.Errorf("child %+v not under root %+v", c, r)
+	}
+	if c.Error != "boom" || c.Attributes["k"] != "v" || c.Kind != KindServer {
+		t.Errorf("child = %+v", c)
+	}
+	if child.SpanContext().TraceState != "vendor=1" {
+		t.Errorf("tracestate not kept: %+v", child.SpanContext())
+	}
+
+	// 格式不正确时开启新的调用链
+	bad := Extract(context.Background(), MapCarrier{TraceparentHeader: "garbage"})
+	if _, s := tr.Start(bad, "new", KindServer); s.SpanContext().TraceID == r.TraceID {
+		t.Error("invalid traceparent was used as parent")
+	}
+	// 不采样的调用链不导出
+	exp.Reset()
+	unsampled := Extract(context.Background(), MapCarrier{TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"})
+	_, s := tr.Start(unsampled, "unsampled", KindServer)
+	s.End(nil)
+	if len(exp.Spans()) != 0 {
+		t.Error("unsampled span was exported")
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exp, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTracer("test", exp)
+	ctx, parent := tr.Start(context.Background(), "parent", KindInternal)
+	_, child := tr.Start(ctx, "child", KindInternal)
+	child.End(nil)
+	parent.End(nil)
+	if err := exp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []map[string]interface{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("%d lines", len(lines))
+	}
+	if lines[0]["name"] != "child" || lines[0]["parent_span_id"] != parent.SpanContext().SpanID.String() {
+		t.Errorf("child line = %v", lines[0])
+	}
+	if _, ok := lines[1]["parent_span_id"]; ok || lines[1]["trace_id"] != parent.SpanContext().TraceID.String() {
+		t.Errorf("parent line = %v", lines[1])
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+	"github.com/morsewayne/kafka-demo/examples/08-order-processing/models"
+	"github.com/morsewayne/kafka-demo/pkg/config"
+	"github.com/morsewayne/kafka-demo/pkg/kafkatrace"
+	"github.com/morsewayne/kafka-demo/pkg/logger"
+)
+
+const (
+	orderTopic    = "order-events"
+	consumerGroup = "inventory-service"
+	// defaultTraceFile 与订单服务写入同一个文件，便于按 trace_id 串起一个订单
+	defaultTraceFile = "order-traces.jsonl"
+)
+
+// inventoryHandler 库存服务：为每个 OrderCreated 事件预留库存
+type inventoryHandler struct {
+	logger *logger.Logger
+	tracer *tracing.Tracer
+}
+
+func (h *inventoryHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *inventoryHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *inventoryHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if err := h.handle(session.Context(), msg); err != nil {
+			h.logger.Error("处理消息失败: Partition=%d, Offset=%d, err=%v", msg.Partition, msg.Offset, err)
+		}
+		session.MarkMessage(msg, "")
+		session.Commit()
+	}
+	return nil
+}
+
+func (h *inventoryHandler) handle(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+	// 从消息 header 的 traceparent 继续订单服务的调用链
+	_, span := kafkatrace.StartConsumer(ctx, h.tracer, msg)
+	defer func() { span.End(err) }()
+
+	var order models.OrderCreated
+	if err := json.Unmarshal(msg.Value, &order); err != nil {
+		return fmt.Errorf("解析订单失败: %w", err)
+	}
+	if order.EventType != models.EventOrderCreated {
+		return nil
+	}
+	span.SetAttribute("order_id", order.OrderID)
+
+	// 模拟预留库存
+	time.Sleep(50 * time.Millisecond)
+	reservationID := fmt.Sprintf("RSV-%s", order.OrderID)
+	span.SetAttribute("reservation_id", reservationID)
+	h.logger.Info("📦 库存已预留: OrderID=%s, ReservationID=%s, TraceID=%s",
+		order.OrderID, reservationID, span.SpanContext().TraceID)
+	return nil
+}
+
+func main() {
+	log := logger.New("InventoryService")
+
+	traceFile := os.Getenv("TRACE_FILE")
+	if traceFile == "" {
+		traceFile = defaultTraceFile
+	}
+	exporter, err := tracing.NewFileExporter(traceFile)
+	if err != nil {
+		log.Error("打开 trace 文件失败: %v", err)
+		os.Exit(1)
+	}
+	defer exporter.Close()
+
+	kafkaConfig := config.DefaultKafkaConfig()
+	group, err := sarama.NewConsumerGroup(kafkaConfig.Brokers, consumerGroup, config.NewConsumerConfig(consumerGroup))
+	if err != nil {
+		log.Error("创建消费者组失败: %v", err)
+		os.Exit(1)
+	}
+	defer group.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	handler := &inventoryHandler{logger: log, tracer: tracing.NewTracer("inventory-service", exporter)}
+	log.Info("✅ 库存服务已启动，span 写入 %s", traceFile)
+	for ctx.Err() == nil {
+		// 再均衡后 Consume 返回，需要重新加入
+		if err := group.Consume(ctx, []string{orderTopic}, handler); err != nil {
+			log.Error("消费失败: %v", err)
+			time.Sleep(time.Second)
+		}
+	}
+	log.Info("收到退出信号，关闭服务...")
+}
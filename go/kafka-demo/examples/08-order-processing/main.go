@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,12 +11,16 @@ import (
 	"time"
 
 	"github.com/IBM/sarama"
-	"github.com/google/uuid"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
 	"github.com/morsewayne/kafka-demo/examples/08-order-processing/models"
+	"github.com/morsewayne/kafka-demo/pkg/kafkatrace"
 )
 
 const orderTopic = "order-events"
 
+// defaultTraceFile 没有设置 TRACE_FILE 时 span 写入的文件，库存服务默认写入同一个文件
+const defaultTraceFile = "order-traces.jsonl"
+
 func main() {
 	logger := log.New(os.Stdout, "[OrderService] ", log.LstdFlags)
 
@@ -38,7 +43,18 @@ func main() {
 	}
 	defer producer.Close()
 
-	logger.Println("✅ 订单服务已启动")
+	traceFile := os.Getenv("TRACE_FILE")
+	if traceFile == "" {
+		traceFile = defaultTraceFile
+	}
+	exporter, err := tracing.NewFileExporter(traceFile)
+	if err != nil {
+		log.Fatalf("打开 trace 文件失败: %v", err)
+	}
+	defer exporter.Close()
+	tracer := tracing.NewTracer("order-service", exporter)
+
+	logger.Printf("✅ 订单服务已启动，span 写入 %s", traceFile)
 
 	// 模拟创建订单
 	go func() {
@@ -47,7 +63,7 @@ func main() {
 
 		orderNum := 1
 		for range ticker.C {
-			if err := createOrder(producer, logger, orderNum); err != nil {
+			if err := createOrder(context.Background(), tracer, producer, logger, orderNum); err != nil {
 				logger.Printf("❌ 创建订单失败: %v", err)
 			}
 			orderNum++
@@ -62,9 +78,14 @@ func main() {
 	logger.Println("收到退出信号，关闭服务...")
 }
 
-func createOrder(producer sarama.SyncProducer, logger *log.Logger, orderNum int) error {
-	// 生成订单
-	traceID := uuid.New().String()
+func createOrder(ctx context.Context, tracer *tracing.Tracer, producer sarama.SyncProducer, logger *log.Logger, orderNum int) (err error) {
+	// 发送订单事件的 span，traceparent 随消息 header 传给消费者
+	msg := &sarama.ProducerMessage{Topic: orderTopic}
+	_, span := kafkatrace.StartProducer(ctx, tracer, msg)
+	defer func() { span.End(err) }()
+
+	// 生成订单，trace_id 与 traceparent 中的调用链 ID 一致
+	traceID := span.SpanContext().TraceID.String()
 	orderID := fmt.Sprintf("ORD-%06d", orderNum)
 	userID := fmt.Sprintf("USER-%03d", orderNum%10)
 
@@ -98,20 +119,20 @@ func createOrder(producer sarama.SyncProducer, logger *log.Logger, orderNum int)
 	}
 
 	// 发送消息
-	msg := &sarama.ProducerMessage{
-		Topic: orderTopic,
-		Key:   sarama.StringEncoder(orderID), // 使用订单 ID 作为 Key，确保有序
-		Value: sarama.ByteEncoder(jsonData),
-		Headers: []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(models.EventOrderCreated)},
-			{Key: []byte("trace_id"), Value: []byte(traceID)},
-		},
-	}
+	msg.Key = sarama.StringEncoder(orderID) // 使用订单 ID 作为 Key，确保有序
+	msg.Value = sarama.ByteEncoder(jsonData)
+	msg.Headers = append(msg.Headers,
+		sarama.RecordHeader{Key: []byte("event_type"), Value: []byte(models.EventOrderCreated)},
+		sarama.RecordHeader{Key: []byte("trace_id"), Value: []byte(traceID)},
+	)
+	span.SetAttribute("order_id", orderID)
 
 	partition, offset, err := producer.SendMessage(msg)
 	if err != nil {
 		return fmt.Errorf("发送消息失败: %w", err)
 	}
+	span.SetAttribute("messaging.kafka.partition", partition)
+	span.SetAttribute("messaging.kafka.offset", offset)
 
 	logger.Printf("📦 订单已创建: OrderID=%s, UserID=%s, Amount=%.2f, TraceID=%s",
 		orderID, userID, order.TotalAmount, traceID)
@@ -4,7 +4,7 @@ go 1.25.3
 
 require (
 	github.com/IBM/sarama v1.46.3
-	github.com/google/uuid v1.6.0
+	github.com/MorseWayne/grpc-demo v0.0.0
 )
 
 require (
@@ -25,3 +25,8 @@ require (
 	golang.org/x/crypto v0.43.0 // indirect
 	golang.org/x/net v0.46.0 // indirect
 )
+
+replace github.com/MorseWayne/grpc-demo => ../grpc-demo
+
+// grpc-demo 依赖同仓库的 minitest，本地 replace 不会传递，这里需要同样替换
+replace minitest => ../minitest
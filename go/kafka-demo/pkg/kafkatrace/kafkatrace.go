@@ -0,0 +1,73 @@
+package kafkatrace
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"github.com/MorseWayne/grpc-demo/pkg/tracing"
+)
+
+// ProducerCarrier 让 tracing 读写待发送消息的 header
+type ProducerCarrier struct {
+	Msg *sarama.ProducerMessage
+}
+
+// Get 返回 key 对应的第一个 header
+func (c ProducerCarrier) Get(key string) string {
+	for _, h := range c.Msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set 替换 key 对应的 header，没有时追加
+func (c ProducerCarrier) Set(key, value string) {
+	for i, h := range c.Msg.Headers {
+		if string(h.Key) == key {
+			c.Msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.Msg.Headers = append(c.Msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// ConsumerCarrier 让 tracing 读取收到的消息的 header
+type ConsumerCarrier struct {
+	Msg *sarama.ConsumerMessage
+}
+
+// Get 返回 key 对应的第一个 header
+func (c ConsumerCarrier) Get(key string) string {
+	for _, h := range c.Msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set 收到的消息不需要写入 header，什么也不做
+func (c ConsumerCarrier) Set(key, value string) {}
+
+// StartProducer 为发送 msg 创建 producer span，并把 traceparent 写入 msg 的 header；
+// 发送完成后用发送结果调用 span.End
+func StartProducer(ctx context.Context, t *tracing.Tracer, msg *sarama.ProducerMessage) (context.Context, *tracing.Span) {
+	ctx, span := t.Start(ctx, "send "+msg.Topic, tracing.KindProducer)
+	span.SetAttribute("messaging.system", "kafka")
+	span.SetAttribute("messaging.destination.name", msg.Topic)
+	tracing.Inject(ctx, ProducerCarrier{Msg: msg})
+	return ctx, span
+}
+
+// StartConsumer 从 msg 的 header 继续调用链，为处理 msg 创建 consumer span；处理完成后调用 span.End
+func StartConsumer(ctx context.Context, t *tracing.Tracer, msg *sarama.ConsumerMessage) (context.Context, *tracing.Span) {
+	ctx = tracing.Extract(ctx, ConsumerCarrier{Msg: msg})
+	ctx, span := t.Start(ctx, "process "+msg.Topic, tracing.KindConsumer)
+	span.SetAttribute("messaging.system", "kafka")
+	span.SetAttribute("messaging.destination.name", msg.Topic)
+	span.SetAttribute("messaging.kafka.partition", msg.Partition)
+	span.SetAttribute("messaging.kafka.offset", msg.Offset)
+	return ctx, span
+}
@@ -21,7 +21,7 @@ func (ps *PrimeSieve) Generate(want int) []int {
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithCancel(context.Background())
 	res := make([]int, 0)
-	ch := ps.generateNatural(ctx, &wg) // 自然数序列: 2, 3, 4, ...
+	ch := ps.generateNatural(ctx, &wg, 100) // 自然数序列: 2, 3, 4, ...
 	for i := 0; i < want; i++ {
 		prime, ok := <-ch // 新出现的素数，检查channel是否关闭
 		if !ok {
@@ -36,13 +36,42 @@ func (ps *PrimeSieve) Generate(want int) []int {
 	return res
 }
 
-func (ps *PrimeSieve) generateNatural(ctx context.Context, wg *sync.WaitGroup) chan int {
+// 统计小于 below 的素数个数，每找到一个素数调用一次 found；ctx 取消时停止筛选并返回 ctx.Err()
+func (ps *PrimeSieve) Count(ctx context.Context, below int, found func(prime int)) (int, error) {
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	count := 0
+	ch := ps.generateNatural(ctx, &wg, below)
+	for {
+		select {
+		case <-ctx.Done():
+			return count, ctx.Err()
+		case prime, ok := <-ch:
+			if !ok {
+				// 被取消时过滤器也会关闭 channel，此时结果不完整
+				return count, ctx.Err()
+			}
+			count++
+			if found != nil {
+				found(prime)
+			}
+			ch = ps.filter(ctx, ch, prime, &wg)
+		}
+	}
+}
+
+// 生成 [2, end) 的自然数
+func (ps *PrimeSieve) generateNatural(ctx context.Context, wg *sync.WaitGroup, end int) chan int {
 	channel := make(chan int)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer close(channel)
-		for i := 2; i < 100; i++ {
+		for i := 2; i < end; i++ {
 			select {
 			case <-ctx.Done():
 				return
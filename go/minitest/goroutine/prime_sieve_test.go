@@ -1,6 +1,8 @@
 package goroutine
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -16,3 +18,25 @@ func TestPrimeSeive(t *testing.T) {
 		}
 	}
 }
+
+func TestPrimeSieveCount(t *testing.T) {
+	ps := NewPrimeSieve()
+	last := 0
+	count, err := ps.Count(context.Background(), 1000, func(prime int) { last = prime })
+	if err != nil || count != 168 || last != 997 {
+		t.Errorf("expected 168 primes up to 997, got %d up to %d, err %v", count, last, err)
+	}
+	if count, err := ps.Count(context.Background(), 2, nil); err != nil || count != 0 {
+		t.Errorf("expected no primes below 2, got %d, err %v", count, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count, err = ps.Count(ctx, 1000000, func(prime int) {
+		if prime > 100 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) || count < 26 {
+		t.Errorf("expected cancellation after 26 primes, got %d, err %v", count, err)
+	}
+}